@@ -4,11 +4,11 @@ import (
 	"errors"
 	"path"
 	"path/filepath"
-	"strings"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/fspath"
 )
 
 // Register with Fs
@@ -44,7 +44,12 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if opt.Remote == "" {
 		return nil, errors.New("alias can't point to an empty remote - check the value of the remote setting")
 	}
-	if strings.HasPrefix(opt.Remote, name+":") {
+	configName, _, err := fspath.Parse(opt.Remote)
+	if err != nil {
+		return nil, err
+	}
+	name, _ = fspath.ParseConfigName(name)
+	if remoteName, _ := fspath.ParseConfigName(configName); remoteName == name {
 		return nil, errors.New("can't point alias remote at itself - check the value of the remote setting")
 	}
 	_, configName, fsPath, err := fs.ParseRemote(opt.Remote)
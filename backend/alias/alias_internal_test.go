@@ -102,3 +102,37 @@ func TestNewFSInvalidRemote(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, f)
 }
+
+func TestNewFSConnectionString(t *testing.T) {
+	root, err := filepath.Abs(filepath.FromSlash("test/files"))
+	require.NoError(t, err)
+	four := path.Join(filepath.ToSlash(root), "four")
+
+	// override the remote with a connection string parameter
+	prepare(t, filepath.ToSlash(root))
+	f, err := fs.NewFs(fmt.Sprintf("%s,remote=%q:", remoteName, four))
+	require.NoError(t, err)
+	entries, err := f.List("")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+
+	// point the alias at an on the fly backend with parameters
+	prepare(t, fmt.Sprintf(":local,nounc=true:%s", four))
+	f, err = fs.NewFs(fmt.Sprintf("%s:five", remoteName))
+	require.NoError(t, err)
+	entries, err = f.List("")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+
+	// use an on the fly alias backend
+	f, err = fs.NewFs(fmt.Sprintf(":alias,remote=%q:", four))
+	require.NoError(t, err)
+	entries, err = f.List("")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+
+	// can't point the alias at itself with parameters
+	prepare(t, fmt.Sprintf("%s,remote=x:", remoteName))
+	_, err = fs.NewFs(fmt.Sprintf("%s:", remoteName))
+	require.Error(t, err)
+}
@@ -64,6 +64,7 @@ func init() {
 		Name:        "b2",
 		Description: "Backblaze B2",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name:     "account",
 			Help:     "Account ID or Application Key ID",
@@ -105,6 +106,24 @@ func init() {
 	})
 }
 
+// commandHelp describes the backend commands
+var commandHelp = []fs.CommandHelp{{
+	Name:  "cleanup",
+	Short: "Delete old versions of files and hide markers",
+	Long: `This command deletes the old versions of the files in the bucket
+and any hide markers, leaving only the current versions.  This is the
+same as "rclone cleanup" but the age of the versions to delete can be
+limited with the max-age option.
+
+    rclone backend cleanup b2:bucket/path -o max-age=30d
+
+Use --dry-run to see what would be deleted.
+`,
+	Opts: map[string]string{
+		"max-age": "Only delete old versions which were uploaded longer ago than this, eg 30d",
+	},
+}}
+
 // Options defines the configuration for this backend
 type Options struct {
 	Account      string        `config:"account"`
@@ -295,7 +314,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		opt:          *opt,
 		bucket:       bucket,
 		root:         directory,
-		srv:          rest.NewClient(fshttp.NewClient(fs.Config)).SetErrorHandler(errorHandler),
+		srv:          rest.NewClient(fshttp.NewClient(fs.GetConfigMap(name, m))).SetErrorHandler(errorHandler),
 		pacer:        pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
 		bufferTokens: make(chan []byte, fs.Config.Transfers),
 	}
@@ -908,10 +927,12 @@ func (f *Fs) deleteByID(ID, Name string) error {
 
 // purge deletes all the files and directories
 //
-// if oldOnly is true then it deletes only non current files.
+// if oldOnly is true then it deletes only non current files.  If
+// maxAge is set then only the non current files uploaded longer than
+// maxAge ago are deleted.
 //
 // Implemented here so we can make sure we delete old versions.
-func (f *Fs) purge(oldOnly bool) error {
+func (f *Fs) purge(oldOnly bool, maxAge time.Duration) error {
 	var errReturn error
 	var checkErrMutex sync.Mutex
 	var checkErr = func(err error) {
@@ -934,7 +955,11 @@ func (f *Fs) purge(oldOnly bool) error {
 			defer wg.Done()
 			for object := range toBeDeleted {
 				accounting.Stats.Checking(object.Name)
-				checkErr(f.deleteByID(object.ID, object.Name))
+				if fs.Config.DryRun {
+					fs.Logf(object.Name, "Not deleting version (id %q) as --dry-run", object.ID)
+				} else {
+					checkErr(f.deleteByID(object.ID, object.Name))
+				}
 				accounting.Stats.DoneChecking(object.Name)
 			}
 		}()
@@ -943,7 +968,9 @@ func (f *Fs) purge(oldOnly bool) error {
 	checkErr(f.list("", true, "", 0, true, func(remote string, object *api.File, isDirectory bool) error {
 		if !isDirectory {
 			accounting.Stats.Checking(remote)
-			if oldOnly && last != remote {
+			if oldOnly && maxAge > 0 && time.Since(time.Time(object.UploadTimestamp)) < maxAge {
+				fs.Debugf(remote, "Not deleting version (id %q) as younger than max-age", object.ID)
+			} else if oldOnly && last != remote {
 				if object.Action == "hide" {
 					fs.Debugf(remote, "Deleting current version (id %q) as it is a hide marker", object.ID)
 					toBeDeleted <- object
@@ -970,12 +997,37 @@ func (f *Fs) purge(oldOnly bool) error {
 
 // Purge deletes all the files and directories including the old versions.
 func (f *Fs) Purge() error {
-	return f.purge(false)
+	return f.purge(false, 0)
 }
 
 // CleanUp deletes all the hidden files.
 func (f *Fs) CleanUp() error {
-	return f.purge(true)
+	return f.purge(true, 0)
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "cleanup":
+		var maxAge time.Duration
+		if age := opt["max-age"]; age != "" {
+			maxAge, err = fs.ParseDuration(age)
+			if err != nil {
+				return nil, errors.Wrap(err, "bad max-age")
+			}
+		}
+		return nil, f.purge(true, maxAge)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
 }
 
 // Hashes returns the supported hash sets.
@@ -1479,6 +1531,7 @@ var (
 	_ fs.PutStreamer = &Fs{}
 	_ fs.CleanUpper  = &Fs{}
 	_ fs.ListRer     = &Fs{}
+	_ fs.Commander   = &Fs{}
 	_ fs.Object      = &Object{}
 	_ fs.MimeTyper   = &Object{}
 	_ fs.IDer        = &Object{}
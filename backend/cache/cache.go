@@ -21,6 +21,7 @@ import (
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fspath"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/rc"
 	"github.com/ncw/rclone/fs/walk"
@@ -252,7 +253,8 @@ func NewFs(name, rootPath string, m configmap.Mapper) (fs.Fs, error) {
 			opt.ChunkTotalSize, opt.ChunkSize, opt.TotalWorkers)
 	}
 
-	if strings.HasPrefix(opt.Remote, name+":") {
+	remoteName, _ := fspath.ParseConfigName(name)
+	if strings.HasPrefix(opt.Remote, remoteName+":") {
 		return nil, errors.New("can't point cache remote at itself - check the value of the remote setting")
 	}
 
@@ -0,0 +1,589 @@
+// Package combine implements a backend to combine multiple remotes
+// into a directory tree
+package combine
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/fspath"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "combine",
+		Description: "Combine several remotes into one",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "upstreams",
+			Help: `Upstreams for combining
+
+These should be in the form
+
+    dir=remote:path dir2=remote2:path
+
+Where before the = is specified the root directory and after is the remote to
+put there.
+
+Embedded spaces can be added using quotes
+
+    "dir=remote:path with space" "dir2=remote2:path with space"
+`,
+			Required: true,
+			Default:  fs.SpaceSepList(nil),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Upstreams fs.SpaceSepList `config:"upstreams"`
+}
+
+// Fs represents a combination of several remotes, each mounted as a
+// directory in the root
+type Fs struct {
+	name      string               // name of this remote
+	root      string               // the path we are working on
+	opt       Options              // parsed config options
+	features  *fs.Features         // optional features
+	upstreams map[string]*upstream // map of upstreams indexed by directory
+	dirs      []string             // sorted list of upstream directories
+	hashSet   hash.Set             // hashes supported by all the upstreams
+	precision time.Duration        // coarsest precision of the upstreams
+}
+
+// upstream represents one of the remotes which make up the combine
+type upstream struct {
+	f   fs.Fs  // the remote
+	dir string // directory the upstream is mounted at in the root
+}
+
+// parseUpstream parses an upstream of the form dir=remote:path
+func parseUpstream(s string) (dir, remote string, err error) {
+	equal := strings.IndexRune(s, '=')
+	if equal < 0 {
+		return "", "", errors.Errorf("no \"=\" in upstream %q", s)
+	}
+	dir, remote = strings.Trim(s[:equal], "/"), s[equal+1:]
+	if dir == "" {
+		return "", "", errors.Errorf("empty directory in upstream %q", s)
+	}
+	if strings.ContainsRune(dir, '/') {
+		return "", "", errors.Errorf("directory %q in upstream %q must not contain \"/\"", dir, s)
+	}
+	if remote == "" {
+		return "", "", errors.Errorf("empty remote in upstream %q", s)
+	}
+	return dir, remote, nil
+}
+
+// joinRemote adds subPath onto the end of the remote given
+func joinRemote(remote, subPath string) (string, error) {
+	_, configName, fsPath, err := fs.ParseRemote(remote)
+	if err != nil {
+		return "", err
+	}
+	fsPath = path.Join(fsPath, filepath.ToSlash(subPath))
+	if configName == "local" {
+		return fsPath, nil
+	}
+	return configName + ":" + fsPath, nil
+}
+
+// NewFs contstructs an Fs from the path.
+//
+// If the path is inside one of the upstreams then the Fs of that
+// upstream is returned directly.
+func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.Upstreams) == 0 {
+		return nil, errors.New("combine can't have empty upstreams - check the value of the upstreams setting")
+	}
+	remoteName, _ := fspath.ParseConfigName(name)
+	remotes := make(map[string]string, len(opt.Upstreams))
+	for _, s := range opt.Upstreams {
+		dir, remote, err := parseUpstream(s)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(remote, remoteName+":") {
+			return nil, errors.New("can't point combine remote at itself - check the value of the upstreams setting")
+		}
+		if _, found := remotes[dir]; found {
+			return nil, errors.Errorf("duplicate directory %q in upstreams", dir)
+		}
+		remotes[dir] = remote
+	}
+
+	// If the root is inside an upstream then return that directly
+	root = strings.Trim(path.Clean("/"+filepath.ToSlash(root)), "/")
+	if root != "" {
+		dir, subPath := root, ""
+		if slash := strings.IndexRune(root, '/'); slash >= 0 {
+			dir, subPath = root[:slash], root[slash+1:]
+		}
+		remote, found := remotes[dir]
+		if !found {
+			return nil, fs.ErrorDirNotFound
+		}
+		remote, err = joinRemote(remote, subPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse upstream %q", dir)
+		}
+		return fs.NewFs(remote)
+	}
+
+	f := &Fs{
+		name:      name,
+		root:      root,
+		opt:       *opt,
+		upstreams: make(map[string]*upstream, len(remotes)),
+		hashSet:   hash.Supported,
+	}
+	for dir, remote := range remotes {
+		uFs, err := fs.NewFs(remote)
+		if err == fs.ErrorIsFile {
+			return nil, errors.Errorf("upstream %q must point to a directory not a file", dir)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to make upstream %q", dir)
+		}
+		f.upstreams[dir] = &upstream{
+			f:   uFs,
+			dir: dir,
+		}
+		f.dirs = append(f.dirs, dir)
+	}
+	sort.Strings(f.dirs)
+
+	// the features here are ones we could support, and they are
+	// ANDed with the ones from the upstreams
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          false,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+	}).Fill(f)
+	canPutStream, canChangeNotify := true, false
+	for _, dir := range f.dirs {
+		u := f.upstreams[dir]
+		features := u.f.Features()
+		f.features.CaseInsensitive = f.features.CaseInsensitive && features.CaseInsensitive
+		f.features.DuplicateFiles = f.features.DuplicateFiles || features.DuplicateFiles
+		f.features.ReadMimeType = f.features.ReadMimeType && features.ReadMimeType
+		f.features.WriteMimeType = f.features.WriteMimeType && features.WriteMimeType
+		f.features.CanHaveEmptyDirectories = f.features.CanHaveEmptyDirectories && features.CanHaveEmptyDirectories
+		f.features.BucketBased = f.features.BucketBased && features.BucketBased
+		canPutStream = canPutStream && features.PutStream != nil
+		canChangeNotify = canChangeNotify || features.ChangeNotify != nil
+		f.hashSet = f.hashSet.Overlap(u.f.Hashes())
+		if precision := u.f.Precision(); precision > f.precision {
+			f.precision = precision
+		}
+	}
+	if !canPutStream {
+		f.features.PutStream = nil
+	}
+	if !canChangeNotify {
+		f.features.ChangeNotify = nil
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("Combined remote '%s:%s'", f.name, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision is the coarsest precision of all the upstreams
+func (f *Fs) Precision() time.Duration {
+	return f.precision
+}
+
+// Hashes returns the hash types supported by all the upstreams
+func (f *Fs) Hashes() hash.Set {
+	return f.hashSet
+}
+
+// findUpstream looks up the upstream for remote, returning the
+// upstream and the path of remote within it.
+//
+// It returns fs.ErrorDirNotFound if there is no upstream for remote.
+func (f *Fs) findUpstream(remote string) (u *upstream, uRemote string, err error) {
+	dir := remote
+	if slash := strings.IndexRune(remote, '/'); slash >= 0 {
+		dir, uRemote = remote[:slash], remote[slash+1:]
+	}
+	u, found := f.upstreams[dir]
+	if !found {
+		return nil, "", fs.ErrorDirNotFound
+	}
+	return u, uRemote, nil
+}
+
+// findUpstreamObject looks up the upstream for the object at remote
+func (f *Fs) findUpstreamObject(remote string) (u *upstream, uRemote string, err error) {
+	u, uRemote, err = f.findUpstream(remote)
+	if err != nil {
+		return nil, "", errors.Errorf("can't store %q - it must be inside one of the upstream directories %q", remote, f.dirs)
+	}
+	if uRemote == "" {
+		return nil, "", errors.Errorf("can't store %q - it is the root of an upstream", remote)
+	}
+	return u, uRemote, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
+	if dir == "" {
+		for _, dir := range f.dirs {
+			entries = append(entries, fs.NewDir(dir, time.Time{}))
+		}
+		return entries, nil
+	}
+	u, uDir, err := f.findUpstream(dir)
+	if err != nil {
+		return nil, err
+	}
+	uEntries, err := u.f.List(uDir)
+	if err != nil {
+		return nil, err
+	}
+	entries = make(fs.DirEntries, 0, len(uEntries))
+	for _, entry := range uEntries {
+		switch x := entry.(type) {
+		case fs.Object:
+			entries = append(entries, f.newObject(u, x))
+		case fs.Directory:
+			newDir := fs.NewDirCopy(x)
+			newDir.SetRemote(path.Join(u.dir, x.Remote()))
+			entries = append(entries, newDir)
+		default:
+			return nil, errors.Errorf("unknown object type %T", entry)
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(remote string) (fs.Object, error) {
+	u, uRemote, err := f.findUpstream(remote)
+	if err != nil || uRemote == "" {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := u.f.NewObject(uRemote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(u, o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	u, uRemote, err := f.findUpstreamObject(src.Remote())
+	if err != nil {
+		return nil, err
+	}
+	o, err := u.f.Put(in, newObjectInfo(src, uRemote), options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(u, o), nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	u, uRemote, err := f.findUpstreamObject(src.Remote())
+	if err != nil {
+		return nil, err
+	}
+	do := u.f.Features().PutStream
+	if do == nil {
+		return nil, errors.New("can't PutStream")
+	}
+	o, err := do(in, newObjectInfo(src, uRemote), options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(u, o), nil
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	u, uDir, err := f.findUpstream(dir)
+	if err != nil {
+		return errors.Errorf("can't create directory %q - it must be inside one of the upstream directories %q", dir, f.dirs)
+	}
+	return u.f.Mkdir(uDir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(dir string) error {
+	if dir == "" {
+		// The root always contains the upstream directories
+		return fs.ErrorDirectoryNotEmpty
+	}
+	u, uDir, err := f.findUpstream(dir)
+	if err != nil {
+		return err
+	}
+	return u.f.Rmdir(uDir)
+}
+
+// Copy src to this remote using server side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	u, uRemote, err := f.findUpstreamObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	do := u.f.Features().Copy
+	if do == nil || srcObj.Object.Fs().Name() != u.f.Name() {
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := do(srcObj.Object, uRemote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(u, o), nil
+}
+
+// Move src to this remote using server side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	u, uRemote, err := f.findUpstreamObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	do := u.f.Features().Move
+	if do == nil || srcObj.Object.Fs().Name() != u.f.Name() {
+		return nil, fs.ErrorCantMove
+	}
+	o, err := do(srcObj.Object, uRemote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(u, o), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(src, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcU, srcURemote, err := srcFs.findUpstream(srcRemote)
+	if err != nil || srcURemote == "" {
+		return fs.ErrorCantDirMove
+	}
+	dstU, dstURemote, err := f.findUpstream(dstRemote)
+	if err != nil || dstURemote == "" {
+		return fs.ErrorCantDirMove
+	}
+	do := dstU.f.Features().DirMove
+	if do == nil || srcU.f.Name() != dstU.f.Name() {
+		return fs.ErrorCantDirMove
+	}
+	return do(srcU.f, srcURemote, dstURemote)
+}
+
+// ChangeNotify calls the passed function with a path
+// that has had changes. If the implementation
+// uses polling, it should adhere to the given interval.
+func (f *Fs) ChangeNotify(notifyFunc func(string, fs.EntryType), pollInterval time.Duration) chan bool {
+	var quits []chan bool
+	for _, dir := range f.dirs {
+		u := f.upstreams[dir]
+		do := u.f.Features().ChangeNotify
+		if do == nil {
+			continue
+		}
+		wrappedNotifyFunc := func(path string, entryType fs.EntryType) {
+			notifyFunc(u.dir+"/"+path, entryType)
+		}
+		quits = append(quits, do(wrappedNotifyFunc, pollInterval))
+	}
+	quit := make(chan bool)
+	go func() {
+		<-quit
+		for _, upstreamQuit := range quits {
+			close(upstreamQuit)
+		}
+	}()
+	return quit
+}
+
+// Object describes an object in one of the upstreams
+type Object struct {
+	fs.Object
+	f *Fs
+	u *upstream
+}
+
+func (f *Fs) newObject(u *upstream, o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+		u:      u,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return path.Join(o.u.dir, o.Object.Remote())
+}
+
+// MimeType returns the content type of the Object if
+// known, or "" if not
+func (o *Object) MimeType() string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType()
+	}
+	return ""
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return o.Object.Update(in, newObjectInfo(src, o.Object.Remote()), options...)
+}
+
+// ObjectInfo describes a source object with the remote name
+// translated to be relative to the upstream
+type ObjectInfo struct {
+	fs.ObjectInfo
+	remote string
+}
+
+func newObjectInfo(src fs.ObjectInfo, remote string) *ObjectInfo {
+	return &ObjectInfo{
+		ObjectInfo: src,
+		remote:     remote,
+	}
+}
+
+// Remote returns the remote path
+func (o *ObjectInfo) Remote() string {
+	return o.remote
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.ObjectInfo      = (*ObjectInfo)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+)
@@ -0,0 +1,163 @@
+package combine
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local" // pull in test backend
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	remoteName = "TestCombine"
+)
+
+func prepare(t *testing.T, upstreams string) {
+	config.LoadConfig()
+
+	// Configure the remote
+	config.FileSet(remoteName, "type", "combine")
+	config.FileSet(remoteName, "upstreams", upstreams)
+}
+
+// makeUpstreams makes two local directories with some files in and
+// returns the directory they are in
+func makeUpstreams(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rclone-combine-test")
+	require.NoError(t, err)
+	for _, file := range []string{"one/file1.txt", "one/sub/file2.txt", "two/file3.txt"} {
+		file = filepath.Join(dir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0777))
+		require.NoError(t, ioutil.WriteFile(file, []byte(file), 0666))
+	}
+	return dir
+}
+
+func listRemotes(t *testing.T, f fs.Fs, dir string) (remotes []string) {
+	entries, err := f.List(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		remote := entry.Remote()
+		if _, isDir := entry.(fs.Directory); isDir {
+			remote += "/"
+		}
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	return remotes
+}
+
+func TestParseUpstream(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		dir     string
+		remote  string
+		wantErr bool
+	}{
+		{"dir=remote:path", "dir", "remote:path", false},
+		{"/dir/=remote:", "dir", "remote:", false},
+		{"dir=/local/path=with=equals", "dir", "/local/path=with=equals", false},
+		{"dir", "", "", true},
+		{"=remote:path", "", "", true},
+		{"dir/sub=remote:path", "", "", true},
+		{"dir=", "", "", true},
+	} {
+		dir, remote, err := parseUpstream(test.in)
+		what := fmt.Sprintf("in=%q", test.in)
+		if test.wantErr {
+			assert.Error(t, err, what)
+			continue
+		}
+		require.NoError(t, err, what)
+		assert.Equal(t, test.dir, dir, what)
+		assert.Equal(t, test.remote, remote, what)
+	}
+}
+
+func TestNewFS(t *testing.T) {
+	dir := makeUpstreams(t)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	prepare(t, fmt.Sprintf("a=%s b=%s", filepath.Join(dir, "one"), filepath.Join(dir, "two")))
+
+	f, err := fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/", "b/"}, listRemotes(t, f, ""))
+	assert.Equal(t, []string{"a/file1.txt", "a/sub/"}, listRemotes(t, f, "a"))
+	assert.Equal(t, []string{"a/sub/file2.txt"}, listRemotes(t, f, "a/sub"))
+	assert.Equal(t, []string{"b/file3.txt"}, listRemotes(t, f, "b"))
+
+	_, err = f.List("c")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	o, err := f.NewObject("a/sub/file2.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a/sub/file2.txt", o.Remote())
+	_, err = f.NewObject("a")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// A root inside an upstream should return the upstream
+	f, err = fs.NewFs(remoteName + ":a/sub")
+	require.NoError(t, err)
+	assert.Equal(t, "local", f.Name())
+	assert.Equal(t, []string{"file2.txt"}, listRemotes(t, f, ""))
+
+	_, err = fs.NewFs(remoteName + ":c")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+}
+
+func TestNewFSInvalidUpstreams(t *testing.T) {
+	for _, upstreams := range []string{
+		"",
+		"a",
+		"a=/tmp a=/tmp",
+		"a=" + remoteName + ":",
+		"a=not_existing_test_remote:",
+	} {
+		prepare(t, upstreams)
+		f, err := fs.NewFs(remoteName + ":")
+		assert.Error(t, err, upstreams)
+		assert.Nil(t, f, upstreams)
+	}
+}
+
+func TestPutMove(t *testing.T) {
+	dir := makeUpstreams(t)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	prepare(t, fmt.Sprintf("a=%s b=%s", filepath.Join(dir, "one"), filepath.Join(dir, "two")))
+	f, err := fs.NewFs(remoteName + ":")
+	require.NoError(t, err)
+
+	contents := []byte("hello")
+	src := object.NewStaticObjectInfo("b/new/file4.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(bytes.NewBuffer(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, "b/new/file4.txt", o.Remote())
+	_, err = os.Stat(filepath.Join(dir, "two", "new", "file4.txt"))
+	require.NoError(t, err)
+
+	// Can't upload outside the upstreams
+	src = object.NewStaticObjectInfo("file5.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	_, err = f.Put(bytes.NewBuffer(contents), src)
+	assert.Error(t, err)
+
+	// Server side move between two local upstreams
+	o, err = f.Features().Move(o, "a/file4.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a/file4.txt", o.Remote())
+	assert.Equal(t, []string{"a/file1.txt", "a/file4.txt", "a/sub/"}, listRemotes(t, f, "a"))
+	assert.Equal(t, []string{"b/file3.txt", "b/new/"}, listRemotes(t, f, "b"))
+}
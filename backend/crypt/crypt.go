@@ -13,6 +13,7 @@ import (
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fspath"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/pkg/errors"
 )
@@ -127,7 +128,8 @@ func NewFs(name, rpath string, m configmap.Mapper) (fs.Fs, error) {
 		return nil, err
 	}
 	remote := opt.Remote
-	if strings.HasPrefix(remote, name+":") {
+	remoteName, _ := fspath.ParseConfigName(name)
+	if strings.HasPrefix(remote, remoteName+":") {
 		return nil, errors.New("can't point crypt remote at itself - check the value of the remote setting")
 	}
 	// Look for a file first
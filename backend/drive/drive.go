@@ -12,7 +12,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
@@ -101,13 +100,13 @@ func init() {
 		Name:        "drive",
 		Description: "Google Drive",
 		NewFs:       NewFs,
-		Config: func(name string, m configmap.Mapper) {
+		CommandHelp: commandHelp,
+		Config: func(name string, m configmap.Mapper, in fs.ConfigIn) (*fs.ConfigOut, error) {
 			// Parse config into Options struct
 			opt := new(Options)
 			err := configstruct.Set(m, opt)
 			if err != nil {
-				fs.Errorf(nil, "Couldn't parse config into struct: %v", err)
-				return
+				return nil, errors.Wrap(err, "couldn't parse config into struct")
 			}
 			// Fill in the scopes
 			if opt.Scope == "" {
@@ -121,16 +120,30 @@ func init() {
 					m.Set("root_folder_id", "appDataFolder")
 				}
 			}
-			if opt.ServiceAccountFile == "" {
-				err = oauthutil.Config("drive", name, m, driveConfig)
-				if err != nil {
-					log.Fatalf("Failed to configure token: %v", err)
+			switch in.State {
+			case "":
+				if opt.ServiceAccountFile == "" {
+					return oauthutil.ConfigOut("teamdrive", &oauthutil.Options{
+						OAuth2Config: driveConfig,
+					})
 				}
+				return fs.ConfigGoto("teamdrive")
+			case "teamdrive":
+				help := "Configure this as a team drive?"
+				if opt.TeamDriveID != "" {
+					help = fmt.Sprintf("Change current team drive ID %q?", opt.TeamDriveID)
+				}
+				return fs.ConfigConfirm("teamdrive_ok", false, "config_change_team_drive", help)
+			case "teamdrive_ok":
+				if in.Result == "false" {
+					return nil, nil
+				}
+				return configTeamDrive(opt, m, name)
+			case "teamdrive_final":
+				m.Set("team_drive", in.Result)
+				return nil, nil
 			}
-			err = configTeamDrive(opt, m, name)
-			if err != nil {
-				log.Fatalf("Failed to configure team drive: %v", err)
-			}
+			return nil, errors.Errorf("unknown state %q", in.State)
 		},
 		Options: []fs.Option{{
 			Name: config.ConfigClientID,
@@ -253,6 +266,43 @@ func init() {
 	}
 }
 
+// commandHelp describes the backend commands
+var commandHelp = []fs.CommandHelp{{
+	Name:  "drives",
+	Short: "List the team drives available to this account",
+	Long: `This command lists the team drives available to this account
+as a JSON list of objects with their id and name.
+
+    rclone backend drives drive:
+
+The id can be used as the team_drive config value to make a remote
+for the team drive.
+`,
+}, {
+	Name:  "copyid",
+	Short: "Copy files by ID",
+	Long: `This command copies files by ID to the remote.
+
+Usage:
+
+    rclone backend copyid drive: ID path
+    rclone backend copyid drive: ID1 path1 ID2 path2
+
+It copies the drive file with ID given to the path, which is
+relative to the root of the remote.  The ID and path pairs can be
+repeated.
+
+The path should end with a / to indicate copy the file as named to
+this directory.  If it doesn't end with a / then the last path
+component will be used as the file name.
+
+This can be used to copy files which have been shared with you, for
+example from a link, into your drive without having to find them in
+the "Shared with me" listing first.  The copy is done server side.
+Google documents can't be copied this way.
+`,
+}}
+
 // Options defines the configuration for this backend
 type Options struct {
 	Scope                     string        `config:"scope"`
@@ -528,54 +578,50 @@ func (f *Fs) parseExtensions(extensions string) error {
 	return nil
 }
 
-// Figure out if the user wants to use a team drive
-func configTeamDrive(opt *Options, m configmap.Mapper, name string) error {
-	if opt.TeamDriveID == "" {
-		fmt.Printf("Configure this as a team drive?\n")
-	} else {
-		fmt.Printf("Change current team drive ID %q?\n", opt.TeamDriveID)
-	}
-	if !config.ConfirmWithDefault(false) {
-		return nil
-	}
+// configTeamDrive lists the team drives for the user to choose from
+func configTeamDrive(opt *Options, m configmap.Mapper, name string) (*fs.ConfigOut, error) {
 	client, err := createOAuthClient(opt, name, m)
 	if err != nil {
-		return errors.Wrap(err, "config team drive failed to create oauth client")
+		return nil, errors.Wrap(err, "config team drive failed to create oauth client")
 	}
 	svc, err := drive.New(client)
 	if err != nil {
-		return errors.Wrap(err, "config team drive failed to make drive client")
+		return nil, errors.Wrap(err, "config team drive failed to make drive client")
 	}
 	fmt.Printf("Fetching team drive list...\n")
-	var driveIDs, driveNames []string
+	teamDrives, err := listTeamDrives(svc, newPacer())
+	if err != nil {
+		return nil, err
+	}
+	if len(teamDrives) == 0 {
+		fmt.Printf("No team drives found in your account\n")
+		return fs.ConfigResult("teamdrive_final", "")
+	}
+	return fs.ConfigChoose("teamdrive_final", "config_team_drive", "Team Drive", len(teamDrives), func(i int) (string, string) {
+		return teamDrives[i].Id, teamDrives[i].Name
+	})
+}
+
+// listTeamDrives lists all the team drives available using svc
+func listTeamDrives(svc *drive.Service, p *pacer.Pacer) (teamDrives []*drive.TeamDrive, err error) {
+	teamDrives = []*drive.TeamDrive{}
 	listTeamDrives := svc.Teamdrives.List().PageSize(100)
 	for {
-		var teamDrives *drive.TeamDriveList
-		err = newPacer().Call(func() (bool, error) {
-			teamDrives, err = listTeamDrives.Do()
+		var teamDriveList *drive.TeamDriveList
+		err = p.Call(func() (bool, error) {
+			teamDriveList, err = listTeamDrives.Do()
 			return shouldRetry(err)
 		})
 		if err != nil {
-			return errors.Wrap(err, "list team drives failed")
-		}
-		for _, drive := range teamDrives.TeamDrives {
-			driveIDs = append(driveIDs, drive.Id)
-			driveNames = append(driveNames, drive.Name)
+			return nil, errors.Wrap(err, "list team drives failed")
 		}
-		if teamDrives.NextPageToken == "" {
+		teamDrives = append(teamDrives, teamDriveList.TeamDrives...)
+		if teamDriveList.NextPageToken == "" {
 			break
 		}
-		listTeamDrives.PageToken(teamDrives.NextPageToken)
+		listTeamDrives.PageToken(teamDriveList.NextPageToken)
 	}
-	var driveID string
-	if len(driveIDs) == 0 {
-		fmt.Printf("No team drives found in your account")
-	} else {
-		driveID = config.Choose("Enter a Team Drive ID", driveIDs, driveNames, true)
-	}
-	m.Set("team_drive", driveID)
-	opt.TeamDriveID = driveID
-	return nil
+	return teamDrives, nil
 }
 
 // newPacer makes a pacer configured for drive
@@ -583,7 +629,7 @@ func newPacer() *pacer.Pacer {
 	return pacer.New().SetMinSleep(minSleep).SetPacer(pacer.GoogleDrivePacer)
 }
 
-func getServiceAccountClient(opt *Options, credentialsData []byte) (*http.Client, error) {
+func getServiceAccountClient(opt *Options, ci *fs.ConfigInfo, credentialsData []byte) (*http.Client, error) {
 	conf, err := google.JWTConfigFromJSON(credentialsData, driveConfig.Scopes...)
 	if err != nil {
 		return nil, errors.Wrap(err, "error processing credentials")
@@ -591,7 +637,7 @@ func getServiceAccountClient(opt *Options, credentialsData []byte) (*http.Client
 	if opt.Impersonate != "" {
 		conf.Subject = opt.Impersonate
 	}
-	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.Config))
+	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(ci))
 	return oauth2.NewClient(ctxWithSpecialClient, conf.TokenSource(ctxWithSpecialClient)), nil
 }
 
@@ -608,7 +654,7 @@ func createOAuthClient(opt *Options, name string, m configmap.Mapper) (*http.Cli
 		opt.ServiceAccountCredentials = string(loadedCreds)
 	}
 	if opt.ServiceAccountCredentials != "" {
-		oAuthClient, err = getServiceAccountClient(opt, []byte(opt.ServiceAccountCredentials))
+		oAuthClient, err = getServiceAccountClient(opt, fs.GetConfigMap(name, m), []byte(opt.ServiceAccountCredentials))
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create oauth client from service account")
 		}
@@ -2066,6 +2112,92 @@ func (o *Object) ID() string {
 	return o.id
 }
 
+// teamDrive describes a team drive for the drives command
+type teamDrive struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// drives lists the team drives available to this account
+func (f *Fs) drives() (out []teamDrive, err error) {
+	teamDrives, err := listTeamDrives(f.svc, f.pacer)
+	if err != nil {
+		return nil, err
+	}
+	out = make([]teamDrive, len(teamDrives))
+	for i, td := range teamDrives {
+		out[i] = teamDrive{ID: td.Id, Name: td.Name}
+	}
+	return out, nil
+}
+
+// copyID copies the drive file with id to dest
+//
+// If dest ends with / then the file is copied into that directory
+// with its own name
+func (f *Fs) copyID(id, dest string) (err error) {
+	var info *drive.File
+	err = f.pacer.Call(func() (bool, error) {
+		info, err = f.svc.Files.Get(id).Fields(googleapi.Field(partialFields)).SupportsTeamDrives(true).Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "couldn't find id %q", id)
+	}
+	if info.MimeType == driveFolderType {
+		return errors.Errorf("can't copy directory %q - use ordinary rclone copy", id)
+	}
+	if strings.HasPrefix(info.MimeType, "application/vnd.google-apps.") {
+		return errors.Errorf("can't copy Google document %q", id)
+	}
+	if dest == "" || strings.HasSuffix(dest, "/") {
+		dest += info.Name
+	}
+	dest = strings.Trim(dest, "/")
+	src, err := f.newObjectWithInfo(info.Name, info)
+	if err != nil {
+		return err
+	}
+	if fs.Config.DryRun {
+		fs.Logf(dest, "Not copying id %q as --dry-run", id)
+		return nil
+	}
+	_, err = f.Copy(src, dest)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy id %q to %q", id, dest)
+	}
+	return nil
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "drives":
+		return f.drives()
+	case "copyid":
+		if len(arg)%2 != 0 {
+			return nil, errors.New("need an even number of arguments")
+		}
+		for i := 0; i < len(arg); i += 2 {
+			err = f.copyID(arg[i], arg[i+1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs              = (*Fs)(nil)
@@ -2082,6 +2214,7 @@ var (
 	_ fs.ListRer         = (*Fs)(nil)
 	_ fs.MergeDirser     = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
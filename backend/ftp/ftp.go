@@ -2,7 +2,10 @@
 package ftp
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"io"
+	"io/ioutil"
 	"net/textproto"
 	"os"
 	"path"
@@ -15,6 +18,7 @@ import (
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/pacer"
 	"github.com/ncw/rclone/lib/readers"
 	"github.com/pkg/errors"
 )
@@ -39,12 +43,55 @@ func init() {
 				Help: "FTP username, leave blank for current username, " + os.Getenv("USER"),
 			}, {
 				Name: "port",
-				Help: "FTP port, leave blank to use default (21)",
+				Help: "FTP port, leave blank to use default (21, or 990 for implicit FTPS)",
 			}, {
 				Name:       "pass",
 				Help:       "FTP password",
 				IsPassword: true,
 				Required:   true,
+			}, {
+				Name: "tls",
+				Help: `Use implicit FTPS (FTP over TLS)
+
+When using implicit FTP over TLS the client connects using TLS
+right from the start, which breaks compatibility with non-TLS-aware
+servers. This is usually served over port 990 rather than port 21.
+Cannot be used in combination with explicit FTPS.`,
+				Default: false,
+			}, {
+				Name: "explicit_tls",
+				Help: `Use explicit FTPS (FTP over TLS)
+
+When using explicit FTP over TLS the client explicitly requests
+security from the server in order to upgrade a plain text connection
+to an encrypted one with the AUTH TLS command. Cannot be used in
+combination with implicit FTPS.`,
+				Default: false,
+			}, {
+				Name:     "no_check_certificate",
+				Help:     "Do not verify the TLS certificate of the server",
+				Default:  false,
+				Advanced: true,
+			}, {
+				Name: "ca_cert",
+				Help: `Path to a PEM encoded CA certificate to verify the server with
+
+If set then only servers with a certificate signed by this CA will be
+trusted, rather than those signed by the system CAs.`,
+				Advanced: true,
+			}, {
+				Name: "concurrency",
+				Help: `Maximum number of FTP simultaneous connections, 0 for unlimited
+
+Set this if the server rejects connections when --transfers and
+--checkers are high.
+
+Note that a transfer holds a connection for its whole duration, so
+when copying between two paths on the same FTP server make sure this
+is at least one more than --transfers plus --checkers otherwise rclone
+may deadlock.`,
+				Default:  0,
+				Advanced: true,
 			},
 		},
 	})
@@ -52,24 +99,31 @@ func init() {
 
 // Options defines the configuration for this backend
 type Options struct {
-	Host string `config:"host"`
-	User string `config:"user"`
-	Pass string `config:"pass"`
-	Port string `config:"port"`
+	Host               string `config:"host"`
+	User               string `config:"user"`
+	Pass               string `config:"pass"`
+	Port               string `config:"port"`
+	TLS                bool   `config:"tls"`
+	ExplicitTLS        bool   `config:"explicit_tls"`
+	NoCheckCertificate bool   `config:"no_check_certificate"`
+	CACert             string `config:"ca_cert"`
+	Concurrency        int    `config:"concurrency"`
 }
 
 // Fs represents a remote FTP server
 type Fs struct {
-	name     string       // name of this remote
-	root     string       // the path we are working on if any
-	opt      Options      // parsed options
-	features *fs.Features // optional features
-	url      string
-	user     string
-	pass     string
-	dialAddr string
-	poolMu   sync.Mutex
-	pool     []*ftp.ServerConn
+	name      string       // name of this remote
+	root      string       // the path we are working on if any
+	opt       Options      // parsed options
+	features  *fs.Features // optional features
+	url       string
+	user      string
+	pass      string
+	dialAddr  string
+	tlsConfig *tls.Config // set if using FTPS
+	poolMu    sync.Mutex
+	pool      []*ftp.ServerConn
+	tokens    *pacer.TokenDispenser // limits the number of connections if set
 }
 
 // Object describes an FTP file
@@ -112,7 +166,13 @@ func (f *Fs) Features() *fs.Features {
 // Open a new connection to the FTP server.
 func (f *Fs) ftpConnection() (*ftp.ServerConn, error) {
 	fs.Debugf(f, "Connecting to FTP server")
-	c, err := ftp.DialTimeout(f.dialAddr, fs.Config.ConnectTimeout)
+	ftpConfig := []ftp.DialOption{ftp.DialWithTimeout(fs.GetConfigFs(f).ConnectTimeout)}
+	if f.opt.TLS {
+		ftpConfig = append(ftpConfig, ftp.DialWithTLS(f.tlsConfig))
+	} else if f.opt.ExplicitTLS {
+		ftpConfig = append(ftpConfig, ftp.DialWithExplicitTLS(f.tlsConfig))
+	}
+	c, err := ftp.Dial(f.dialAddr, ftpConfig...)
 	if err != nil {
 		fs.Errorf(f, "Error while Dialing %s: %s", f.dialAddr, err)
 		return nil, errors.Wrap(err, "ftpConnection Dial")
@@ -127,7 +187,13 @@ func (f *Fs) ftpConnection() (*ftp.ServerConn, error) {
 }
 
 // Get an FTP connection from the pool, or open a new one
+//
+// If concurrency is limited this waits until a connection is
+// available.
 func (f *Fs) getFtpConnection() (c *ftp.ServerConn, err error) {
+	if f.tokens != nil {
+		f.tokens.Get()
+	}
 	f.poolMu.Lock()
 	if len(f.pool) > 0 {
 		c = f.pool[0]
@@ -137,7 +203,23 @@ func (f *Fs) getFtpConnection() (c *ftp.ServerConn, err error) {
 	if c != nil {
 		return c, nil
 	}
-	return f.ftpConnection()
+	c, err = f.ftpConnection()
+	if err != nil && f.tokens != nil {
+		f.tokens.Put()
+	}
+	return c, err
+}
+
+// Close an FTP connection which can't be returned to the pool
+//
+// It nils the pointed to connection out so it can't be reused
+func (f *Fs) closeFtpConnection(pc **ftp.ServerConn) {
+	c := *pc
+	*pc = nil
+	_ = c.Quit()
+	if f.tokens != nil {
+		f.tokens.Put()
+	}
 }
 
 // Return an FTP connection to the pool
@@ -147,23 +229,49 @@ func (f *Fs) getFtpConnection() (c *ftp.ServerConn, err error) {
 // if err is not nil then it checks the connection is alive using a
 // NOOP request
 func (f *Fs) putFtpConnection(pc **ftp.ServerConn, err error) {
-	c := *pc
-	*pc = nil
 	if err != nil {
 		// If not a regular FTP error code then check the connection
 		_, isRegularError := errors.Cause(err).(*textproto.Error)
 		if !isRegularError {
-			nopErr := c.NoOp()
+			nopErr := (*pc).NoOp()
 			if nopErr != nil {
 				fs.Debugf(f, "Connection failed, closing: %v", nopErr)
-				_ = c.Quit()
+				f.closeFtpConnection(pc)
 				return
 			}
 		}
 	}
+	c := *pc
+	*pc = nil
 	f.poolMu.Lock()
 	f.pool = append(f.pool, c)
 	f.poolMu.Unlock()
+	if f.tokens != nil {
+		f.tokens.Put()
+	}
+}
+
+// newTLSConfig makes the TLS config for connecting with FTPS
+func newTLSConfig(opt *Options) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         opt.Host,
+		InsecureSkipVerify: opt.NoCheckCertificate || fs.Config.InsecureSkipVerify,
+		// Cache the sessions so the data connections can resume
+		// the TLS session of the control connection as many
+		// servers require this.
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+	if opt.CACert != "" {
+		pem, err := ioutil.ReadFile(opt.CACert)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read ca_cert")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in ca_cert %q", opt.CACert)
+		}
+	}
+	return tlsConfig, nil
 }
 
 // NewFs contstructs an Fs from the path, container:path
@@ -183,21 +291,40 @@ func NewFs(name, root string, m configmap.Mapper) (ff fs.Fs, err error) {
 	if user == "" {
 		user = os.Getenv("USER")
 	}
+	if opt.TLS && opt.ExplicitTLS {
+		return nil, errors.New("implicit TLS and explicit TLS are mutually incompatible - please revise your config")
+	}
 	port := opt.Port
 	if port == "" {
 		port = "21"
+		if opt.TLS {
+			port = "990"
+		}
+	}
+	var tlsConfig *tls.Config
+	protocol := "ftp://"
+	if opt.TLS || opt.ExplicitTLS {
+		tlsConfig, err = newTLSConfig(opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "NewFS")
+		}
+		protocol = "ftps://"
 	}
 
 	dialAddr := opt.Host + ":" + port
-	u := "ftp://" + path.Join(dialAddr+"/", root)
+	u := protocol + path.Join(dialAddr+"/", root)
 	f := &Fs{
-		name:     name,
-		root:     root,
-		opt:      *opt,
-		url:      u,
-		user:     user,
-		pass:     pass,
-		dialAddr: dialAddr,
+		name:      name,
+		root:      root,
+		opt:       *opt,
+		url:       u,
+		user:      user,
+		pass:      pass,
+		dialAddr:  dialAddr,
+		tlsConfig: tlsConfig,
+	}
+	if opt.Concurrency > 0 {
+		f.tokens = pacer.NewTokenDispenser(opt.Concurrency)
 	}
 	f.features = (&fs.Features{
 		CanHaveEmptyDirectories: true,
@@ -649,7 +776,7 @@ func (f *ftpReadCloser) Close() error {
 	err := f.rc.Close()
 	// if errors while reading or closing, dump the connection
 	if err != nil || f.err != nil {
-		_ = f.c.Quit()
+		f.f.closeFtpConnection(&f.c)
 	} else {
 		f.f.putFtpConnection(&f.c, nil)
 	}
@@ -717,7 +844,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	}
 	err = c.Stor(path, in)
 	if err != nil {
-		_ = c.Quit()
+		o.fs.closeFtpConnection(&c)
 		remove()
 		return errors.Wrap(err, "update stor")
 	}
@@ -0,0 +1,249 @@
+package ftp
+
+import (
+	"bufio"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is a minimal FTP server which accepts any login
+type fakeServer struct {
+	listener    net.Listener
+	connections int32 // number of connections accepted
+}
+
+// newFakeServer starts a fakeServer listening on localhost
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&s.connections, 1)
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// serve a single control connection
+func (s *fakeServer) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	reply := func(line string) bool {
+		_, err := conn.Write([]byte(line + "\r\n"))
+		return err == nil
+	}
+	if !reply("220 ready") {
+		return
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.Fields(line)[0]
+		switch command {
+		case "QUIT":
+			reply("221 bye")
+			return
+		case "FEAT":
+			reply("502 not implemented")
+		case "USER":
+			reply("230 logged in")
+		default:
+			reply("200 ok")
+		}
+	}
+}
+
+// Close the server
+func (s *fakeServer) Close() {
+	_ = s.listener.Close()
+}
+
+// newTestFs makes an Fs connecting to addr with the given concurrency
+func newTestFs(addr string, concurrency int) *Fs {
+	f := &Fs{
+		opt:      Options{Concurrency: concurrency},
+		user:     "rclone",
+		dialAddr: addr,
+	}
+	f.features = (&fs.Features{}).Fill(f)
+	if concurrency > 0 {
+		f.tokens = pacer.NewTokenDispenser(concurrency)
+	}
+	return f
+}
+
+func TestConcurrencyTokens(t *testing.T) {
+	s := newFakeServer(t)
+	defer s.Close()
+	f := newTestFs(s.listener.Addr().String(), 1)
+
+	c, err := f.getFtpConnection()
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&s.connections))
+
+	// A second connection must wait until the first is returned
+	got := make(chan error)
+	var c2 *ftp.ServerConn
+	go func() {
+		var err error
+		c2, err = f.getFtpConnection()
+		got <- err
+	}()
+	select {
+	case <-got:
+		t.Fatal("got a second connection with concurrency 1")
+	case <-time.After(100 * time.Millisecond):
+	}
+	f.putFtpConnection(&c, nil)
+	assert.Nil(t, c)
+	select {
+	case err = <-got:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a connection")
+	}
+	// the connection was reused from the pool
+	assert.Equal(t, int32(1), atomic.LoadInt32(&s.connections))
+
+	// Closing a connection returns its token too
+	f.closeFtpConnection(&c2)
+	assert.Nil(t, c2)
+	c, err = f.getFtpConnection()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&s.connections))
+	f.putFtpConnection(&c, nil)
+}
+
+func TestConcurrencyTokensDialError(t *testing.T) {
+	// find an address with nothing listening on it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	f := newTestFs(addr, 1)
+
+	// A failed dial must return its token otherwise the second
+	// call would block forever
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2; i++ {
+			_, err := f.getFtpConnection()
+			assert.Error(t, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("token not returned after failed dial")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-ftp-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	tlsConfig, err := newTLSConfig(&Options{Host: "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", tlsConfig.ServerName)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+	assert.Nil(t, tlsConfig.RootCAs)
+	assert.NotNil(t, tlsConfig.ClientSessionCache)
+
+	tlsConfig, err = newTLSConfig(&Options{Host: "example.com", NoCheckCertificate: true})
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+
+	// Missing ca_cert
+	_, err = newTLSConfig(&Options{CACert: filepath.Join(dir, "missing.pem")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read ca_cert")
+
+	// ca_cert without any certificates in
+	badCert := filepath.Join(dir, "bad.pem")
+	require.NoError(t, ioutil.WriteFile(badCert, []byte("potato"), 0600))
+	_, err = newTLSConfig(&Options{CACert: badCert})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no certificates found")
+
+	// Valid ca_cert
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	goodCert := filepath.Join(dir, "good.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(goodCert, certPEM, 0600))
+	tlsConfig, err = newTLSConfig(&Options{CACert: goodCert})
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig.RootCAs)
+	assert.Equal(t, 1, len(tlsConfig.RootCAs.Subjects()))
+}
+
+func TestNewFsTLSOptions(t *testing.T) {
+	pass := obscure.MustObscure("potato")
+
+	// Both kinds of TLS
+	_, err := NewFs("TestFTP", "", configmap.Simple{
+		"host":         "127.0.0.1",
+		"pass":         pass,
+		"tls":          "true",
+		"explicit_tls": "true",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mutually incompatible")
+
+	// The TLS config is checked before connecting
+	for _, key := range []string{"tls", "explicit_tls"} {
+		_, err = NewFs("TestFTP", "", configmap.Simple{
+			"host":    "127.0.0.1",
+			"pass":    pass,
+			key:       "true",
+			"ca_cert": "/this/does/not/exist.pem",
+		})
+		require.Error(t, err, key)
+		assert.Contains(t, err.Error(), "failed to read ca_cert", key)
+	}
+
+	// Without TLS ca_cert isn't used
+	s := newFakeServer(t)
+	defer s.Close()
+	host, port, err := net.SplitHostPort(s.listener.Addr().String())
+	require.NoError(t, err)
+	f, err := NewFs("TestFTP", "", configmap.Simple{
+		"host":    host,
+		"port":    port,
+		"pass":    pass,
+		"ca_cert": "/this/does/not/exist.pem",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, f.(*Fs).tlsConfig)
+	assert.Equal(t, "ftp://"+host+":"+port, f.String())
+}
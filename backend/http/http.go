@@ -1,10 +1,12 @@
 // Package http provides a filesystem interface using golang.org/net/http
 //
 // It treats HTML pages served from the endpoint as directory
-// listings, and includes any links found as files.
+// listings, and includes any links found as files.  JSON directory
+// listings as produced by nginx and caddy are understood too.
 package http
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/url"
@@ -16,6 +18,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/lib/rest"
@@ -41,6 +44,52 @@ func init() {
 				Value: "https://example.com",
 				Help:  "Connect to example.com",
 			}},
+		}, {
+			Name: "headers",
+			Help: `Set HTTP headers for all transactions
+
+Use this to set additional HTTP headers for all transactions
+
+The input format is comma separated list of key,value pairs.  Standard
+[CSV encoding](https://godoc.org/encoding/csv) may be used.
+
+For example to set a Cookie use 'Cookie,name=value', or
+'"Cookie","name=value"'.
+
+You can set multiple headers, eg
+'"Cookie","name=value","Authorization","xxx"'.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}, {
+			Name: "user",
+			Help: "User name for basic authentication",
+		}, {
+			Name:       "pass",
+			Help:       "Password for basic authentication.",
+			IsPassword: true,
+		}, {
+			Name: "bearer_token",
+			Help: "Bearer token to send in the Authorization header instead of user/pass",
+		}, {
+			Name: "no_head",
+			Help: `Don't use HEAD requests to find file sizes in dir listing
+
+If your site is being very slow to load then you can try this option.
+Normally rclone does a HEAD request for each potential file in a
+directory listing to:
+
+- find its size
+- check it really exists
+- check to see if it is a directory
+
+If you set this option, rclone will not do the HEAD request.  This
+will mean that files found in HTML directory listings will have an
+unknown size and modification time.
+
+JSON directory listings contain sizes and modification times so
+rclone never needs to do HEAD requests for those.`,
+			Default:  false,
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -48,7 +97,12 @@ func init() {
 
 // Options defines the configuration for this backend
 type Options struct {
-	Endpoint string `config:"url"`
+	Endpoint    string          `config:"url"`
+	Headers     fs.CommaSepList `config:"headers"`
+	User        string          `config:"user"`
+	Pass        string          `config:"pass"`
+	BearerToken string          `config:"bearer_token"`
+	NoHead      bool            `config:"no_head"`
 }
 
 // Fs stores the interface to the remote HTTP files
@@ -92,6 +146,15 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(opt.Headers)%2 != 0 {
+		return nil, errors.New("odd number of headers supplied")
+	}
+	if opt.Pass != "" {
+		opt.Pass, err = obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt password")
+		}
+	}
 
 	if !strings.HasSuffix(opt.Endpoint, "/") {
 		opt.Endpoint += "/"
@@ -107,20 +170,26 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		return nil, err
 	}
 
-	client := fshttp.NewClient(fs.Config)
+	f := &Fs{
+		name:       name,
+		root:       root,
+		opt:        *opt,
+		httpClient: fshttp.NewClient(fs.GetConfigMap(name, m)),
+	}
 
 	var isFile = false
 	if !strings.HasSuffix(u.String(), "/") {
 		// Make a client which doesn't follow redirects so the server
 		// doesn't redirect http://host/dir to http://host/dir/
-		noRedir := *client
+		noRedir := *f.httpClient
 		noRedir.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}
 		// check to see if points to a file
-		res, err := noRedir.Head(u.String())
+		res, err := f.do(&noRedir, "HEAD", u.String(), nil)
 		err = statusError(res, err)
 		if err == nil {
+			_ = res.Body.Close()
 			isFile = true
 		}
 	}
@@ -140,14 +209,8 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		return nil, err
 	}
 
-	f := &Fs{
-		name:        name,
-		root:        root,
-		opt:         *opt,
-		httpClient:  client,
-		endpoint:    u,
-		endpointURL: u.String(),
-	}
+	f.endpoint = u
+	f.endpointURL = u.String()
 	f.features = (&fs.Features{
 		CanHaveEmptyDirectories: true,
 	}).Fill(f)
@@ -203,6 +266,28 @@ func (f *Fs) url(remote string) string {
 	return f.endpointURL + rest.URLPathEscape(remote)
 }
 
+// do makes an HTTP request with method to URL using client, adding
+// the authentication and headers configured and any options passed in
+func (f *Fs) do(client *http.Client, method, URL string, options []fs.OpenOption) (*http.Response, error) {
+	req, err := http.NewRequest(method, URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.opt.User != "" || f.opt.Pass != "" {
+		req.SetBasicAuth(f.opt.User, f.opt.Pass)
+	}
+	if f.opt.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+f.opt.BearerToken)
+	}
+	for i := 0; i+1 < len(f.opt.Headers); i += 2 {
+		req.Header.Set(f.opt.Headers[i], f.opt.Headers[i+1])
+	}
+	for k, v := range fs.OpenOptionHeaders(options) {
+		req.Header.Add(k, v)
+	}
+	return client.Do(req)
+}
+
 // parse s into an int64, on failure return def
 func parseInt64(s string, def int64) int64 {
 	n, e := strconv.ParseInt(s, 10, 64)
@@ -288,8 +373,93 @@ func parse(base *url.URL, in io.Reader) (names []string, err error) {
 	return names, nil
 }
 
+// jsonEntry is an item in a JSON directory listing.
+//
+// It understands the format produced by nginx with
+// "autoindex_format json" and by caddy's browse directive (both v1
+// and v2) when asked for application/json.
+type jsonEntry struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`     // nginx: "directory", "file" or "other"
+	IsDir     bool   `json:"is_dir"`   // caddy v2
+	IsDirV1   bool   `json:"IsDir"`    // caddy v1
+	Size      int64  `json:"size"`     // nginx and caddy
+	MTime     string `json:"mtime"`    // nginx: RFC1123
+	ModTime   string `json:"mod_time"` // caddy v2: RFC3339
+	ModTimeV1 string `json:"ModTime"`  // caddy v1: RFC3339
+}
+
+// dirEntry is an entry found in a directory listing
+type dirEntry struct {
+	name    string    // name relative to the directory - ends in / if a directory
+	size    int64     // size of the file or -1 if unknown
+	modTime time.Time // modification time or timeUnset if unknown
+	hasInfo bool      // set if size and modTime were read from the listing
+}
+
+// isDir returns true if the entry is a directory
+func (e *dirEntry) isDir() bool {
+	return strings.HasSuffix(e.name, "/")
+}
+
+// parseTime parses the modification time as found in a JSON
+// listing, returning timeUnset if it can't be parsed
+func parseTime(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return t
+	}
+	return timeUnset
+}
+
+// parseJSON turns a JSON directory listing into entries
+// base should be the base URL to check the names against
+func parseJSON(base *url.URL, in io.Reader) (entries []dirEntry, err error) {
+	var items []jsonEntry
+	err = json.NewDecoder(in).Decode(&items)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		isDir := item.Type == "directory" || item.IsDir || item.IsDirV1
+		if item.Type == "other" {
+			continue
+		}
+		leaf := strings.TrimSuffix(item.Name, "/")
+		if isDir {
+			leaf += "/"
+		}
+		// check the name in the same way as a link in a HTML page
+		name, err := parseName(base, rest.URLPathEscape(leaf))
+		if err != nil {
+			fs.Debugf(nil, "Ignoring %q in JSON listing: %v", item.Name, err)
+			continue
+		}
+		modTime := item.MTime
+		if modTime == "" {
+			modTime = item.ModTime
+		}
+		if modTime == "" {
+			modTime = item.ModTimeV1
+		}
+		entry := dirEntry{
+			name:    name,
+			size:    item.Size,
+			modTime: parseTime(modTime),
+			hasInfo: true,
+		}
+		if isDir {
+			entry.size = -1
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
 // Read the directory passed in
-func (f *Fs) readDir(dir string) (names []string, err error) {
+func (f *Fs) readDir(dir string) (entries []dirEntry, err error) {
 	URL := f.url(dir)
 	u, err := url.Parse(URL)
 	if err != nil {
@@ -298,8 +468,11 @@ func (f *Fs) readDir(dir string) (names []string, err error) {
 	if !strings.HasSuffix(URL, "/") {
 		return nil, errors.Errorf("internal error: readDir URL %q didn't end in /", URL)
 	}
-	res, err := f.httpClient.Get(URL)
+	// Ask for JSON listings from servers which can make them
+	options := []fs.OpenOption{&fs.HTTPOption{Key: "Accept", Value: "text/html, application/json;q=0.9"}}
+	res, err := f.do(f.httpClient, "GET", URL, options)
 	if err == nil && res.StatusCode == http.StatusNotFound {
+		_ = res.Body.Close()
 		return nil, fs.ErrorDirNotFound
 	}
 	err = statusError(res, err)
@@ -311,14 +484,26 @@ func (f *Fs) readDir(dir string) (names []string, err error) {
 	contentType := strings.SplitN(res.Header.Get("Content-Type"), ";", 2)[0]
 	switch contentType {
 	case "text/html":
-		names, err = parse(u, res.Body)
+		names, err := parse(u, res.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "readDir")
+		}
+		for _, name := range names {
+			entries = append(entries, dirEntry{
+				name:    name,
+				size:    -1,
+				modTime: timeUnset,
+			})
+		}
+	case "application/json":
+		entries, err = parseJSON(u, res.Body)
 		if err != nil {
 			return nil, errors.Wrap(err, "readDir")
 		}
 	default:
 		return nil, errors.Errorf("Can't parse content type %q", contentType)
 	}
-	return names, nil
+	return entries, nil
 }
 
 // List the objects and directories in dir into entries.  The
@@ -334,25 +519,27 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 	if !strings.HasSuffix(dir, "/") && dir != "" {
 		dir += "/"
 	}
-	names, err := f.readDir(dir)
+	items, err := f.readDir(dir)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error listing %q", dir)
 	}
-	for _, name := range names {
-		isDir := name[len(name)-1] == '/'
-		name = strings.TrimRight(name, "/")
-		remote := path.Join(dir, name)
-		if isDir {
-			dir := fs.NewDir(remote, timeUnset)
+	for _, item := range items {
+		remote := path.Join(dir, strings.TrimRight(item.name, "/"))
+		if item.isDir() {
+			dir := fs.NewDir(remote, item.modTime)
 			entries = append(entries, dir)
 		} else {
 			file := &Object{
-				fs:     f,
-				remote: remote,
+				fs:      f,
+				remote:  remote,
+				size:    item.size,
+				modTime: item.modTime,
 			}
-			if err = file.stat(); err != nil {
-				fs.Debugf(remote, "skipping because of error: %v", err)
-				continue
+			if !item.hasInfo && !f.opt.NoHead {
+				if err = file.stat(); err != nil {
+					fs.Debugf(remote, "skipping because of error: %v", err)
+					continue
+				}
 			}
 			entries = append(entries, file)
 		}
@@ -415,11 +602,12 @@ func (o *Object) url() string {
 // stat updates the info field in the Object
 func (o *Object) stat() error {
 	url := o.url()
-	res, err := o.fs.httpClient.Head(url)
+	res, err := o.fs.do(o.fs.httpClient, "HEAD", url, nil)
 	err = statusError(res, err)
 	if err != nil {
 		return errors.Wrap(err, "failed to stat")
 	}
+	_ = res.Body.Close()
 	t, err := http.ParseTime(res.Header.Get("Last-Modified"))
 	if err != nil {
 		t = timeUnset
@@ -445,18 +633,7 @@ func (o *Object) Storable() bool {
 // Open a remote http file object for reading. Seek is supported
 func (o *Object) Open(options ...fs.OpenOption) (in io.ReadCloser, err error) {
 	url := o.url()
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "Open failed")
-	}
-
-	// Add optional headers
-	for k, v := range fs.OpenOptionHeaders(options) {
-		req.Header.Add(k, v)
-	}
-
-	// Do the request
-	res, err := o.fs.httpClient.Do(req)
+	res, err := o.fs.do(o.fs.httpClient, "GET", url, options)
 	err = statusError(res, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "Open failed")
@@ -17,6 +17,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fstest"
 	"github.com/ncw/rclone/lib/rest"
 	"github.com/stretchr/testify/assert"
@@ -325,3 +326,171 @@ func TestParseCaddy(t *testing.T) {
 		"v1.36-22-g06ea13a-ssh-agentβ/",
 	})
 }
+
+// Load JSON from the file given and parse it, checking it against the entries passed in
+func parseJSONFile(t *testing.T, name string, want []dirEntry) {
+	in, err := os.Open(filepath.Join(testPath, "index_files", name))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, in.Close())
+	}()
+	u, err := url.Parse("http://example.com/")
+	require.NoError(t, err)
+	entries, err := parseJSON(u, in)
+	require.NoError(t, err)
+	require.Equal(t, len(want), len(entries))
+	for i := range want {
+		assert.Equal(t, want[i].name, entries[i].name)
+		assert.Equal(t, want[i].size, entries[i].size, want[i].name)
+		assert.True(t, want[i].modTime.Equal(entries[i].modTime), want[i].name)
+		assert.True(t, entries[i].hasInfo, want[i].name)
+	}
+}
+
+func TestParseJSONNginx(t *testing.T) {
+	mtime := func(s string) time.Time {
+		t, err := http.ParseTime(s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	parseJSONFile(t, "nginx.json", []dirEntry{
+		{name: "deltas/", size: -1, modTime: mtime("Sat, 21 Apr 2018 16:04:53 GMT")},
+		{name: "objects/", size: -1, modTime: mtime("Sat, 21 Apr 2018 16:04:54 GMT")},
+		{name: "config", size: 63, modTime: mtime("Sat, 21 Apr 2018 16:04:53 GMT")},
+		{name: "summary", size: 1402, modTime: mtime("Sat, 21 Apr 2018 16:06:10 GMT")},
+		{name: "with space & 100%.txt", size: 0, modTime: mtime("Sat, 21 Apr 2018 16:06:10 GMT")},
+	})
+}
+
+func TestParseJSONCaddy(t *testing.T) {
+	mtime := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	parseJSONFile(t, "caddy.json", []dirEntry{
+		{name: "v1.36-155-gcf29ee8b-team-driveβ/", size: -1, modTime: mtime("2017-07-01T16:49:45.120533+01:00")},
+		{name: "mimetype.zip", size: 1437462, modTime: mtime("2017-06-30T10:57:49+01:00")},
+		{name: "old-style/", size: -1, modTime: mtime("2017-05-26T11:10:57Z")},
+		{name: "old-style.txt", size: 12, modTime: mtime("2017-05-26T11:10:57Z")},
+	})
+}
+
+func TestHeadersAndAuth(t *testing.T) {
+	fileServer := http.FileServer(http.Dir(filesPath))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "secret" || r.Header.Get("X-Test") != "potato" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	config.LoadConfig()
+
+	m := configmap.Simple{
+		"type":    "http",
+		"url":     ts.URL,
+		"user":    "user",
+		"pass":    obscure.MustObscure("secret"),
+		"headers": "X-Test,potato",
+	}
+	f, err := NewFs(remoteName, "", m)
+	require.NoError(t, err)
+	testListRoot(t, f)
+
+	// Check it fails without the header
+	delete(m, "headers")
+	f, err = NewFs(remoteName, "", m)
+	require.NoError(t, err)
+	_, err = f.List("")
+	require.Error(t, err)
+
+	// Check an odd number of headers is rejected
+	m["headers"] = "X-Test"
+	_, err = NewFs(remoteName, "", m)
+	require.Error(t, err)
+}
+
+func TestBearerToken(t *testing.T) {
+	fileServer := http.FileServer(http.Dir(filesPath))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	config.LoadConfig()
+
+	f, err := NewFs(remoteName, "four", configmap.Simple{
+		"type":         "http",
+		"url":          ts.URL,
+		"bearer_token": "token",
+	})
+	require.NoError(t, err)
+	o, err := f.NewObject("under four.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), o.Size())
+}
+
+func TestListJSON(t *testing.T) {
+	modTime := time.Date(2018, 4, 21, 16, 4, 53, 0, time.UTC)
+	heads := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			heads++
+		}
+		assert.Contains(t, r.Header.Get("Accept"), "application/json")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `[{"name":"dir","type":"directory","mtime":%q},{"name":"file.txt","type":"file","mtime":%q,"size":42}]`,
+			modTime.Format(http.TimeFormat), modTime.Format(http.TimeFormat))
+	}))
+	defer ts.Close()
+	config.LoadConfig()
+
+	f, err := NewFs(remoteName, "", configmap.Simple{
+		"type": "http",
+		"url":  ts.URL,
+	})
+	require.NoError(t, err)
+	entries, err := f.List("")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	require.Equal(t, 2, len(entries))
+
+	_, ok := entries[0].(fs.Directory)
+	assert.True(t, ok)
+	assert.Equal(t, "dir", entries[0].Remote())
+	assert.True(t, modTime.Equal(entries[0].ModTime()))
+
+	_, ok = entries[1].(*Object)
+	assert.True(t, ok)
+	assert.Equal(t, "file.txt", entries[1].Remote())
+	assert.Equal(t, int64(42), entries[1].Size())
+	assert.True(t, modTime.Equal(entries[1].ModTime()))
+
+	assert.Equal(t, 0, heads)
+}
+
+func TestNoHead(t *testing.T) {
+	m, tidy := prepareServer(t)
+	defer tidy()
+	m["no_head"] = "true"
+
+	f, err := NewFs(remoteName, "three", m)
+	require.NoError(t, err)
+
+	entries, err := f.List("")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "underthree.txt", entries[0].Remote())
+	assert.Equal(t, int64(-1), entries[0].Size())
+	assert.True(t, timeUnset.Equal(entries[0].ModTime()))
+}
@@ -0,0 +1,138 @@
+// Persistent cache of the hashes of local files
+
+// +build !plan9
+
+package local
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// hashCacheBucket is the name of the bolt bucket the hashes are in
+var hashCacheBucket = []byte("hashes")
+
+// hashCache is a persistent cache of the hashes of local files
+//
+// The entries are keyed on the path of the file.  They are only used
+// if the device and inode, size, modification time and change time of
+// the file still match, so a file which has been replaced or written
+// to is hashed again even if its size and modification time haven't
+// changed.
+type hashCache struct {
+	db *bolt.DB
+}
+
+// hashCacheEntry is stored in the database for each file
+type hashCacheEntry struct {
+	FileID     string               `json:"fileID,omitempty"` // device and inode if known
+	Size       int64                `json:"size"`
+	ModTime    int64                `json:"modTime"`              // in ns since the epoch
+	ChangeTime int64                `json:"changeTime,omitempty"` // in ns since the epoch, 0 if not known
+	Hashes     map[hash.Type]string `json:"hashes,omitempty"`
+}
+
+// sameFile returns true if entry is for the same version of the
+// file as other
+func (entry *hashCacheEntry) sameFile(other *hashCacheEntry) bool {
+	return entry.FileID == other.FileID &&
+		entry.Size == other.Size &&
+		entry.ModTime == other.ModTime &&
+		entry.ChangeTime == other.ChangeTime
+}
+
+// hashCacheEntry returns the entry describing the current version of
+// the object, as read by info, without any hashes
+func (o *Object) hashCacheEntry(info os.FileInfo) *hashCacheEntry {
+	fileID, _ := readFileID(info)
+	return &hashCacheEntry{
+		FileID:     fileID,
+		Size:       o.size,
+		ModTime:    o.modTime.UnixNano(),
+		ChangeTime: readChangeTime(info),
+	}
+}
+
+var (
+	hashCachesMu sync.Mutex
+	hashCaches   = map[string]*hashCache{}
+)
+
+// getHashCache returns the hash cache in the database at dbPath,
+// opening it if necessary.
+//
+// The caches are shared between all the Fs using the same dbPath as
+// the database can only be opened once.
+func getHashCache(dbPath string) (*hashCache, error) {
+	hashCachesMu.Lock()
+	defer hashCachesMu.Unlock()
+	if c, ok := hashCaches[dbPath]; ok {
+		return c, nil
+	}
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, errors.Errorf("hash cache %q is in use by another rclone", dbPath)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open hash cache %q", dbPath)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(hashCacheBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "failed to initialise hash cache %q", dbPath)
+	}
+	c := &hashCache{db: db}
+	hashCaches[dbPath] = c
+	return c, nil
+}
+
+// get returns the hashes stored for key if they are for the same
+// version of the file as want, or nil if there aren't any
+func (c *hashCache) get(key string, want *hashCacheEntry) (hashes map[hash.Type]string) {
+	err := c.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(hashCacheBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		var entry hashCacheEntry
+		err := json.Unmarshal(data, &entry)
+		if err != nil {
+			return err
+		}
+		if entry.sameFile(want) {
+			hashes = entry.Hashes
+		}
+		return nil
+	})
+	if err != nil {
+		fs.Debugf(key, "Failed to read hash cache: %v", err)
+		return nil
+	}
+	return hashes
+}
+
+// put stores the hashes for key along with the version of the file
+// they are for
+func (c *hashCache) put(key string, entry *hashCacheEntry, hashes map[hash.Type]string) {
+	newEntry := *entry
+	newEntry.Hashes = hashes
+	data, err := json.Marshal(&newEntry)
+	if err == nil {
+		err = c.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(hashCacheBucket).Put([]byte(key), data)
+		})
+	}
+	if err != nil {
+		fs.Debugf(key, "Failed to update hash cache: %v", err)
+	}
+}
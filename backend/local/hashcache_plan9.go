@@ -0,0 +1,37 @@
+// Persistent cache of the hashes of local files
+
+// +build plan9
+
+package local
+
+import (
+	"os"
+
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// hashCache isn't supported on plan9
+type hashCache struct{}
+
+// hashCacheEntry isn't supported on plan9
+type hashCacheEntry struct{}
+
+// hashCacheEntry returns nil
+func (o *Object) hashCacheEntry(info os.FileInfo) *hashCacheEntry {
+	return nil
+}
+
+// getHashCache returns an error as the hash cache isn't supported
+func getHashCache(dbPath string) (*hashCache, error) {
+	return nil, errors.New("hash cache not supported on plan9")
+}
+
+// get does nothing
+func (c *hashCache) get(key string, want *hashCacheEntry) (hashes map[hash.Type]string) {
+	return nil
+}
+
+// put does nothing
+func (c *hashCache) put(key string, entry *hashCacheEntry, hashes map[hash.Type]string) {
+}
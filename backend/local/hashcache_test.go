@@ -0,0 +1,101 @@
+// +build !plan9
+
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test the persistent hash cache is only used while the file is
+// unchanged
+func TestHashCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-hash-cache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	root := path.Join(dir, "files")
+	require.NoError(t, os.Mkdir(root, 0777))
+	modTime := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	write := func(name, content string) {
+		filePath := path.Join(root, name)
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0666))
+		require.NoError(t, os.Chtimes(filePath, modTime, modTime))
+	}
+	write("potato", "hello")
+
+	f, err := NewFs("local", root, configmap.Simple{"hash_cache": path.Join(dir, "hashes.db")})
+	require.NoError(t, err)
+	md5sum := func(name string) string {
+		o, err := f.NewObject(name)
+		require.NoError(t, err)
+		sum, err := o.Hash(hash.MD5)
+		require.NoError(t, err)
+		return sum
+	}
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", md5sum("potato"))
+
+	// The hashes are read from the cache while the file is
+	// unchanged
+	o, err := f.NewObject("potato")
+	require.NoError(t, err)
+	fi, err := os.Stat(path.Join(root, "potato"))
+	require.NoError(t, err)
+	f.(*Fs).hashCache.put(o.(*Object).path, o.(*Object).hashCacheEntry(fi), map[hash.Type]string{hash.MD5: "cached"})
+	assert.Equal(t, "cached", md5sum("potato"))
+
+	if readChangeTime(fi) == 0 {
+		t.Skip("change time not supported on this OS")
+	}
+
+	// Change the contents without changing the size or
+	// modification time - the change time changes so the hash is
+	// recalculated
+	write("potato", "HELLO")
+	assert.Equal(t, "eb61eead90e3b899c6bcbe27ac581660", md5sum("potato"))
+
+	// Rename the file - it is hashed again
+	require.NoError(t, os.Rename(path.Join(root, "potato"), path.Join(root, "yam")))
+	assert.Equal(t, "eb61eead90e3b899c6bcbe27ac581660", md5sum("yam"))
+
+	// Replace the file with one with the same size and
+	// modification time
+	require.NoError(t, os.Remove(path.Join(root, "yam")))
+	write("yam", "hello")
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", md5sum("yam"))
+}
+
+func TestHashCacheInUse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-hash-cache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	dbPath := path.Join(dir, "hashes.db")
+
+	// Hold the database open as another rclone would
+	db, err := bolt.Open(dbPath, 0600, nil)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	_, err = getHashCache(dbPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "in use by another rclone")
+
+	// The Fs carries on without the hash cache
+	f, err := NewFs("local", dir, configmap.Simple{"hash_cache": dbPath})
+	require.NoError(t, err)
+	assert.Nil(t, f.(*Fs).hashCache)
+}
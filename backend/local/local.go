@@ -32,6 +32,7 @@ func init() {
 		Name:        "local",
 		Description: "Local Disk",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name: "nounc",
 			Help: "Disable UNC (long path names) conversion on Windows",
@@ -69,19 +70,46 @@ func init() {
 			NoPrefix: true,
 			ShortOpt: "x",
 			Advanced: true,
+		}, {
+			Name: "hash_cache",
+			Help: `Path to a database to cache the hashes of local files in.
+
+If set the hashes of the local files are stored in this database and
+reused while the file is unchanged, so files only need to be read to
+hash them once.  A file counts as changed if its size, modification
+time, change time or inode change (unix/macOS only - only the size
+and modification time are checked elsewhere).
+
+If the database is in use by another rclone then this rclone carries
+on without it.`,
+			Default:  "",
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
 }
 
+// commandHelp describes the backend commands
+var commandHelp = []fs.CommandHelp{{
+	Name:  "noop",
+	Short: "A null operation for testing backend commands",
+	Long: `This is a test command which has some options you can try to
+change the output.`,
+	Opts: map[string]string{
+		"echo":  "echo the input arguments",
+		"error": "return an error based on option value",
+	},
+}}
+
 // Options defines the configuration for this backend
 type Options struct {
-	FollowSymlinks bool `config:"copy_links"`
-	SkipSymlinks   bool `config:"skip_links"`
-	NoUTFNorm      bool `config:"no_unicode_normalization"`
-	NoCheckUpdated bool `config:"no_check_updated"`
-	NoUNC          bool `config:"nounc"`
-	OneFileSystem  bool `config:"one_file_system"`
+	FollowSymlinks bool   `config:"copy_links"`
+	SkipSymlinks   bool   `config:"skip_links"`
+	NoUTFNorm      bool   `config:"no_unicode_normalization"`
+	NoCheckUpdated bool   `config:"no_check_updated"`
+	NoUNC          bool   `config:"nounc"`
+	OneFileSystem  bool   `config:"one_file_system"`
+	HashCache      string `config:"hash_cache"`
 }
 
 // Fs represents a local filesystem rooted at root
@@ -99,6 +127,7 @@ type Fs struct {
 	lstat          func(name string) (os.FileInfo, error)
 	dirNames       *mapper    // directory name mapping
 	objectHashesMu sync.Mutex // global lock for Object.hashes
+	hashCache      *hashCache // persistent hash cache if set
 }
 
 // Object represents a local filesystem object
@@ -136,6 +165,12 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		dirNames: newMapper(),
 	}
 	f.root = f.cleanPath(root)
+	if opt.HashCache != "" {
+		f.hashCache, err = getHashCache(opt.HashCache)
+		if err != nil {
+			fs.Errorf(nil, "Not using the hash cache: %v", err)
+		}
+	}
 	f.features = (&fs.Features{
 		CaseInsensitive:         f.caseInsensitive(),
 		CanHaveEmptyDirectories: true,
@@ -593,6 +628,27 @@ func (f *Fs) Hashes() hash.Set {
 	return hash.Supported
 }
 
+// OpenWriterAt opens with a handle for random access writes
+//
+// Pass in the remote desired and the size if known.
+//
+// It truncates any existing object
+func (f *Fs) OpenWriterAt(remote string, size int64) (fs.WriterAtCloser, error) {
+	// Temporary Object under construction
+	o := f.newObject(remote, "")
+
+	err := o.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -618,27 +674,39 @@ func (o *Object) Hash(r hash.Type) (string, error) {
 	// Check that the underlying file hasn't changed
 	oldtime := o.modTime
 	oldsize := o.size
-	err := o.lstat()
+	info, err := o.fs.lstat(o.path)
 	if err != nil {
 		return "", errors.Wrap(err, "hash: failed to stat")
 	}
+	o.setMetadata(info)
 
 	o.fs.objectHashesMu.Lock()
 	hashes := o.hashes
 	o.fs.objectHashesMu.Unlock()
 
 	if !o.modTime.Equal(oldtime) || oldsize != o.size || hashes == nil {
-		in, err := os.Open(o.path)
-		if err != nil {
-			return "", errors.Wrap(err, "hash: failed to open")
+		hashes = nil
+		var cacheEntry *hashCacheEntry
+		if o.fs.hashCache != nil {
+			cacheEntry = o.hashCacheEntry(info)
+			hashes = o.fs.hashCache.get(o.path, cacheEntry)
 		}
-		hashes, err = hash.Stream(in)
-		closeErr := in.Close()
-		if err != nil {
-			return "", errors.Wrap(err, "hash: failed to read")
-		}
-		if closeErr != nil {
-			return "", errors.Wrap(closeErr, "hash: failed to close")
+		if hashes == nil {
+			in, err := os.Open(o.path)
+			if err != nil {
+				return "", errors.Wrap(err, "hash: failed to open")
+			}
+			hashes, err = hash.Stream(in)
+			closeErr := in.Close()
+			if err != nil {
+				return "", errors.Wrap(err, "hash: failed to read")
+			}
+			if closeErr != nil {
+				return "", errors.Wrap(closeErr, "hash: failed to close")
+			}
+			if o.fs.hashCache != nil {
+				o.fs.hashCache.put(o.path, cacheEntry, hashes)
+			}
 		}
 		o.fs.objectHashesMu.Lock()
 		o.hashes = hashes
@@ -993,12 +1061,45 @@ func cleanWindowsName(f *Fs, name string) string {
 	return name2
 }
 
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (interface{}, error) {
+	switch name {
+	case "noop":
+		if txt, ok := opt["error"]; ok {
+			if txt == "" {
+				txt = "unspecified error"
+			}
+			return nil, errors.New(txt)
+		}
+		if _, ok := opt["echo"]; ok {
+			return map[string]interface{}{
+				"name": name,
+				"arg":  arg,
+				"opt":  opt,
+			}, nil
+		}
+		return nil, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Purger      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Purger         = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.Mover          = &Fs{}
+	_ fs.DirMover       = &Fs{}
+	_ fs.OpenWriterAter = &Fs{}
+	_ fs.Commander      = &Fs{}
+	_ fs.Object         = &Object{}
 )
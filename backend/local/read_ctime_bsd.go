@@ -0,0 +1,20 @@
+// Change time reading functions
+
+// +build darwin freebsd netbsd
+
+package local
+
+import (
+	"os"
+	"syscall"
+)
+
+// readChangeTime returns the change time (ctime) of a valid
+// os.FileInfo in ns since the epoch, or 0 if it isn't available.
+func readChangeTime(fi os.FileInfo) int64 {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return statT.Ctimespec.Nano()
+}
@@ -11,3 +11,15 @@ import "os"
 func readDevice(fi os.FileInfo, oneFileSystem bool) uint64 {
 	return devUnset
 }
+
+// readFileID turns a valid os.FileInfo into a string identifying
+// the file by its device and inode, returning false if it fails.
+func readFileID(fi os.FileInfo) (id string, ok bool) {
+	return "", false
+}
+
+// readChangeTime returns the change time (ctime) of a valid
+// os.FileInfo in ns since the epoch, or 0 if it isn't available.
+func readChangeTime(fi os.FileInfo) int64 {
+	return 0
+}
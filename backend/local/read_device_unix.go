@@ -5,6 +5,7 @@
 package local
 
 import (
+	"fmt"
 	"os"
 	"syscall"
 
@@ -24,3 +25,13 @@ func readDevice(fi os.FileInfo, oneFileSystem bool) uint64 {
 	}
 	return uint64(statT.Dev)
 }
+
+// readFileID turns a valid os.FileInfo into a string identifying
+// the file by its device and inode, returning false if it fails.
+func readFileID(fi os.FileInfo) (id string, ok bool) {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%d", uint64(statT.Dev), uint64(statT.Ino)), true
+}
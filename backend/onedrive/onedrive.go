@@ -38,6 +38,7 @@ const (
 	maxSleep                    = 2 * time.Second
 	decayConstant               = 2 // bigger for slower decay, exponential
 	graphURL                    = "https://graph.microsoft.com/v1.0"
+	deviceAuthURL               = "https://login.microsoftonline.com/common/oauth2/v2.0/devicecode"
 	configDriveID               = "drive_id"
 	configDriveType             = "drive_type"
 	driveTypePersonal           = "personal"
@@ -66,144 +67,7 @@ func init() {
 		Name:        "onedrive",
 		Description: "Microsoft OneDrive",
 		NewFs:       NewFs,
-		Config: func(name string, m configmap.Mapper) {
-			err := oauthutil.Config("onedrive", name, m, oauthConfig)
-			if err != nil {
-				log.Fatalf("Failed to configure token: %v", err)
-				return
-			}
-
-			// Are we running headless?
-			if automatic, _ := m.Get(config.ConfigAutomatic); automatic != "" {
-				// Yes, okay we are done
-				return
-			}
-
-			type driveResource struct {
-				DriveID   string `json:"id"`
-				DriveName string `json:"name"`
-				DriveType string `json:"driveType"`
-			}
-			type drivesResponse struct {
-				Drives []driveResource `json:"value"`
-			}
-
-			type siteResource struct {
-				SiteID   string `json:"id"`
-				SiteName string `json:"displayName"`
-				SiteURL  string `json:"webUrl"`
-			}
-			type siteResponse struct {
-				Sites []siteResource `json:"value"`
-			}
-
-			oAuthClient, _, err := oauthutil.NewClient(name, m, oauthConfig)
-			if err != nil {
-				log.Fatalf("Failed to configure OneDrive: %v", err)
-			}
-			srv := rest.NewClient(oAuthClient)
-
-			var opts rest.Opts
-			var finalDriveID string
-			var siteID string
-			switch config.Choose("Your choice",
-				[]string{"onedrive", "sharepoint", "driveid", "siteid", "search"},
-				[]string{"OneDrive Personal or Business", "Sharepoint site", "Type in driveID", "Type in SiteID", "Search a Sharepoint site"},
-				false) {
-
-			case "onedrive":
-				opts = rest.Opts{
-					Method:  "GET",
-					RootURL: graphURL,
-					Path:    "/me/drives",
-				}
-			case "sharepoint":
-				opts = rest.Opts{
-					Method:  "GET",
-					RootURL: graphURL,
-					Path:    "/sites/root/drives",
-				}
-			case "driveid":
-				fmt.Printf("Paste your Drive ID here> ")
-				finalDriveID = config.ReadLine()
-			case "siteid":
-				fmt.Printf("Paste your Site ID here> ")
-				siteID = config.ReadLine()
-			case "search":
-				fmt.Printf("What to search for> ")
-				searchTerm := config.ReadLine()
-				opts = rest.Opts{
-					Method:  "GET",
-					RootURL: graphURL,
-					Path:    "/sites?search=" + searchTerm,
-				}
-
-				sites := siteResponse{}
-				_, err := srv.CallJSON(&opts, nil, &sites)
-				if err != nil {
-					log.Fatalf("Failed to query available sites: %v", err)
-				}
-
-				if len(sites.Sites) == 0 {
-					log.Fatalf("Search for '%s' returned no results", searchTerm)
-				} else {
-					fmt.Printf("Found %d sites, please select the one you want to use:\n", len(sites.Sites))
-					for index, site := range sites.Sites {
-						fmt.Printf("%d: %s (%s) id=%s\n", index, site.SiteName, site.SiteURL, site.SiteID)
-					}
-					siteID = sites.Sites[config.ChooseNumber("Chose drive to use:", 0, len(sites.Sites)-1)].SiteID
-				}
-			}
-
-			// if we have a siteID we need to ask for the drives
-			if siteID != "" {
-				opts = rest.Opts{
-					Method:  "GET",
-					RootURL: graphURL,
-					Path:    "/sites/" + siteID + "/drives",
-				}
-			}
-
-			// We don't have the final ID yet?
-			// query Microsoft Graph
-			if finalDriveID == "" {
-				drives := drivesResponse{}
-				_, err := srv.CallJSON(&opts, nil, &drives)
-				if err != nil {
-					log.Fatalf("Failed to query available drives: %v", err)
-				}
-
-				if len(drives.Drives) == 0 {
-					log.Fatalf("No drives found")
-				} else {
-					fmt.Printf("Found %d drives, please select the one you want to use:\n", len(drives.Drives))
-					for index, drive := range drives.Drives {
-						fmt.Printf("%d: %s (%s) id=%s\n", index, drive.DriveName, drive.DriveType, drive.DriveID)
-					}
-					finalDriveID = drives.Drives[config.ChooseNumber("Chose drive to use:", 0, len(drives.Drives)-1)].DriveID
-				}
-			}
-
-			// Test the driveID and get drive type
-			opts = rest.Opts{
-				Method:  "GET",
-				RootURL: graphURL,
-				Path:    "/drives/" + finalDriveID + "/root"}
-			var rootItem api.Item
-			_, err = srv.CallJSON(&opts, nil, &rootItem)
-			if err != nil {
-				log.Fatalf("Failed to query root for drive %s: %v", finalDriveID, err)
-			}
-
-			fmt.Printf("Found drive '%s' of type '%s', URL: %s\nIs that okay?\n", rootItem.Name, rootItem.ParentReference.DriveType, rootItem.WebURL)
-			// This does not work, YET :)
-			if !config.Confirm() {
-				log.Fatalf("Cancelled by user")
-			}
-
-			config.FileSet(name, configDriveID, finalDriveID)
-			config.FileSet(name, configDriveType, rootItem.ParentReference.DriveType)
-		},
+		Config:      Config,
 		Options: []fs.Option{{
 			Name: config.ConfigClientID,
 			Help: "Microsoft App Client Id\nLeave blank normally.",
@@ -229,6 +93,152 @@ func init() {
 	})
 }
 
+// driveResource is a drive returned by Microsoft Graph
+type driveResource struct {
+	DriveID   string `json:"id"`
+	DriveName string `json:"name"`
+	DriveType string `json:"driveType"`
+}
+
+// drivesResponse is the list of drives returned by Microsoft Graph
+type drivesResponse struct {
+	Drives []driveResource `json:"value"`
+}
+
+// siteResource is a site returned by Microsoft Graph
+type siteResource struct {
+	SiteID   string `json:"id"`
+	SiteName string `json:"displayName"`
+	SiteURL  string `json:"webUrl"`
+}
+
+// siteResponse is the list of sites returned by Microsoft Graph
+type siteResponse struct {
+	Sites []siteResource `json:"value"`
+}
+
+// Config is the config state machine for onedrive
+func Config(name string, m configmap.Mapper, in fs.ConfigIn) (*fs.ConfigOut, error) {
+	if in.State == "" {
+		return oauthutil.ConfigOut("choose_type", &oauthutil.Options{
+			OAuth2Config:  oauthConfig,
+			DeviceAuthURL: deviceAuthURL,
+		})
+	}
+
+	// Are we running headless?
+	if automatic, _ := m.Get(config.ConfigAutomatic); automatic != "" {
+		// Yes, okay we are done
+		return nil, nil
+	}
+
+	oAuthClient, _, err := oauthutil.NewClient(name, m, oauthConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure OneDrive")
+	}
+	srv := rest.NewClient(oAuthClient)
+
+	values, state := fs.StatePop(in.State)
+	switch state {
+	case "choose_type":
+		return fs.ConfigChooseFixed("choose_type_done", "config_type", "Type of connection", []fs.OptionExample{{
+			Value: "onedrive",
+			Help:  "OneDrive Personal or Business",
+		}, {
+			Value: "sharepoint",
+			Help:  "Sharepoint site",
+		}, {
+			Value: "driveid",
+			Help:  "Type in driveID",
+		}, {
+			Value: "siteid",
+			Help:  "Type in SiteID",
+		}, {
+			Value: "search",
+			Help:  "Search a Sharepoint site",
+		}})
+	case "choose_type_done":
+		switch in.Result {
+		case "onedrive":
+			return chooseDrive(srv, "/me/drives")
+		case "sharepoint":
+			return chooseDrive(srv, "/sites/root/drives")
+		case "driveid":
+			return fs.ConfigInput("driveid_final", "config_driveid", "Drive ID")
+		case "siteid":
+			return fs.ConfigInput("siteid", "config_siteid", "Site ID")
+		case "search":
+			return fs.ConfigInput("search_term", "config_search_term", "What to search for")
+		}
+	case "siteid":
+		return chooseDrive(srv, "/sites/"+in.Result+"/drives")
+	case "search_term":
+		opts := rest.Opts{
+			Method:  "GET",
+			RootURL: graphURL,
+			Path:    "/sites?search=" + in.Result,
+		}
+		sites := siteResponse{}
+		_, err := srv.CallJSON(&opts, nil, &sites)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to query available sites")
+		}
+		if len(sites.Sites) == 0 {
+			return fs.ConfigError("choose_type", fmt.Sprintf("Search for %q returned no results", in.Result))
+		}
+		return fs.ConfigChoose("siteid", "config_site", "Site to use", len(sites.Sites), func(i int) (string, string) {
+			site := sites.Sites[i]
+			return site.SiteID, fmt.Sprintf("%s (%s)", site.SiteName, site.SiteURL)
+		})
+	case "driveid_final":
+		finalDriveID := in.Result
+		// Test the driveID and get drive type
+		opts := rest.Opts{
+			Method:  "GET",
+			RootURL: graphURL,
+			Path:    "/drives/" + finalDriveID + "/root"}
+		var rootItem api.Item
+		_, err = srv.CallJSON(&opts, nil, &rootItem)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query root for drive %s", finalDriveID)
+		}
+		driveType := rootItem.ParentReference.DriveType
+		help := fmt.Sprintf("Found drive '%s' of type '%s', URL: %s\nIs that okay?", rootItem.Name, driveType, rootItem.WebURL)
+		return fs.ConfigConfirm(fs.StatePush("", "driveid_final_end", finalDriveID, driveType), true, "config_drive_ok", help)
+	case "driveid_final_end":
+		if in.Result == "false" {
+			return fs.ConfigGoto("choose_type")
+		}
+		values, finalDriveID := fs.StatePop(values)
+		_, driveType := fs.StatePop(values)
+		m.Set(configDriveID, finalDriveID)
+		m.Set(configDriveType, driveType)
+		return nil, nil
+	}
+	return nil, errors.Errorf("unknown state %q", in.State)
+}
+
+// chooseDrive asks the user to choose one of the drives listed at path
+func chooseDrive(srv *rest.Client, path string) (*fs.ConfigOut, error) {
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: graphURL,
+		Path:    path,
+	}
+	drives := drivesResponse{}
+	_, err := srv.CallJSON(&opts, nil, &drives)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query available drives")
+	}
+	if len(drives.Drives) == 0 {
+		return fs.ConfigError("choose_type", "No drives found")
+	}
+	return fs.ConfigChoose("driveid_final", "config_driveid", "Drive to use", len(drives.Drives), func(i int) (string, string) {
+		drive := drives.Drives[i]
+		return drive.DriveID, fmt.Sprintf("%s (%s)", drive.DriveName, drive.DriveType)
+	})
+}
+
 // Options defines the configuration for this backend
 type Options struct {
 	ChunkSize fs.SizeSuffix `config:"chunk_size"`
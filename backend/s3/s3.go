@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -53,6 +54,7 @@ func init() {
 		Name:        "s3",
 		Description: "Amazon S3 Compliant Storage Providers (AWS, Ceph, Dreamhost, IBM COS, Minio)",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name: fs.ConfigProvider,
 			Help: "Choose your S3 provider.",
@@ -548,6 +550,51 @@ func init() {
 	})
 }
 
+// commandHelp describes the backend commands
+var commandHelp = []fs.CommandHelp{{
+	Name:  "restore",
+	Short: "Restore objects from GLACIER to normal storage",
+	Long: `This command can be used to restore one or more objects from GLACIER
+or DEEP_ARCHIVE to normal storage.
+
+Usage Examples:
+
+    rclone backend restore s3:bucket/path/to/object -o priority=PRIORITY -o lifetime=DAYS
+    rclone backend restore s3:bucket/path/to/directory -o priority=PRIORITY -o lifetime=DAYS
+    rclone backend restore s3:bucket -o priority=PRIORITY -o lifetime=DAYS
+
+This command also obeys the filters.  Test first with --dry-run
+
+    rclone --dry-run backend restore --include "*.txt" s3:bucket/path -o priority=Standard
+
+All the objects shown will be marked for restore, then
+
+    rclone backend restore --include "*.txt" s3:bucket/path -o priority=Standard
+
+It returns a list of status dictionaries with Remote and Status
+keys.  The Status will be OK if it was successful or an error message
+if not.
+`,
+	Opts: map[string]string{
+		"description": "The optional description for the job.",
+		"lifetime":    "Lifetime of the active copy in days, default 1",
+		"priority":    "Priority of restore: Standard|Expedited|Bulk, default Standard",
+	},
+}, {
+	Name:  "list-multipart-uploads",
+	Short: "List the unfinished multipart uploads",
+	Long: `This command lists the unfinished multipart uploads in JSON format.
+
+    rclone backend list-multipart-uploads s3:bucket/path/to/object
+
+It returns a list of the uploads with their Key, UploadId and when
+they were Initiated.
+
+You can clean up the unfinished uploads by deleting the bucket or with
+a bucket lifecycle rule.
+`,
+}}
+
 // Constants
 const (
 	metaMtime      = "Mtime"                       // the meta key to store mtime in - eg X-Amz-Meta-Mtime
@@ -650,7 +697,7 @@ func s3ParsePath(path string) (bucket, directory string, err error) {
 }
 
 // s3Connection makes a connection to s3
-func s3Connection(opt *Options) (*s3.S3, *session.Session, error) {
+func s3Connection(opt *Options, ci *fs.ConfigInfo) (*s3.S3, *session.Session, error) {
 	// Make the auth
 	v := credentials.Value{
 		AccessKeyID:     opt.AccessKeyID,
@@ -712,7 +759,7 @@ func s3Connection(opt *Options) (*s3.S3, *session.Session, error) {
 		WithMaxRetries(maxRetries).
 		WithCredentials(cred).
 		WithEndpoint(opt.Endpoint).
-		WithHTTPClient(fshttp.NewClient(fs.Config)).
+		WithHTTPClient(fshttp.NewClient(ci)).
 		WithS3ForcePathStyle(opt.ForcePathStyle)
 	// awsConfig.WithLogLevel(aws.LogDebugWithSigning)
 	ses := session.New()
@@ -748,7 +795,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if err != nil {
 		return nil, err
 	}
-	c, ses, err := s3Connection(opt)
+	c, ses, err := s3Connection(opt, fs.GetConfigMap(name, m))
 	if err != nil {
 		return nil, err
 	}
@@ -1464,12 +1511,127 @@ func (o *Object) MimeType() string {
 	return o.mimeType
 }
 
+// restoreStatus is returned for each object by the restore command
+type restoreStatus struct {
+	Remote string
+	Status string
+}
+
+// restore restores the objects in f from GLACIER using the options
+// in opt
+func (f *Fs) restore(opt map[string]string) (out []restoreStatus, err error) {
+	req := s3.RestoreRequest{
+		Days: aws.Int64(1),
+		GlacierJobParameters: &s3.GlacierJobParameters{
+			Tier: aws.String(s3.TierStandard),
+		},
+	}
+	if lifetime := opt["lifetime"]; lifetime != "" {
+		days, err := strconv.ParseInt(lifetime, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad lifetime")
+		}
+		req.Days = &days
+	}
+	if priority := opt["priority"]; priority != "" {
+		req.GlacierJobParameters.Tier = &priority
+	}
+	if description := opt["description"]; description != "" {
+		req.Description = &description
+	}
+	out = []restoreStatus{}
+	var outMu sync.Mutex
+	err = walk.Walk(f, "", false, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			o, ok := entry.(*Object)
+			if !ok {
+				continue
+			}
+			status := restoreStatus{Remote: o.remote, Status: "OK"}
+			if fs.Config.DryRun {
+				fs.Logf(o, "Not restoring as --dry-run")
+				status.Status = "Not restored as --dry-run"
+			} else {
+				key := f.root + o.remote
+				_, err := f.c.RestoreObject(&s3.RestoreObjectInput{
+					Bucket:         &f.bucket,
+					Key:            &key,
+					RestoreRequest: &req,
+				})
+				if err != nil {
+					fs.Errorf(o, "Failed to restore: %v", err)
+					status.Status = err.Error()
+				} else {
+					fs.Infof(o, "Restore requested")
+				}
+			}
+			outMu.Lock()
+			out = append(out, status)
+			outMu.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "restore failed")
+	}
+	return out, nil
+}
+
+// listMultipartUploads lists the unfinished multipart uploads in f
+func (f *Fs) listMultipartUploads() (uploads []*s3.MultipartUpload, err error) {
+	uploads = []*s3.MultipartUpload{}
+	req := s3.ListMultipartUploadsInput{
+		Bucket: &f.bucket,
+		Prefix: &f.root,
+	}
+	for {
+		resp, err := f.c.ListMultipartUploads(&req)
+		if err != nil {
+			return nil, errors.Wrap(err, "list multipart uploads")
+		}
+		uploads = append(uploads, resp.Uploads...)
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		req.KeyMarker = resp.NextKeyMarker
+		req.UploadIdMarker = resp.NextUploadIdMarker
+	}
+	return uploads, nil
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	if f.bucket == "" {
+		return nil, errors.New("need a bucket for backend commands")
+	}
+	switch name {
+	case "restore":
+		return f.restore(opt)
+	case "list-multipart-uploads":
+		return f.listMultipartUploads()
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
 // Check the interfaces are satisfied
 var (
 	_ fs.Fs          = &Fs{}
 	_ fs.Copier      = &Fs{}
 	_ fs.PutStreamer = &Fs{}
 	_ fs.ListRer     = &Fs{}
+	_ fs.Commander   = &Fs{}
 	_ fs.Object      = &Object{}
 	_ fs.MimeTyper   = &Object{}
 )
@@ -13,6 +13,7 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -30,11 +31,13 @@ import (
 	"github.com/pkg/sftp"
 	"github.com/xanzy/ssh-agent"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/time/rate"
 )
 
 const (
-	connectionsPerSecond = 10 // don't make more than this many ssh connections/s
+	connectionsPerSecond    = 10     // don't make more than this many ssh connections/s
+	hashCommandNotSupported = "none" // hash command which disables that hash
 )
 
 var (
@@ -66,7 +69,34 @@ func init() {
 			IsPassword: true,
 		}, {
 			Name: "key_file",
-			Help: "Path to unencrypted PEM-encoded private key file, leave blank to use ssh-agent.",
+			Help: "Path to PEM-encoded private key file, leave blank or set key_use_agent to use ssh-agent.",
+		}, {
+			Name: "key_file_pass",
+			Help: `The passphrase to decrypt the PEM-encoded private key file.
+
+Only PEM encrypted key files (old OpenSSH format) are supported.`,
+			IsPassword: true,
+		}, {
+			Name: "key_use_agent",
+			Help: `When set forces the usage of the ssh-agent.
+
+When key_file is also set, the ".pub" file of the specified key_file is
+read and only the associated key is requested from the ssh-agent. This
+allows to avoid "Too many authentication failures for *username*" errors
+when the ssh-agent contains many keys.`,
+			Default: false,
+		}, {
+			Name: "known_hosts_file",
+			Help: `Optional path to known_hosts file.
+
+Set this value to enable server host key validation.
+
+Leading "~" will be expanded to the home directory.`,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "~/.ssh/known_hosts",
+				Help:  "Use OpenSSH's known_hosts file",
+			}},
 		}, {
 			Name:    "use_insecure_cipher",
 			Help:    "Enable the use of the aes128-cbc cipher. This cipher is insecure and may allow plaintext data to be recovered by an attacker.",
@@ -99,6 +129,44 @@ func init() {
 			Default:  true,
 			Help:     "Set the modified time on the remote if set.",
 			Advanced: true,
+		}, {
+			Name:     "md5sum_command",
+			Default:  "",
+			Help:     "The command used to read md5 hashes. Leave blank for autodetect, set to \"none\" to disable.",
+			Advanced: true,
+		}, {
+			Name:     "sha1sum_command",
+			Default:  "",
+			Help:     "The command used to read sha1 hashes. Leave blank for autodetect, set to \"none\" to disable.",
+			Advanced: true,
+		}, {
+			Name: "ciphers",
+			Help: `Space separated list of ciphers to be used for session encryption, ordered by preference.
+
+At least one must match with server configuration. This can be checked
+for example using "ssh -Q cipher". Leave blank to use the defaults.
+
+This must not be set if use_insecure_cipher is true.`,
+			Default:  fs.SpaceSepList{},
+			Advanced: true,
+		}, {
+			Name: "key_exchange",
+			Help: `Space separated list of key exchange algorithms, ordered by preference.
+
+At least one must match with server configuration. This can be checked
+for example using "ssh -Q kex". Leave blank to use the defaults.
+
+This must not be set if use_insecure_cipher is true.`,
+			Default:  fs.SpaceSepList{},
+			Advanced: true,
+		}, {
+			Name: "macs",
+			Help: `Space separated list of MACs (message authentication code) algorithms, ordered by preference.
+
+At least one must match with server configuration. This can be checked
+for example using "ssh -Q mac". Leave blank to use the defaults.`,
+			Default:  fs.SpaceSepList{},
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -106,16 +174,24 @@ func init() {
 
 // Options defines the configuration for this backend
 type Options struct {
-	Host              string `config:"host"`
-	User              string `config:"user"`
-	Port              string `config:"port"`
-	Pass              string `config:"pass"`
-	KeyFile           string `config:"key_file"`
-	UseInsecureCipher bool   `config:"use_insecure_cipher"`
-	DisableHashCheck  bool   `config:"disable_hashcheck"`
-	AskPassword       bool   `config:"ask_password"`
-	PathOverride      string `config:"path_override"`
-	SetModTime        bool   `config:"set_modtime"`
+	Host              string          `config:"host"`
+	User              string          `config:"user"`
+	Port              string          `config:"port"`
+	Pass              string          `config:"pass"`
+	KeyFile           string          `config:"key_file"`
+	KeyFilePass       string          `config:"key_file_pass"`
+	KeyUseAgent       bool            `config:"key_use_agent"`
+	KnownHostsFile    string          `config:"known_hosts_file"`
+	UseInsecureCipher bool            `config:"use_insecure_cipher"`
+	DisableHashCheck  bool            `config:"disable_hashcheck"`
+	AskPassword       bool            `config:"ask_password"`
+	PathOverride      string          `config:"path_override"`
+	SetModTime        bool            `config:"set_modtime"`
+	Md5sumCommand     string          `config:"md5sum_command"`
+	Sha1sumCommand    string          `config:"sha1sum_command"`
+	Ciphers           fs.SpaceSepList `config:"ciphers"`
+	KeyExchange       fs.SpaceSepList `config:"key_exchange"`
+	MACs              fs.SpaceSepList `config:"macs"`
 }
 
 // Fs stores the interface to the remote SFTP files
@@ -128,6 +204,7 @@ type Fs struct {
 	url          string
 	mkdirLock    *stringLock
 	cachedHashes *hash.Set
+	hashMu       sync.Mutex // protects cachedHashes and the hash commands
 	poolMu       sync.Mutex
 	pool         []*conn
 	connLimit    *rate.Limiter // for limiting number of connections per second
@@ -158,6 +235,52 @@ func readCurrentUser() (userName string) {
 	return os.Getenv("LOGNAME")
 }
 
+// expandHome expands a leading "~" in the path to the home directory
+// of the current user
+func expandHome(filePath string) string {
+	if filePath != "~" && !strings.HasPrefix(filePath, "~/") {
+		return filePath
+	}
+	usr, err := user.Current()
+	if err != nil {
+		fs.Errorf(nil, "Couldn't find home directory to expand %q: %v", filePath, err)
+		return filePath
+	}
+	return filepath.Join(usr.HomeDir, filePath[1:])
+}
+
+// keyboardInteractive returns an ssh.KeyboardInteractiveChallenge
+// which answers all the questions with the password passed in
+func keyboardInteractive(password string) ssh.KeyboardInteractiveChallenge {
+	return func(user, instruction string, questions []string, echos []bool) (answers []string, err error) {
+		answers = make([]string, len(questions))
+		for i := range answers {
+			answers[i] = password
+		}
+		return answers, nil
+	}
+}
+
+// filterSigners returns only the signers which match the public key
+// in pubBytes, which is in authorized_keys format
+func filterSigners(signers []ssh.Signer, pubBytes []byte) ([]ssh.Signer, error) {
+	pubKey, _, _, _, err := ssh.ParseAuthorizedKey(pubBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse public key file")
+	}
+	pubKeyRaw := pubKey.Marshal()
+	var filtered []ssh.Signer
+	for _, signer := range signers {
+		if bytes.Equal(pubKeyRaw, signer.PublicKey().Marshal()) {
+			filtered = append(filtered, signer)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil, errors.New("private key not found in the ssh-agent")
+	}
+	return filtered, nil
+}
+
 // Dial starts a client connection to the given SSH server. It is a
 // convenience function that connects to the given network address,
 // initiates the SSH handshake, and then sets up a Client.
@@ -306,16 +429,38 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		User:            opt.User,
 		Auth:            []ssh.AuthMethod{},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         fs.Config.ConnectTimeout,
+		Timeout:         fs.GetConfigMap(name, m).ConnectTimeout,
+	}
+
+	if opt.KnownHostsFile != "" {
+		hostKeyCallback, err := knownhosts.New(expandHome(opt.KnownHostsFile))
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't parse known_hosts_file")
+		}
+		sshConfig.HostKeyCallback = hostKeyCallback
 	}
 
+	if opt.UseInsecureCipher && (len(opt.Ciphers) > 0 || len(opt.KeyExchange) > 0) {
+		return nil, errors.New("use_insecure_cipher must be false if ciphers or key_exchange are set")
+	}
+	sshConfig.Config.SetDefaults()
 	if opt.UseInsecureCipher {
-		sshConfig.Config.SetDefaults()
 		sshConfig.Config.Ciphers = append(sshConfig.Config.Ciphers, "aes128-cbc")
 	}
+	if len(opt.Ciphers) > 0 {
+		sshConfig.Config.Ciphers = opt.Ciphers
+	}
+	if len(opt.KeyExchange) > 0 {
+		sshConfig.Config.KeyExchanges = opt.KeyExchange
+	}
+	if len(opt.MACs) > 0 {
+		sshConfig.Config.MACs = opt.MACs
+	}
+
+	keyFile := expandHome(opt.KeyFile)
 
 	// Add ssh agent-auth if no password or file specified
-	if opt.Pass == "" && opt.KeyFile == "" {
+	if (opt.Pass == "" && keyFile == "") || opt.KeyUseAgent {
 		sshAgentClient, _, err := sshagent.New()
 		if err != nil {
 			return nil, errors.Wrap(err, "couldn't connect to ssh-agent")
@@ -324,16 +469,38 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "couldn't read ssh agent signers")
 		}
+		if keyFile != "" {
+			pubBytes, err := ioutil.ReadFile(keyFile + ".pub")
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read public key file")
+			}
+			signers, err = filterSigners(signers, pubBytes)
+			if err != nil {
+				return nil, err
+			}
+		}
 		sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(signers...))
 	}
 
 	// Load key file if specified
-	if opt.KeyFile != "" {
-		key, err := ioutil.ReadFile(opt.KeyFile)
+	if keyFile != "" && !opt.KeyUseAgent {
+		key, err := ioutil.ReadFile(keyFile)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to read private key file")
 		}
-		signer, err := ssh.ParsePrivateKey(key)
+		clearpass := ""
+		if opt.KeyFilePass != "" {
+			clearpass, err = obscure.Reveal(opt.KeyFilePass)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var signer ssh.Signer
+		if clearpass == "" {
+			signer, err = ssh.ParsePrivateKey(key)
+		} else {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(clearpass))
+		}
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to parse private key file")
 		}
@@ -346,14 +513,20 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		if err != nil {
 			return nil, err
 		}
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(clearpass))
+		sshConfig.Auth = append(sshConfig.Auth,
+			ssh.Password(clearpass),
+			ssh.KeyboardInteractive(keyboardInteractive(clearpass)),
+		)
 	}
 
 	// Ask for password if none was defined and we're allowed to
 	if opt.Pass == "" && opt.AskPassword {
 		_, _ = fmt.Fprint(os.Stderr, "Enter SFTP password: ")
 		clearpass := config.ReadPassword()
-		sshConfig.Auth = append(sshConfig.Auth, ssh.Password(clearpass))
+		sshConfig.Auth = append(sshConfig.Auth,
+			ssh.Password(clearpass),
+			ssh.KeyboardInteractive(keyboardInteractive(clearpass)),
+		)
 	}
 
 	f := &Fs{
@@ -671,8 +844,44 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	return nil
 }
 
+// Commands to try, in order, to find a working hash command on the
+// remote if none is configured.
+var (
+	md5sumCommands  = []string{"md5sum", "md5 -r"}
+	sha1sumCommands = []string{"sha1sum", "sha1 -r", "shasum -a 1"}
+)
+
+// findHashCommand returns the first of commands which gives the
+// expected hash of "abc\n" on the remote, or hashCommandNotSupported
+// if none of them work.
+//
+// If command is set then only that is tried.
+func (f *Fs) findHashCommand(c *conn, command string, commands []string, expected string) string {
+	if command == hashCommandNotSupported {
+		return command
+	}
+	if command != "" {
+		commands = []string{command}
+	}
+	for _, command := range commands {
+		session, err := c.sshClient.NewSession()
+		if err != nil {
+			return hashCommandNotSupported
+		}
+		output, _ := session.Output("echo 'abc' | " + command)
+		_ = session.Close()
+		if parseHash(output) == expected {
+			fs.Debugf(f, "Using %q to read hashes", command)
+			return command
+		}
+	}
+	return hashCommandNotSupported
+}
+
 // Hashes returns the supported hash types of the filesystem
 func (f *Fs) Hashes() hash.Set {
+	f.hashMu.Lock()
+	defer f.hashMu.Unlock()
 	if f.cachedHashes != nil {
 		return *f.cachedHashes
 	}
@@ -687,24 +896,14 @@ func (f *Fs) Hashes() hash.Set {
 		return hash.Set(hash.None)
 	}
 	defer f.putSftpConnection(&c, err)
-	session, err := c.sshClient.NewSession()
-	if err != nil {
-		return hash.Set(hash.None)
-	}
-	sha1Output, _ := session.Output("echo 'abc' | sha1sum")
-	expectedSha1 := "03cfd743661f07975fa2f1220c5194cbaff48451"
-	_ = session.Close()
 
-	session, err = c.sshClient.NewSession()
-	if err != nil {
-		return hash.Set(hash.None)
-	}
-	md5Output, _ := session.Output("echo 'abc' | md5sum")
+	expectedSha1 := "03cfd743661f07975fa2f1220c5194cbaff48451"
+	f.opt.Sha1sumCommand = f.findHashCommand(c, f.opt.Sha1sumCommand, sha1sumCommands, expectedSha1)
 	expectedMd5 := "0bee89b07a248e27c83fc3d5951213c1"
-	_ = session.Close()
+	f.opt.Md5sumCommand = f.findHashCommand(c, f.opt.Md5sumCommand, md5sumCommands, expectedMd5)
 
-	sha1Works := parseHash(sha1Output) == expectedSha1
-	md5Works := parseHash(md5Output) == expectedMd5
+	sha1Works := f.opt.Sha1sumCommand != hashCommandNotSupported
+	md5Works := f.opt.Md5sumCommand != hashCommandNotSupported
 
 	set := hash.NewHashSet()
 	if !sha1Works && !md5Works {
@@ -717,11 +916,27 @@ func (f *Fs) Hashes() hash.Set {
 		set.Add(hash.MD5)
 	}
 
-	_ = session.Close()
 	f.cachedHashes = &set
 	return set
 }
 
+// hashCommand returns the command to read the hash of type r on the
+// remote or "" if it isn't supported
+func (f *Fs) hashCommand(r hash.Type) string {
+	if !f.Hashes().Contains(r) {
+		return ""
+	}
+	f.hashMu.Lock()
+	defer f.hashMu.Unlock()
+	switch r {
+	case hash.MD5:
+		return f.opt.Md5sumCommand
+	case hash.SHA1:
+		return f.opt.Sha1sumCommand
+	}
+	return ""
+}
+
 // Fs is the filesystem this remote sftp file object is located within
 func (o *Object) Fs() fs.Info {
 	return o.fs
@@ -743,20 +958,21 @@ func (o *Object) Remote() string {
 // Hash returns the selected checksum of the file
 // If no checksum is available it returns ""
 func (o *Object) Hash(r hash.Type) (string, error) {
-	var hashCmd string
 	if r == hash.MD5 {
 		if o.md5sum != nil {
 			return *o.md5sum, nil
 		}
-		hashCmd = "md5sum"
 	} else if r == hash.SHA1 {
 		if o.sha1sum != nil {
 			return *o.sha1sum, nil
 		}
-		hashCmd = "sha1sum"
 	} else {
 		return "", hash.ErrUnsupported
 	}
+	hashCmd := o.fs.hashCommand(r)
+	if hashCmd == "" {
+		return "", hash.ErrUnsupported
+	}
 
 	c, err := o.fs.getSftpConnection()
 	if err != nil {
@@ -3,10 +3,16 @@
 package sftp
 
 import (
+	"crypto/rand"
 	"fmt"
+	"os/user"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestShellEscape(t *testing.T) {
@@ -35,3 +41,50 @@ func TestParseHash(t *testing.T) {
 		assert.Equal(t, test.checksum, got, fmt.Sprintf("Test %d sshOutput = %q", i, test.sshOutput))
 	}
 }
+
+func TestExpandHome(t *testing.T) {
+	usr, err := user.Current()
+	require.NoError(t, err)
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"/etc/ssh/known_hosts", "/etc/ssh/known_hosts"},
+		{"~user/known_hosts", "~user/known_hosts"},
+		{"~", filepath.Join(usr.HomeDir, "")},
+		{"~/.ssh/known_hosts", filepath.Join(usr.HomeDir, ".ssh", "known_hosts")},
+	} {
+		assert.Equal(t, test.want, expandHome(test.in), test.in)
+	}
+}
+
+func TestKeyboardInteractive(t *testing.T) {
+	challenge := keyboardInteractive("potato")
+	answers, err := challenge("user", "", []string{"Password: ", "Again: "}, []bool{false, false})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"potato", "potato"}, answers)
+	answers, err = challenge("user", "", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, answers)
+}
+
+func TestFilterSigners(t *testing.T) {
+	var signers []ssh.Signer
+	for i := 0; i < 3; i++ {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		signer, err := ssh.NewSignerFromKey(key)
+		require.NoError(t, err)
+		signers = append(signers, signer)
+	}
+	pubBytes := ssh.MarshalAuthorizedKey(signers[1].PublicKey())
+	filtered, err := filterSigners(signers, pubBytes)
+	require.NoError(t, err)
+	assert.Equal(t, signers[1:2], filtered)
+
+	_, err = filterSigners(signers[2:], pubBytes)
+	assert.Error(t, err)
+
+	_, err = filterSigners(signers, []byte("not a key"))
+	assert.Error(t, err)
+}
@@ -231,7 +231,7 @@ func parsePath(path string) (container, directory string, err error) {
 }
 
 // swiftConnection makes a connection to swift
-func swiftConnection(opt *Options, name string) (*swift.Connection, error) {
+func swiftConnection(opt *Options, ci *fs.ConfigInfo) (*swift.Connection, error) {
 	c := &swift.Connection{
 		// Keep these in the same order as the Config for ease of checking
 		UserName:       opt.User,
@@ -247,9 +247,9 @@ func swiftConnection(opt *Options, name string) (*swift.Connection, error) {
 		AuthToken:      opt.AuthToken,
 		AuthVersion:    opt.AuthVersion,
 		EndpointType:   swift.EndpointType(opt.EndpointType),
-		ConnectTimeout: 10 * fs.Config.ConnectTimeout, // Use the timeouts in the transport
-		Timeout:        10 * fs.Config.Timeout,        // Use the timeouts in the transport
-		Transport:      fshttp.NewTransport(fs.Config),
+		ConnectTimeout: 10 * ci.ConnectTimeout, // Use the timeouts in the transport
+		Timeout:        10 * ci.Timeout,        // Use the timeouts in the transport
+		Transport:      fshttp.NewTransport(ci),
 	}
 	if opt.EnvAuth {
 		err := c.ApplyEnvironment()
@@ -333,7 +333,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		return nil, err
 	}
 
-	c, err := swiftConnection(opt, name)
+	c, err := swiftConnection(opt, fs.GetConfigMap(name, m))
 	if err != nil {
 		return nil, err
 	}
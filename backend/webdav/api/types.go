@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/ncw/rclone/fs/hash"
 )
 
 const (
@@ -58,12 +60,17 @@ type Response struct {
 //
 // Note that status collects all the status values for which we just
 // check the first is OK.
+//
+// Owncloud and Nextcloud can return checksums in the form
+//
+//	<oc:checksums><oc:checksum>SHA1:xxx MD5:yyy ADLER32:zzz</oc:checksum></oc:checksums>
 type Prop struct {
-	Status   []string  `xml:"DAV: status"`
-	Name     string    `xml:"DAV: prop>displayname,omitempty"`
-	Type     *xml.Name `xml:"DAV: prop>resourcetype>collection,omitempty"`
-	Size     int64     `xml:"DAV: prop>getcontentlength,omitempty"`
-	Modified Time      `xml:"DAV: prop>getlastmodified,omitempty"`
+	Status    []string  `xml:"DAV: status"`
+	Name      string    `xml:"DAV: prop>displayname,omitempty"`
+	Type      *xml.Name `xml:"DAV: prop>resourcetype>collection,omitempty"`
+	Size      int64     `xml:"DAV: prop>getcontentlength,omitempty"`
+	Modified  Time      `xml:"DAV: prop>getlastmodified,omitempty"`
+	Checksums []string  `xml:"prop>checksums>checksum,omitempty"`
 }
 
 // Parse a status of the form "HTTP/1.1 200 OK" or "HTTP/1.1 200"
@@ -89,6 +96,33 @@ func (p *Prop) StatusOK() bool {
 	return false
 }
 
+// Hashes returns a map of the checksums found in the Prop, keyed by
+// hash type. It returns nil if there were none.
+func (p *Prop) Hashes() (hashes map[hash.Type]string) {
+	for _, checksums := range p.Checksums {
+		for _, checksum := range strings.Fields(checksums) {
+			i := strings.IndexRune(checksum, ':')
+			if i < 0 {
+				continue
+			}
+			var ht hash.Type
+			switch strings.ToUpper(checksum[:i]) {
+			case "SHA1":
+				ht = hash.SHA1
+			case "MD5":
+				ht = hash.MD5
+			default:
+				continue
+			}
+			if hashes == nil {
+				hashes = make(map[hash.Type]string, 2)
+			}
+			hashes[ht] = strings.ToLower(checksum[i+1:])
+		}
+	}
+	return hashes
+}
+
 // PropValue is a tagged name and value
 type PropValue struct {
 	XMLName xml.Name `xml:""`
@@ -9,29 +9,35 @@ package webdav
 // docs for file webdav
 // https://docs.nextcloud.com/server/12/developer_manual/client_apis/WebDAV/index.html
 
-// indicates checksums can be set as metadata here
+// Checksums are set with the OC-Checksum header on upload and read
+// back with the oc:checksums property
 // https://github.com/nextcloud/server/issues/6129
-// owncloud seems to have checksums as metadata though - can read them
 
-// SetModTime might be possible
+// SetModTime is done with a PROPPATCH to lastmodified (mind the
+// missing get) which does the utime() call on Owncloud and Nextcloud
 // https://stackoverflow.com/questions/3579608/webdav-can-a-client-modify-the-mtime-of-a-file
-// ...support for a PROPSET to lastmodified (mind the missing get) which does the utime() call might be an option.
-// For example the ownCloud WebDAV server does it that way.
+
+// docs for chunked uploads
+// https://docs.nextcloud.com/server/12/developer_manual/client_apis/WebDAV/chunking.html
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ncw/rclone/backend/webdav/api"
 	"github.com/ncw/rclone/backend/webdav/odrvcookie"
 	"github.com/ncw/rclone/fs"
-	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/config/obscure"
@@ -39,17 +45,37 @@ import (
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/readers"
 	"github.com/ncw/rclone/lib/rest"
 	"github.com/pkg/errors"
 )
 
 const (
-	minSleep      = 10 * time.Millisecond
-	maxSleep      = 2 * time.Second
-	decayConstant = 2   // bigger for slower decay, exponential
-	defaultDepth  = "1" // depth for PROPFIND
+	minSleep         = 10 * time.Millisecond
+	maxSleep         = 2 * time.Second
+	decayConstant    = 2   // bigger for slower decay, exponential
+	defaultDepth     = "1" // depth for PROPFIND
+	defaultChunkSize = fs.SizeSuffix(10 * 1024 * 1024)
 )
 
+// owncloudProps is the PROPFIND body used for Owncloud and Nextcloud
+// to read the checksums as well as the usual properties
+var owncloudProps = []byte(`<?xml version="1.0"?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+ <d:prop>
+  <d:displayname />
+  <d:getlastmodified />
+  <d:getcontentlength />
+  <d:resourcetype />
+  <oc:checksums />
+ </d:prop>
+</d:propfind>
+`)
+
+// nextcloudURL matches the dav endpoint for a user's files on
+// Owncloud and Nextcloud, capturing the base URL and the user name
+var nextcloudURL = regexp.MustCompile(`^(.*)/dav/files/([^/]+)/?$`)
+
 // Register with Fs
 func init() {
 	fs.Register(&fs.RegInfo{
@@ -76,6 +102,9 @@ func init() {
 			}, {
 				Value: "sharepoint",
 				Help:  "Sharepoint",
+			}, {
+				Value: "rclone",
+				Help:  "rclone serve webdav",
 			}, {
 				Value: "other",
 				Help:  "Other site/service or software",
@@ -90,16 +119,28 @@ func init() {
 		}, {
 			Name: "bearer_token",
 			Help: "Bearer token instead of user/pass (eg a Macaroon)",
+		}, {
+			Name: "chunk_size",
+			Help: `Upload chunk size for Owncloud and Nextcloud
+
+Files bigger than this are uploaded in chunks of this size using the
+Owncloud/Nextcloud chunked upload protocol.  This needs the url to
+point to the /remote.php/dav/files/USER/ endpoint.
+
+Set to 0 to disable chunked uploads.`,
+			Default:  defaultChunkSize,
+			Advanced: true,
 		}},
 	})
 }
 
 // Options defines the configuration for this backend
 type Options struct {
-	URL    string `config:"url"`
-	Vendor string `config:"vendor"`
-	User   string `config:"user"`
-	Pass   string `config:"pass"`
+	URL       string        `config:"url"`
+	Vendor    string        `config:"vendor"`
+	User      string        `config:"user"`
+	Pass      string        `config:"pass"`
+	ChunkSize fs.SizeSuffix `config:"chunk_size"`
 }
 
 // Fs represents a remote webdav
@@ -115,6 +156,12 @@ type Fs struct {
 	precision          time.Duration // mod time precision
 	canStream          bool          // set if can stream
 	useOCMtime         bool          // set if can use X-OC-Mtime
+	propsetMtime       bool          // set if can use PROPPATCH to set the modification time
+	hasMD5             bool          // set if can read and write MD5 checksums with oc:checksums
+	hasSHA1            bool          // set if can read and write SHA1 checksums with oc:checksums
+	canChunk           bool          // set if can do chunked uploads
+	chunksUploadURL    string        // URL to make chunked uploads under
+	propsBody          []byte        // body for PROPFIND or nil for all properties
 	retryWithZeroDepth bool          // some vendors (sharepoint) won't list files when Depth is 1 (our default)
 }
 
@@ -128,6 +175,7 @@ type Object struct {
 	size        int64     // size of the object
 	modTime     time.Time // modification time of the object
 	sha1        string    // SHA-1 of the object content
+	md5         string    // MD5 of the object content
 }
 
 // ------------------------------------------------------------
@@ -194,9 +242,11 @@ func (f *Fs) readMetaDataForPath(path string, depth string) (info *api.Prop, err
 		},
 		NoRedirect: true,
 	}
+	f.setPropsBody(&opts)
 	var result api.Multistatus
 	var resp *http.Response
 	err = f.pacer.Call(func() (bool, error) {
+		f.resetPropsBody(&opts)
 		resp, err = f.srv.CallXML(&opts, nil, &result)
 		return shouldRetry(resp, err)
 	})
@@ -231,6 +281,25 @@ func (f *Fs) readMetaDataForPath(path string, depth string) (info *api.Prop, err
 	return &item.Props, nil
 }
 
+// setPropsBody sets the body of the PROPFIND in opts to ask for the
+// properties we need if we need more than the default
+func (f *Fs) setPropsBody(opts *rest.Opts) {
+	if f.propsBody == nil {
+		return
+	}
+	opts.ContentType = "application/xml; charset=utf-8"
+	opts.Body = bytes.NewReader(f.propsBody)
+}
+
+// resetPropsBody rewinds the body set by setPropsBody so the call
+// can be retried
+func (f *Fs) resetPropsBody(opts *rest.Opts) {
+	if f.propsBody == nil {
+		return
+	}
+	opts.Body = bytes.NewReader(f.propsBody)
+}
+
 // errorHandler parses a non 2xx error response into an error
 func errorHandler(resp *http.Response) error {
 	body, err := rest.ReadBody(resp)
@@ -283,9 +352,9 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	rootIsDir := strings.HasSuffix(root, "/")
 	root = strings.Trim(root, "/")
 
-	user := config.FileGet(name, "user")
-	pass := config.FileGet(name, "pass")
-	bearerToken := config.FileGet(name, "bearer_token")
+	user, _ := m.Get("user")
+	pass, _ := m.Get("pass")
+	bearerToken, _ := m.Get("bearer_token")
 	if !strings.HasSuffix(opt.URL, "/") {
 		opt.URL += "/"
 	}
@@ -313,7 +382,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		opt:         *opt,
 		endpoint:    u,
 		endpointURL: u.String(),
-		srv:         rest.NewClient(fshttp.NewClient(fs.Config)).SetRoot(u.String()),
+		srv:         rest.NewClient(fshttp.NewClient(fs.GetConfigMap(name, m))).SetRoot(u.String()),
 		pacer:       pacer.New().SetMinSleep(minSleep).SetMaxSleep(maxSleep).SetDecayConstant(decayConstant),
 		precision:   fs.ModTimeNotSupported,
 	}
@@ -360,9 +429,20 @@ func (f *Fs) setQuirks(vendor string) error {
 		f.canStream = true
 		f.precision = time.Second
 		f.useOCMtime = true
+		f.propsetMtime = true
+		f.hasMD5 = true
+		f.hasSHA1 = true
+		f.canChunk = true
 	case "nextcloud":
 		f.precision = time.Second
 		f.useOCMtime = true
+		f.propsetMtime = true
+		f.hasSHA1 = true
+		f.canChunk = true
+	case "rclone":
+		f.canStream = true
+		f.precision = time.Second
+		f.useOCMtime = true
 	case "sharepoint":
 		// To mount sharepoint, two Cookies are required
 		// They have to be set instead of BasicAuth
@@ -384,6 +464,16 @@ func (f *Fs) setQuirks(vendor string) error {
 		fs.Debugf(f, "Unknown vendor %q", vendor)
 	}
 
+	// Ask for the checksums in PROPFIND if we can read them
+	if f.hasMD5 || f.hasSHA1 {
+		f.propsBody = owncloudProps
+	}
+
+	// Work out where to upload chunks to
+	if f.canChunk {
+		f.setupChunking()
+	}
+
 	// Remove PutStream from optional features
 	if !f.canStream {
 		f.features.PutStream = nil
@@ -391,6 +481,24 @@ func (f *Fs) setQuirks(vendor string) error {
 	return nil
 }
 
+// setupChunking works out the URL to make chunked uploads to from
+// the endpoint, disabling chunked uploads if it isn't possible
+func (f *Fs) setupChunking() {
+	if f.opt.ChunkSize <= 0 {
+		f.canChunk = false
+		return
+	}
+	match := nextcloudURL.FindStringSubmatch(f.endpointURL)
+	if match == nil {
+		fs.Debugf(f, "Chunked uploads disabled: url must end in /dav/files/USER/ to use them")
+		f.canChunk = false
+		return
+	}
+	f.chunksUploadURL = match[1] + "/dav/uploads/" + match[2] + "/"
+	// Uploads of unknown length can be chunked
+	f.canStream = true
+}
+
 // Return an Object from a path
 //
 // If it can't be found it returns the error fs.ErrorObjectNotFound.
@@ -437,9 +545,11 @@ func (f *Fs) listAll(dir string, directoriesOnly bool, filesOnly bool, depth str
 			"Depth": depth,
 		},
 	}
+	f.setPropsBody(&opts)
 	var result api.Multistatus
 	var resp *http.Response
 	err = f.pacer.Call(func() (bool, error) {
+		f.resetPropsBody(&opts)
 		resp, err = f.srv.CallXML(&opts, nil, &result)
 		return shouldRetry(resp, err)
 	})
@@ -834,7 +944,14 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
-	return hash.Set(hash.None)
+	hashes := hash.Set(hash.None)
+	if f.hasMD5 {
+		hashes.Add(hash.MD5)
+	}
+	if f.hasSHA1 {
+		hashes.Add(hash.SHA1)
+	}
+	return hashes
 }
 
 // ------------------------------------------------------------
@@ -857,12 +974,15 @@ func (o *Object) Remote() string {
 	return o.remote
 }
 
-// Hash returns the SHA-1 of an object returning a lowercase hex string
+// Hash returns the SHA-1 or MD5 of an object returning a lowercase hex string
 func (o *Object) Hash(t hash.Type) (string, error) {
-	if t != hash.SHA1 {
-		return "", hash.ErrUnsupported
+	switch {
+	case t == hash.SHA1 && o.fs.hasSHA1:
+		return o.sha1, nil
+	case t == hash.MD5 && o.fs.hasMD5:
+		return o.md5, nil
 	}
-	return o.sha1, nil
+	return "", hash.ErrUnsupported
 }
 
 // Size returns the size of an object in bytes
@@ -880,6 +1000,9 @@ func (o *Object) setMetaData(info *api.Prop) (err error) {
 	o.hasMetaData = true
 	o.size = info.Size
 	o.modTime = time.Time(info.Modified)
+	hashes := info.Hashes()
+	o.sha1 = hashes[hash.SHA1]
+	o.md5 = hashes[hash.MD5]
 	return nil
 }
 
@@ -911,8 +1034,47 @@ func (o *Object) ModTime() time.Time {
 }
 
 // SetModTime sets the modification time of the local fs object
+//
+// This uses a PROPPATCH of lastmodified which Owncloud and Nextcloud
+// use to set the modification time of the file.
 func (o *Object) SetModTime(modTime time.Time) error {
-	return fs.ErrorCantSetModTime
+	if !o.fs.propsetMtime {
+		return fs.ErrorCantSetModTime
+	}
+	body := fmt.Sprintf(`<?xml version="1.0"?>
+<d:propertyupdate xmlns:d="DAV:">
+ <d:set>
+  <d:prop>
+   <d:lastmodified>%d</d:lastmodified>
+  </d:prop>
+ </d:set>
+</d:propertyupdate>
+`, modTime.Unix())
+	opts := rest.Opts{
+		Method:      "PROPPATCH",
+		Path:        o.filePath(),
+		ContentType: "application/xml; charset=utf-8",
+		NoRedirect:  true,
+	}
+	var result api.Multistatus
+	var resp *http.Response
+	var err error
+	err = o.fs.pacer.Call(func() (bool, error) {
+		opts.Body = strings.NewReader(body)
+		resp, err = o.fs.srv.CallXML(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		if apiErr, ok := err.(*api.Error); ok && apiErr.StatusCode == http.StatusNotFound {
+			return fs.ErrorObjectNotFound
+		}
+		return errors.Wrap(err, "couldn't set modification time")
+	}
+	if len(result.Responses) < 1 || !result.Responses[0].Props.StatusOK() {
+		return fs.ErrorCantSetModTime
+	}
+	o.modTime = modTime
+	return nil
 }
 
 // Storable returns a boolean showing whether this object storable
@@ -950,23 +1112,23 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	}
 
 	size := src.Size()
-	var resp *http.Response
-	opts := rest.Opts{
-		Method:        "PUT",
-		Path:          o.filePath(),
-		Body:          in,
-		NoResponse:    true,
-		ContentLength: &size, // FIXME this isn't necessary with owncloud - See https://github.com/nextcloud/nextcloud-snap/issues/365
-	}
-	if o.fs.useOCMtime {
-		opts.ExtraHeaders = map[string]string{
-			"X-OC-Mtime": fmt.Sprintf("%f", float64(src.ModTime().UnixNano())/1E9),
+	if o.fs.canChunk && (size < 0 || size > int64(o.fs.opt.ChunkSize)) {
+		err = o.updateChunked(in, src)
+	} else {
+		var resp *http.Response
+		opts := rest.Opts{
+			Method:        "PUT",
+			Path:          o.filePath(),
+			Body:          in,
+			NoResponse:    true,
+			ContentLength: &size, // FIXME this isn't necessary with owncloud - See https://github.com/nextcloud/nextcloud-snap/issues/365
+			ExtraHeaders:  o.extraHeaders(src),
 		}
+		err = o.fs.pacer.CallNoRetry(func() (bool, error) {
+			resp, err = o.fs.srv.Call(&opts)
+			return shouldRetry(resp, err)
+		})
 	}
-	err = o.fs.pacer.CallNoRetry(func() (bool, error) {
-		resp, err = o.fs.srv.Call(&opts)
-		return shouldRetry(resp, err)
-	})
 	if err != nil {
 		// Remove failed upload
 		_ = o.Remove()
@@ -977,6 +1139,140 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	return o.readMetaData()
 }
 
+// extraHeaders returns the headers to send when uploading src to set
+// its modification time and checksum if possible
+func (o *Object) extraHeaders(src fs.ObjectInfo) map[string]string {
+	extraHeaders := map[string]string{}
+	if o.fs.useOCMtime {
+		extraHeaders["X-OC-Mtime"] = fmt.Sprintf("%f", float64(src.ModTime().UnixNano())/1e9)
+	}
+	// Only one checksum can be set - prefer SHA1
+	if o.fs.hasSHA1 {
+		if sha1, _ := src.Hash(hash.SHA1); sha1 != "" {
+			extraHeaders["OC-Checksum"] = "SHA1:" + sha1
+		}
+	}
+	if o.fs.hasMD5 && extraHeaders["OC-Checksum"] == "" {
+		if md5, _ := src.Hash(hash.MD5); md5 != "" {
+			extraHeaders["OC-Checksum"] = "MD5:" + md5
+		}
+	}
+	return extraHeaders
+}
+
+// updateChunked uploads in to the object in chunks
+//
+// The chunks are uploaded into a temporary directory under
+// f.chunksUploadURL then assembled into the object by a MOVE of the
+// special file ".file" in that directory.
+func (o *Object) updateChunked(in io.Reader, src fs.ObjectInfo) (err error) {
+	var id [8]byte
+	_, err = rand.Read(id[:])
+	if err != nil {
+		return errors.Wrap(err, "failed to make upload ID")
+	}
+	uploadDir := "rclone-chunked-upload-" + hex.EncodeToString(id[:]) + "/"
+	destinationURL, err := rest.URLJoin(o.fs.endpoint, o.filePath())
+	if err != nil {
+		return errors.Wrap(err, "chunked upload couldn't join URL")
+	}
+	destination := destinationURL.String()
+
+	// Make the directory to upload the chunks into
+	var resp *http.Response
+	opts := rest.Opts{
+		Method:     "MKCOL",
+		RootURL:    o.fs.chunksUploadURL,
+		Path:       uploadDir,
+		NoResponse: true,
+		ExtraHeaders: map[string]string{
+			"Destination": destination,
+		},
+	}
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to make chunked upload directory")
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Remove the chunks if the upload failed
+		opts := rest.Opts{
+			Method:     "DELETE",
+			RootURL:    o.fs.chunksUploadURL,
+			Path:       uploadDir,
+			NoResponse: true,
+		}
+		removeErr := o.fs.pacer.Call(func() (bool, error) {
+			resp, err := o.fs.srv.Call(&opts)
+			return shouldRetry(resp, err)
+		})
+		if removeErr != nil {
+			fs.Debugf(o, "Failed to remove chunked upload directory: %v", removeErr)
+		}
+	}()
+
+	// Upload the chunks
+	buf := make([]byte, int(o.fs.opt.ChunkSize))
+	var offset int64
+	for {
+		n, readErr := readers.ReadFill(in, buf)
+		if readErr != nil && readErr != io.EOF {
+			return errors.Wrap(readErr, "failed to read chunk")
+		}
+		if n == 0 && offset > 0 {
+			break
+		}
+		chunk := buf[:n]
+		chunkSize := int64(n)
+		opts := rest.Opts{
+			Method:        "PUT",
+			RootURL:       o.fs.chunksUploadURL,
+			Path:          uploadDir + fmt.Sprintf("%015d", offset),
+			NoResponse:    true,
+			ContentLength: &chunkSize,
+			ExtraHeaders: map[string]string{
+				"Destination": destination,
+			},
+		}
+		err = o.fs.pacer.Call(func() (bool, error) {
+			opts.Body = bytes.NewReader(chunk)
+			resp, err = o.fs.srv.Call(&opts)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to upload chunk at offset %d", offset)
+		}
+		offset += chunkSize
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	// Assemble the chunks into the object
+	opts = rest.Opts{
+		Method:       "MOVE",
+		RootURL:      o.fs.chunksUploadURL,
+		Path:         uploadDir + ".file",
+		NoResponse:   true,
+		ExtraHeaders: o.extraHeaders(src),
+	}
+	opts.ExtraHeaders["Destination"] = destination
+	opts.ExtraHeaders["OC-Total-Length"] = strconv.FormatInt(offset, 10)
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(&opts)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to assemble chunks")
+	}
+	return nil
+}
+
 // Remove an object
 func (o *Object) Remove() error {
 	opts := rest.Opts{
@@ -0,0 +1,202 @@
+package webdav
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/backend/webdav/api"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	filesPrefix   = "/remote.php/dav/files/user/"
+	uploadsPrefix = "/remote.php/dav/uploads/user/"
+)
+
+// fakeNextcloud is a minimal Nextcloud server supporting the
+// requests needed for chunked uploads, checksums and modtimes
+type fakeNextcloud struct {
+	mu       sync.Mutex
+	files    map[string][]byte         // file contents by path
+	mtimes   map[string]time.Time      // modification times by path
+	sums     map[string]string         // OC-Checksum by path
+	chunks   map[string]map[string]int // chunk upload dirs to chunk sizes
+	uploads  map[string][]byte         // chunk contents by path
+	moveHdrs http.Header               // headers of the last chunk assembly
+	puts     int                       // number of PUTs to the files endpoint
+}
+
+func newFakeNextcloud() *fakeNextcloud {
+	return &fakeNextcloud{
+		files:   map[string][]byte{},
+		mtimes:  map[string]time.Time{},
+		sums:    map[string]string{},
+		chunks:  map[string]map[string]int{},
+		uploads: map[string][]byte{},
+	}
+}
+
+func (n *fakeNextcloud) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	p := r.URL.Path
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.Method == "MKCOL" && strings.HasPrefix(p, uploadsPrefix):
+		n.chunks[p] = map[string]int{}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "MKCOL":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	case r.Method == "PUT" && strings.HasPrefix(p, uploadsPrefix):
+		n.uploads[p] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PUT":
+		n.puts++
+		n.store(p, body, r.Header)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "MOVE" && strings.HasSuffix(p, "/.file"):
+		dir := strings.TrimSuffix(p, ".file")
+		var names []string
+		for name := range n.uploads {
+			if strings.HasPrefix(name, dir) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var buf bytes.Buffer
+		for _, name := range names {
+			buf.Write(n.uploads[name])
+		}
+		dst, _ := url.Parse(r.Header.Get("Destination"))
+		n.store(dst.Path, buf.Bytes(), r.Header)
+		n.moveHdrs = r.Header
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == "PROPPATCH":
+		var t int64
+		_, err := fmt.Sscanf(string(body[strings.Index(string(body), "<d:lastmodified>"):]), "<d:lastmodified>%d", &t)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n.mtimes[p] = time.Unix(t, 0)
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:"><d:response><d:href>%s</d:href><d:propstat><d:prop><d:lastmodified/></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response></d:multistatus>`, p)
+	case r.Method == "PROPFIND":
+		data, ok := n.files[p]
+		if !ok {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns"><d:response><d:href>%s</d:href><d:propstat><d:prop><d:getlastmodified>%s</d:getlastmodified><d:getcontentlength>%d</d:getcontentlength><d:resourcetype/><oc:checksums><oc:checksum>%s</oc:checksum></oc:checksums></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response></d:multistatus>`,
+			p, n.mtimes[p].UTC().Format(http.TimeFormat), len(data), n.sums[p])
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// store saves the data at path p setting the modtime and checksum
+// from the headers passed in
+func (n *fakeNextcloud) store(p string, data []byte, header http.Header) {
+	n.files[p] = data
+	n.sums[p] = header.Get("OC-Checksum")
+	var t float64
+	if _, err := fmt.Sscanf(header.Get("X-OC-Mtime"), "%f", &t); err == nil {
+		n.mtimes[p] = time.Unix(int64(t), 0)
+	}
+}
+
+func prepareNextcloud(t *testing.T, chunkSize string) (*fakeNextcloud, fs.Fs, func()) {
+	n := newFakeNextcloud()
+	ts := httptest.NewServer(n)
+	f, err := NewFs("TestWebdavNextcloud", "", configmap.Simple{
+		"type":       "webdav",
+		"url":        ts.URL + filesPrefix,
+		"vendor":     "nextcloud",
+		"chunk_size": chunkSize,
+	})
+	require.NoError(t, err)
+	return n, f, ts.Close
+}
+
+func TestPropHashes(t *testing.T) {
+	p := api.Prop{Checksums: []string{"SHA1:0BEEC7B5EA3F0FDBC95D0DD47F3C5BC275DA8A33 MD5:acbd18db4cc2f85cedef654fccc4a4d8 ADLER32:02820145"}}
+	assert.Equal(t, map[hash.Type]string{
+		hash.SHA1: "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33",
+		hash.MD5:  "acbd18db4cc2f85cedef654fccc4a4d8",
+	}, p.Hashes())
+	p = api.Prop{}
+	assert.Nil(t, p.Hashes())
+}
+
+func TestChunkedUpload(t *testing.T) {
+	n, f, tidy := prepareNextcloud(t, "10b")
+	defer tidy()
+	assert.True(t, f.Hashes().Contains(hash.SHA1))
+
+	contents := []byte("0123456789abcdefghijABCDE")
+	sha1 := "fe2bc8a2358340096d471c4cc49e22afbb87ee10"
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	src := object.NewStaticObjectInfo("dir/file.txt", modTime, int64(len(contents)), true, map[hash.Type]string{hash.SHA1: sha1}, nil)
+	o, err := f.Put(bytes.NewReader(contents), src)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, n.puts)
+	assert.Equal(t, 3, len(n.uploads))
+	assert.Equal(t, contents, n.files[filesPrefix+"dir/file.txt"])
+	assert.Equal(t, "25", n.moveHdrs.Get("OC-Total-Length"))
+	assert.Equal(t, "SHA1:"+sha1, n.moveHdrs.Get("OC-Checksum"))
+
+	assert.Equal(t, int64(len(contents)), o.Size())
+	assert.True(t, modTime.Equal(o.ModTime()), o.ModTime())
+	gotSHA1, err := o.Hash(hash.SHA1)
+	require.NoError(t, err)
+	assert.Equal(t, sha1, gotSHA1)
+
+	// Small files are uploaded in one go
+	src = object.NewStaticObjectInfo("small.txt", modTime, 5, true, nil, nil)
+	_, err = f.Put(bytes.NewReader(contents[:5]), src)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n.puts)
+	assert.Equal(t, contents[:5], n.files[filesPrefix+"small.txt"])
+}
+
+func TestChunkedUploadDisabled(t *testing.T) {
+	n, f, tidy := prepareNextcloud(t, "0")
+	defer tidy()
+
+	contents := []byte("0123456789abcdefghijABCDE")
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	_, err := f.Put(bytes.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n.puts)
+	assert.Equal(t, 0, len(n.uploads))
+}
+
+func TestSetModTime(t *testing.T) {
+	n, f, tidy := prepareNextcloud(t, "10M")
+	defer tidy()
+
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), 5, true, nil, nil)
+	o, err := f.Put(bytes.NewReader([]byte("hello")), src)
+	require.NoError(t, err)
+
+	modTime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	require.NoError(t, o.SetModTime(modTime))
+	assert.True(t, modTime.Equal(n.mtimes[filesPrefix+"file.txt"]))
+	assert.True(t, modTime.Equal(o.ModTime()))
+}
@@ -28,6 +28,7 @@ docs = [
     "b2.md",
     "box.md",
     "cache.md",
+    "combine.md",
     "crypt.md",
     "dropbox.md",
     "ftp.md",
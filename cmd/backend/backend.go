@@ -0,0 +1,176 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	options    []string
+	jsonOutput bool
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	commandDefinition.Flags().StringArrayVarP(&options, "option", "o", options, "Option in the form name=value or name.")
+	commandDefinition.Flags().BoolVarP(&jsonOutput, "json", "", jsonOutput, "Always output in JSON format.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "backend <command> remote:path [opts] <args>",
+	Short: `Run a backend specific command.`,
+	Long: `
+This runs a backend specific command.  The commands themselves (except
+for "help" and "features") are defined by the backends and you should
+see the backend docs for definitions.
+
+You can discover what commands a backend implements by using
+
+    rclone backend help remote:
+    rclone backend help <backendname>
+
+You can also discover which optional features the backend supports with
+
+    rclone backend features remote:
+
+Pass options to the backend command with -o.  These should be
+key=value or key, eg
+
+    rclone backend restore s3:bucket/path -o priority=Bulk -o lifetime=2
+
+Pass arguments to the backend by placing them on the end of the line
+
+    rclone backend copyid drive: ID1 path1 ID2 path2
+
+If the result of the command is a string or a list of strings it is
+printed one per line, otherwise it is printed as JSON.  Use --json to
+always print the result as JSON.
+
+To run these commands on a running rclone see
+[backend/command](/rc/#backend-command) in the rc docs.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 1e6, command, args)
+		name, remote := args[0], args[1]
+		if name == "help" {
+			cmd.Run(false, false, command, func() error {
+				fsInfo, err := fs.Find(remote)
+				if err != nil {
+					fsInfo, _, _, err = fs.ParseRemote(remote)
+					if err != nil {
+						return err
+					}
+				}
+				return showHelp(fsInfo)
+			})
+			return
+		}
+		f := cmd.NewFsSrc(args[1:2])
+		cmd.Run(false, false, command, func() (err error) {
+			var out interface{}
+			switch name {
+			case "features":
+				var hashes []string
+				for _, hashType := range f.Hashes().Array() {
+					hashes = append(hashes, hashType.String())
+				}
+				out = map[string]interface{}{
+					"Name":      f.Name(),
+					"Root":      f.Root(),
+					"String":    f.String(),
+					"Precision": f.Precision(),
+					"Hashes":    hashes,
+					"Features":  f.Features().Enabled(),
+				}
+			default:
+				out, err = operations.Command(f, name, args[2:], rc.ParseOptions(options))
+				if err != nil {
+					return errors.Wrapf(err, "command %q failed", name)
+				}
+			}
+			return printResult(out)
+		})
+	},
+}
+
+// printResult prints the result of a backend command
+func printResult(out interface{}) error {
+	if !jsonOutput {
+		switch x := out.(type) {
+		case nil:
+			return nil
+		case string:
+			fmt.Println(x)
+			return nil
+		case []string:
+			for _, line := range x {
+				fmt.Println(line)
+			}
+			return nil
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	err := enc.Encode(out)
+	if err != nil {
+		return errors.Wrap(err, "failed to write JSON")
+	}
+	return nil
+}
+
+// showHelp shows the help for the commands of the backend fsInfo in
+// markdown format
+func showHelp(fsInfo *fs.RegInfo) error {
+	cmds := fsInfo.CommandHelp
+	name := fsInfo.Name
+	if len(cmds) == 0 {
+		return errors.Errorf("%s backend has no commands", name)
+	}
+	fmt.Printf("### Backend commands\n\n")
+	fmt.Printf(`Here are the commands specific to the %s backend.
+
+Run them with
+
+    rclone backend COMMAND remote:
+
+The help below will explain what arguments each command takes.
+
+See [the "rclone backend" command](/commands/rclone_backend/) for more
+info on how to pass options and arguments.
+
+These can be run on a running rclone using the rc command
+[backend/command](/rc/#backend-command).
+
+`, name)
+	for _, command := range cmds {
+		fmt.Printf("#### %s\n\n", command.Name)
+		fmt.Printf("%s\n\n", command.Short)
+		fmt.Printf("    rclone backend %s remote: [options] [<arguments>+]\n\n", command.Name)
+		if command.Long != "" {
+			fmt.Printf("%s\n\n", strings.TrimSpace(command.Long))
+		}
+		if len(command.Opts) != 0 {
+			fmt.Printf("Options:\n\n")
+			var keys []string
+			for key := range command.Opts {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("- %q: %s\n", key, command.Opts[key])
+			}
+			fmt.Printf("\n")
+		}
+	}
+	return nil
+}
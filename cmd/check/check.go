@@ -14,8 +14,9 @@ var (
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
-	commandDefintion.Flags().BoolVarP(&download, "download", "", download, "Check by downloading rather than with hash.")
+	commandDefintion.Flags().BoolVarP(&download, "download", "", download, "Check by downloading files which can't be checked with a hash.")
 	commandDefintion.Flags().BoolVarP(&oneway, "one-way", "", oneway, "Check one way only, source files must exist on remote")
+	cmd.AddReportFlags(commandDefintion.Flags())
 }
 
 var commandDefintion = &cobra.Command{
@@ -29,23 +30,34 @@ match.  It doesn't alter the source or destination.
 If you supply the --size-only flag, it will only compare the sizes not
 the hashes as well.  Use this for a quick check.
 
-If you supply the --download flag, it will download the data from
-both remotes and check them against each other on the fly.  This can
-be useful for remotes that don't support hashes or if you really want
-to check all the data.
+If you supply the --download flag, it will compare the hashes of
+files where the remotes have a hash in common and download the data
+from both remotes and check them against each other on the fly where
+they don't.  This can be useful for remotes that don't support hashes.
+Both sides are downloaded at the same time.  If you really want to
+check all the data, add the --ignore-checksum flag too and every file
+will be downloaded.
 
 If you supply the --one-way flag, it will only check that files in source
 match the files in destination, not the other way around. Meaning extra files in
 destination that are not in the source will not trigger an error.
-`,
+` + cmd.ReportHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(false, false, command, func() error {
-			if download {
-				return operations.CheckDownload(fdst, fsrc, oneway)
-			}
-			return operations.Check(fdst, fsrc, oneway)
+			return cmd.WithReport(func(report *operations.Report) error {
+				opt := &operations.CheckOpt{
+					Fdst:   fdst,
+					Fsrc:   fsrc,
+					OneWay: oneway,
+					Report: report,
+				}
+				if download {
+					return operations.CheckDownload(opt)
+				}
+				return operations.Check(opt)
+			})
 		})
 	},
 }
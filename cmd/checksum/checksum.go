@@ -0,0 +1,91 @@
+package checksum
+
+import (
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	download = false
+	oneway   = false
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&download, "download", "", download, "Check by downloading and hashing the files rather than reading the hashes from the remote.")
+	commandDefintion.Flags().BoolVarP(&oneway, "one-way", "", oneway, "Check one way only, files in the sum file must exist on the remote")
+	cmd.AddReportFlags(commandDefintion.Flags())
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "checksum <hash> sumfile dst:path",
+	Short: `Checks the files in the destination against a SUM file.`,
+	Long: `
+Checks that the hashes in the destination files match the ones in the
+SUM file, such as an MD5SUMS or SHA1SUMS file in the format produced by
+md5sum and sha1sum, and logs a report of files which don't match.  It
+doesn't alter the file system.
+
+<hash> is the type of hash in the SUM file - run ` + "`rclone hashsum`" + `
+to see the list of supported hashes.  The sumfile may be a local file
+or a file on a remote.
+
+    rclone checksum MD5 /path/to/MD5SUMS remote:path
+
+The paths in the SUM file are relative to dst:path.  Files which are in
+the SUM file but not in the destination, files in the destination which
+aren't in the SUM file and files whose hashes differ are reported.
+Filters, including --files-from, are applied to the paths in the SUM
+file as well as to the destination.
+
+If the destination doesn't support the hash type then the files are
+downloaded and hashed.  If you supply the --download flag the files
+will always be downloaded and hashed, which is useful if you don't
+trust the hashes stored on the remote.
+
+If you supply the --one-way flag, files in the destination which are
+not in the SUM file will not trigger an error.
+` + cmd.ReportHelp,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(3, 3, command, args)
+		var ht hash.Type
+		err := ht.Set(args[0])
+		if err != nil {
+			return err
+		}
+		fsum, sumFile := cmd.NewFsFile(args[1])
+		if sumFile == "" {
+			return errors.Errorf("%s is not a file", args[1])
+		}
+		fdst := cmd.NewFsDir(args[2:])
+		cmd.Run(false, true, command, func() error {
+			return cmd.WithReport(func(report *operations.Report) error {
+				return checkSum(fsum, sumFile, ht, fdst, report)
+			})
+		})
+		return nil
+	},
+}
+
+// checkSum opens the sum file and checks fdst against it
+func checkSum(fsum fs.Fs, sumFile string, ht hash.Type, fdst fs.Fs, report *operations.Report) (err error) {
+	o, err := fsum.NewObject(sumFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to find sum file")
+	}
+	in, err := o.Open()
+	if err != nil {
+		return errors.Wrap(err, "failed to open sum file")
+	}
+	defer fs.CheckClose(in, &err)
+	return operations.CheckSum(in, ht, download, &operations.CheckOpt{
+		Fdst:   fdst,
+		OneWay: oneway,
+		Report: report,
+	})
+}
@@ -64,6 +64,7 @@ const (
 	exitCodeNoRetryError
 	exitCodeFatalError
 	exitCodeTransferExceeded
+	exitCodeDurationExceeded
 )
 
 // Root is the main rclone command
@@ -486,8 +487,10 @@ func resolveExitCode(err error) {
 		os.Exit(exitCodeFileNotFound)
 	case unwrapped == errorUncategorized:
 		os.Exit(exitCodeUncategorizedError)
-	case unwrapped == accounting.ErrorMaxTransferLimitReached:
+	case unwrapped == accounting.ErrorMaxTransferLimitReached || unwrapped == accounting.ErrorMaxTransferLimitReachedGraceful:
 		os.Exit(exitCodeTransferExceeded)
+	case unwrapped == accounting.ErrorMaxDurationReached || unwrapped == accounting.ErrorMaxDurationReachedGraceful:
+		os.Exit(exitCodeDurationExceeded)
 	case fserrors.ShouldRetry(err):
 		os.Exit(exitCodeRetryError)
 	case fserrors.IsNoRetryError(err):
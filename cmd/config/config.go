@@ -17,6 +17,7 @@ func init() {
 	configCommand.AddCommand(configUpdateCommand)
 	configCommand.AddCommand(configDeleteCommand)
 	configCommand.AddCommand(configPasswordCommand)
+	configCommand.AddCommand(configValidateCommand)
 }
 
 var configCommand = &cobra.Command{
@@ -139,3 +140,25 @@ For example to set password of a remote of name myremote you would do:
 		return config.PasswordRemote(args[0], args[1:])
 	},
 }
+
+var configValidateCommand = &cobra.Command{
+	Use:   "validate [<remote>]+",
+	Short: `Check the config of remotes for errors.`,
+	Long: `
+Check the config of the remotes given, or all the remotes in the
+config file if none are given, against the options of their backends.
+
+This checks that the type is set and known, that any remote named in
+` + "`inherits`" + ` exists, that values can be parsed as the type of the
+option, that passwords are obscured and that required options are
+set. Unknown options are reported as warnings.
+
+Environment variables in values (eg ` + "`${HOME}`" + `) are expanded
+before checking, but ` + "`*_command`" + ` values are not run.
+
+It exits with a non zero exit code if any remote has errors.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		return config.Validate(args)
+	},
+}
@@ -9,6 +9,7 @@ import (
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	cmd.AddReportFlags(commandDefintion.Flags())
 }
 
 var commandDefintion = &cobra.Command{
@@ -50,13 +51,15 @@ If you are familiar with ` + "`rsync`" + `, rclone always works as if you had
 written a trailing / - meaning "copy the contents of this directory".
 This applies to all commands and whether you are talking about the
 source or destination.
-`,
+` + cmd.ReportHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
-				return sync.CopyDir(fdst, fsrc)
+				return cmd.WithReport(func(report *operations.Report) error {
+					return sync.CopyDirWithReport(fdst, fsrc, report)
+				})
 			}
 			return operations.CopyFile(fdst, fsrc, srcFileName, srcFileName)
 		})
@@ -18,6 +18,7 @@ var (
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&oneway, "one-way", "", oneway, "Check one way only, source files must exist on destination")
+	cmd.AddReportFlags(commandDefintion.Flags())
 }
 
 var commandDefintion = &cobra.Command{
@@ -28,8 +29,9 @@ rclone cryptcheck checks a remote against a crypted remote.  This is
 the equivalent of running rclone check, but able to check the
 checksums of the crypted remote.
 
-For it to work the underlying remote of the cryptedremote must support
-some kind of checksum.
+If the underlying remote of the cryptedremote doesn't support any kind
+of checksum, or a file has no checksum, then the files are downloaded
+and their contents compared instead.
 
 It works by reading the nonce from each file on the cryptedremote: and
 using that to encrypt each file on the remote:.  It then checks the
@@ -50,18 +52,20 @@ After it has run it will log the status of the encryptedremote:.
 If you supply the --one-way flag, it will only check that files in source
 match the files in destination, not the other way around. Meaning extra files in
 destination that are not in the source will not trigger an error.
-`,
+` + cmd.ReportHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(false, true, command, func() error {
-			return cryptCheck(fdst, fsrc)
+			return cmd.WithReport(func(report *operations.Report) error {
+				return cryptCheck(fdst, fsrc, report)
+			})
 		})
 	},
 }
 
 // cryptCheck checks the integrity of a crypted remote
-func cryptCheck(fdst, fsrc fs.Fs) error {
+func cryptCheck(fdst, fsrc fs.Fs, report *operations.Report) error {
 	// Check to see fcrypt is a crypt
 	fcrypt, ok := fdst.(*crypt.Fs)
 	if !ok {
@@ -71,7 +75,14 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 	funderlying := fcrypt.UnWrap()
 	hashType := funderlying.Hashes().GetOne()
 	if hashType == hash.None {
-		return errors.Errorf("%s:%s does not support any hashes", funderlying.Name(), funderlying.Root())
+		fs.Logf(nil, "%s:%s does not support any hashes so downloading files to check them", funderlying.Name(), funderlying.Root())
+		return operations.CheckFn(&operations.CheckOpt{
+			Fdst:   fcrypt,
+			Fsrc:   fsrc,
+			Check:  operations.CheckIdenticalDownload,
+			OneWay: oneway,
+			Report: report,
+		})
 	}
 	fs.Infof(nil, "Using %v for hash comparisons", hashType)
 
@@ -79,36 +90,44 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 	//
 	// it returns true if differences were found
 	// it also returns whether it couldn't be hashed
-	checkIdentical := func(dst, src fs.Object) (differ bool, noHash bool) {
+	checkIdentical := func(dst, src fs.Object) (differ bool, noHash bool, err error) {
 		cryptDst := dst.(*crypt.Object)
 		underlyingDst := cryptDst.UnWrap()
 		underlyingHash, err := underlyingDst.Hash(hashType)
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(dst, "Error reading hash from underlying %v: %v", underlyingDst, err)
-			return true, false
+			return true, false, err
 		}
 		if underlyingHash == "" {
-			return false, true
+			fs.Debugf(src, "No hash on underlying object - downloading to check")
+			return operations.CheckIdenticalDownload(dst, src)
 		}
 		cryptHash, err := fcrypt.ComputeHash(cryptDst, src, hashType)
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(dst, "Error computing hash: %v", err)
-			return true, false
+			return true, false, err
 		}
 		if cryptHash == "" {
-			return false, true
+			fs.Debugf(src, "Couldn't compute hash - downloading to check")
+			return operations.CheckIdenticalDownload(dst, src)
 		}
 		if cryptHash != underlyingHash {
 			err = errors.Errorf("hashes differ (%s:%s) %q vs (%s:%s) %q", fdst.Name(), fdst.Root(), cryptHash, fsrc.Name(), fsrc.Root(), underlyingHash)
 			fs.CountError(err)
 			fs.Errorf(src, err.Error())
-			return true, false
+			return true, false, nil
 		}
 		fs.Debugf(src, "OK")
-		return false, false
+		return false, false, nil
 	}
 
-	return operations.CheckFn(fcrypt, fsrc, checkIdentical, oneway)
+	return operations.CheckFn(&operations.CheckOpt{
+		Fdst:   fcrypt,
+		Fsrc:   fsrc,
+		Check:  checkIdentical,
+		OneWay: oneway,
+		Report: report,
+	})
 }
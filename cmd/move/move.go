@@ -15,6 +15,7 @@ var (
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&deleteEmptySrcDirs, "delete-empty-src-dirs", "", deleteEmptySrcDirs, "Delete empty source dirs after move")
+	cmd.AddReportFlags(commandDefintion.Flags())
 }
 
 var commandDefintion = &cobra.Command{
@@ -39,13 +40,15 @@ If you want to delete empty source directories after move, use the --delete-empt
 
 **Important**: Since this can cause data loss, test first with the
 --dry-run flag.
-`,
+` + cmd.ReportHelp,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
 		cmd.Run(true, true, command, func() error {
 			if srcFileName == "" {
-				return sync.MoveDir(fdst, fsrc, deleteEmptySrcDirs)
+				return cmd.WithReport(func(report *operations.Report) error {
+					return sync.MoveDirWithReport(fdst, fsrc, deleteEmptySrcDirs, report)
+				})
 			}
 			return operations.MoveFile(fdst, fsrc, srcFileName, srcFileName)
 		})
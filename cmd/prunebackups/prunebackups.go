@@ -0,0 +1,76 @@
+package prunebackups
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	opt operations.PruneOpt
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	flags := commandDefintion.Flags()
+	flags.IntVarP(&opt.KeepLast, "keep-last", "", opt.KeepLast, "Keep the most recent N versions of each file")
+	flags.IntVarP(&opt.KeepDaily, "keep-daily", "", opt.KeepDaily, "Keep the most recent version of each file for each of the last N days with versions")
+	flags.IntVarP(&opt.KeepWeekly, "keep-weekly", "", opt.KeepWeekly, "Keep the most recent version of each file for each of the last N weeks with versions")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "prunebackups [path]",
+	Short: `List and prune the versions of files in --backup-dir.`,
+	Long: `
+This lists the versions of the files stored in the ` + "`--backup-dir`" + ` by
+` + "`sync`, `copy` and `move`" + ` when ` + "`--suffix`" + ` contains a time format, and
+deletes the ones which aren't kept by the ` + "`--keep-*`" + ` rules.
+
+Use the same ` + "`--backup-dir`, `--suffix` and `--suffix-keep-extension`" + `
+flags as were used to make the backups so the versions can be found.
+If path is supplied then only the versions in that directory of the
+backup dir are considered.  Filters can be used to choose which files
+to consider too.
+
+For example if you back up like this every day
+
+    rclone sync /path/to/local remote:current --backup-dir remote:old --suffix "-{2006-01-02}" --suffix-keep-extension
+
+then you can keep the last 7 daily versions and 4 weekly versions of
+each file with
+
+    rclone prunebackups --backup-dir remote:old --suffix "-{2006-01-02}" --suffix-keep-extension --keep-daily 7 --keep-weekly 4
+
+A version is kept if any of the rules keep it:
+
+- ` + "`--keep-last N`" + ` keeps the most recent N versions
+- ` + "`--keep-daily N`" + ` keeps the most recent version for each of the last N days which have versions
+- ` + "`--keep-weekly N`" + ` keeps the most recent version for each of the last N weeks which have versions
+
+Each version is listed on standard output with "keep" or "delete", its
+time and its path.  If no ` + "`--keep-*`" + ` flags are given the versions are
+listed but nothing is deleted.  Use ` + "`--dry-run`" + ` to see what would be
+deleted.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(0, 1, command, args)
+		if fs.Config.BackupDir == "" {
+			return errors.New("need --backup-dir to find the versions in")
+		}
+		dir := ""
+		if len(args) > 0 {
+			dir = strings.Trim(args[0], "/")
+		}
+		fbackup := cmd.NewFsDir([]string{fs.Config.BackupDir})
+		cmd.Run(false, false, command, func() error {
+			return operations.PruneBackups(fbackup, dir, opt, os.Stdout)
+		})
+		return nil
+	},
+}
@@ -19,14 +19,18 @@ import (
 )
 
 var (
-	noOutput = false
-	url      = "http://localhost:5572/"
+	noOutput  = false
+	url       = "http://localhost:5572/"
+	options   []string
+	arguments []string
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&noOutput, "no-output", "", noOutput, "If set don't output the JSON result.")
 	commandDefintion.Flags().StringVarP(&url, "url", "", url, "URL to connect to rclone remote control.")
+	commandDefintion.Flags().StringArrayVarP(&options, "opt", "o", options, "Option in the form name=value or name placed in the \"opt\" map.")
+	commandDefintion.Flags().StringArrayVarP(&arguments, "arg", "a", arguments, "Argument placed in the \"arg\" list.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -38,6 +42,11 @@ that specified in the --rc-addr command.
 
 Arguments should be passed in as parameter=value.
 
+Use --opt/-o name=value to set an entry in the "opt" map parameter
+and --arg/-a value to add an entry to the "arg" list parameter, eg
+
+    rclone rc backend/command command=restore fs=s3:bucket/path/to/file -o priority=Bulk
+
 The result will be returned as a JSON object by default.
 
 Use "rclone rc" to see a list of all possible commands.`,
@@ -123,6 +132,12 @@ func run(args []string) (err error) {
 		key, value := param[:equals], param[equals+1:]
 		in[key] = value
 	}
+	if len(options) > 0 {
+		in["opt"] = rc.ParseOptions(options)
+	}
+	if len(arguments) > 0 {
+		in["arg"] = arguments
+	}
 
 	// Do the call
 	out, callErr := doCall(path, in)
@@ -0,0 +1,69 @@
+package rcd
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/ncw/rclone/fs/rc/rcflags"
+	"github.com/ncw/rclone/fs/rc/schedule"
+	"github.com/ncw/rclone/lib/atexit"
+	"github.com/spf13/cobra"
+)
+
+// Globals
+var (
+	scheduleFile = ""
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().StringVarP(&scheduleFile, "schedule-file", "", scheduleFile, "File to save the scheduled jobs in. (default \"schedule.json\" next to the config file)")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "rcd",
+	Short: `Run rclone listening to remote control commands only.`,
+	Long: `
+This runs rclone so that it only listens to remote control commands.
+
+This is useful if you are controlling rclone via the rc API, eg from a
+web GUI.  The remote control server is configured with the --rc-*
+flags, eg --rc-addr, but the --rc flag itself isn't needed.
+
+rclone rcd also runs the scheduler which runs remote control calls on
+a schedule given by a cron expression.  Use the schedule/add,
+schedule/list and schedule/delete calls to manage the scheduled jobs.
+These are saved to the file given by --schedule-file so they are kept
+when rclone rcd is restarted.  By default this is "schedule.json" in
+the same directory as the config file.  The jobs in this file are run
+with all the rights rclone has so, like the config file, it should
+only be writable by the user running rclone.
+
+See the [rc documentation](/rc/) for more info on the rc flags and
+the scheduler.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(0, 0, command, args)
+		if rcflags.Opt.Enabled {
+			log.Fatalf("Don't supply --rc flag when using rcd")
+		}
+		path := scheduleFile
+		if path == "" {
+			path = filepath.Join(filepath.Dir(config.ConfigPath), "schedule.json")
+		}
+		s, err := schedule.Start(path)
+		if err != nil {
+			log.Fatalf("Failed to start scheduler: %v", err)
+		}
+		// Stop the scheduler when rclone exits, including on a signal
+		atexit.Register(s.Stop)
+		err = rc.Serve(&rcflags.Opt)
+		if err != nil {
+			s.Stop()
+			log.Fatalf("Failed to start remote control: %v", err)
+		}
+	},
+}
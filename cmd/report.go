@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Report flags
+var (
+	reportCombined     string
+	reportMissingOnSrc string
+	reportMissingOnDst string
+	reportMatch        string
+	reportDiffer       string
+	reportError        string
+)
+
+// AddReportFlags adds the flags which control the report files
+// written by check, sync, copy and move to flagSet
+func AddReportFlags(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(&reportCombined, "combined", "", reportCombined, "Make a combined report of changes to this file")
+	flagSet.StringVarP(&reportMissingOnSrc, "missing-on-src", "", reportMissingOnSrc, "Report all files missing from the source to this file")
+	flagSet.StringVarP(&reportMissingOnDst, "missing-on-dst", "", reportMissingOnDst, "Report all files missing from the destination to this file")
+	flagSet.StringVarP(&reportMatch, "match", "", reportMatch, "Report all matching files to this file")
+	flagSet.StringVarP(&reportDiffer, "differ", "", reportDiffer, "Report all non-matching files to this file")
+	flagSet.StringVarP(&reportError, "error", "", reportError, "Report all files with errors (hashing or reading) to this file")
+}
+
+// ReportHelp is the help text for the report flags which can be
+// added to the Long help of the commands which use them
+const ReportHelp = `
+The --combined, --missing-on-src, --missing-on-dst, --match, --differ
+and --error flags write paths, one per line, to the file named.  Use
+"-" to write to standard output.  The files are truncated at the start
+of each attempt.
+
+- ` + "`--missing-on-src`" + ` - paths which only exist in the destination
+- ` + "`--missing-on-dst`" + ` - paths which only exist in the source
+- ` + "`--match`" + ` - paths which are identical in source and destination
+- ` + "`--differ`" + ` - paths which exist in both but differ
+- ` + "`--error`" + ` - paths which had an error while being processed
+
+The --combined file lists every path with a status character and a
+space in front of it:
+
+- ` + "`= path`" + ` means path is identical in source and destination
+- ` + "`- path`" + ` means path is missing on the destination (only in the source)
+- ` + "`+ path`" + ` means path is missing on the source (only in the destination)
+- ` + "`* path`" + ` means path exists in both but differs
+- ` + "`! path`" + ` means there was an error processing path
+
+For sync, copy and move the "-" and "*" paths are the ones which
+were transferred and the "+" paths are the ones sync deleted.  A path
+may appear with "!" as well as with one of the other statuses if, for
+example, it was found to differ but the transfer then failed.
+`
+
+// nopWriteCloser wraps an io.Writer with a Close method which does
+// nothing - used for stdout
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// WithReport opens the files named by the report flags, calls fn
+// with a Report writing to them then closes them again.
+//
+// The report passed to fn is nil if no report flags were set.
+func WithReport(fn func(report *operations.Report) error) (err error) {
+	var (
+		report  operations.Report
+		closers []io.Closer
+		opened  = map[string]io.Writer{}
+		used    = false
+	)
+	defer func() {
+		for _, c := range closers {
+			closeErr := c.Close()
+			if closeErr != nil && err == nil {
+				err = errors.Wrap(closeErr, "failed to close report file")
+			}
+		}
+	}()
+	for _, item := range []struct {
+		name string
+		w    *io.Writer
+	}{
+		{reportCombined, &report.Combined},
+		{reportMissingOnSrc, &report.MissingOnSrc},
+		{reportMissingOnDst, &report.MissingOnDst},
+		{reportMatch, &report.Match},
+		{reportDiffer, &report.Differ},
+		{reportError, &report.Error},
+	} {
+		if item.name == "" {
+			continue
+		}
+		used = true
+		// Share the writer if the same file is named twice
+		if w, ok := opened[item.name]; ok {
+			*item.w = w
+			continue
+		}
+		var out io.WriteCloser
+		if item.name == "-" {
+			out = nopWriteCloser{os.Stdout}
+		} else {
+			out, err = os.Create(item.name)
+			if err != nil {
+				return errors.Wrap(err, "failed to open report file")
+			}
+			fs.Debugf(nil, "Writing report to %q", item.name)
+		}
+		closers = append(closers, out)
+		opened[item.name] = out
+		*item.w = out
+	}
+	if !used {
+		return fn(nil)
+	}
+	return fn(&report)
+}
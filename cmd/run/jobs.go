@@ -0,0 +1,536 @@
+package run
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/configflags"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/filter/filterflags"
+	"github.com/ncw/rclone/fs/operations"
+	fssync "github.com/ncw/rclone/fs/sync"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Operations which a job can do
+const (
+	opSync  = "sync"
+	opCopy  = "copy"
+	opMove  = "move"
+	opCheck = "check"
+)
+
+// Filters are the filter rules for a job file or a job
+//
+// These correspond to the filter flags, eg --include
+type Filters struct {
+	DeleteExcluded   bool     `yaml:"delete_excluded"`
+	Filter           []string `yaml:"filter"`
+	FilterFrom       []string `yaml:"filter_from"`
+	Exclude          []string `yaml:"exclude"`
+	ExcludeFrom      []string `yaml:"exclude_from"`
+	ExcludeIfPresent string   `yaml:"exclude_if_present"`
+	Include          []string `yaml:"include"`
+	IncludeFrom      []string `yaml:"include_from"`
+	FilesFrom        []string `yaml:"files_from"`
+	MinAge           string   `yaml:"min_age"`
+	MaxAge           string   `yaml:"max_age"`
+	MinSize          string   `yaml:"min_size"`
+	MaxSize          string   `yaml:"max_size"`
+}
+
+// IsEmpty returns true if no filters are set
+func (fl *Filters) IsEmpty() bool {
+	return !fl.DeleteExcluded && len(fl.Filter) == 0 && len(fl.FilterFrom) == 0 &&
+		len(fl.Exclude) == 0 && len(fl.ExcludeFrom) == 0 && fl.ExcludeIfPresent == "" &&
+		len(fl.Include) == 0 && len(fl.IncludeFrom) == 0 && len(fl.FilesFrom) == 0 &&
+		fl.MinAge == "" && fl.MaxAge == "" && fl.MinSize == "" && fl.MaxSize == ""
+}
+
+// addTo adds the filters to opt, appending any rules to the ones
+// already there and overriding any other values which are set
+func (fl *Filters) addTo(opt *filter.Opt) error {
+	if fl.DeleteExcluded {
+		opt.DeleteExcluded = true
+	}
+	// copy the slices before appending so as not to modify the originals
+	appendRules := func(rules []string, more []string) []string {
+		return append(append([]string(nil), rules...), more...)
+	}
+	opt.FilterRule = appendRules(opt.FilterRule, fl.Filter)
+	opt.FilterFrom = appendRules(opt.FilterFrom, fl.FilterFrom)
+	opt.ExcludeRule = appendRules(opt.ExcludeRule, fl.Exclude)
+	opt.ExcludeFrom = appendRules(opt.ExcludeFrom, fl.ExcludeFrom)
+	opt.IncludeRule = appendRules(opt.IncludeRule, fl.Include)
+	opt.IncludeFrom = appendRules(opt.IncludeFrom, fl.IncludeFrom)
+	opt.FilesFrom = appendRules(opt.FilesFrom, fl.FilesFrom)
+	if fl.ExcludeIfPresent != "" {
+		opt.ExcludeFile = fl.ExcludeIfPresent
+	}
+	for _, value := range []struct {
+		name  string
+		value string
+		flag  pflag.Value
+	}{
+		{"min_age", fl.MinAge, &opt.MinAge},
+		{"max_age", fl.MaxAge, &opt.MaxAge},
+		{"min_size", fl.MinSize, &opt.MinSize},
+		{"max_size", fl.MaxSize, &opt.MaxSize},
+	} {
+		if value.value == "" {
+			continue
+		}
+		err := value.flag.Set(value.value)
+		if err != nil {
+			return errors.Wrapf(err, "bad %s %q", value.name, value.value)
+		}
+	}
+	return nil
+}
+
+// Job is a single named job in a job file
+type Job struct {
+	Name               string            `yaml:"name"`
+	Operation          string            `yaml:"operation"`
+	Source             string            `yaml:"source"`
+	Destination        string            `yaml:"destination"`
+	DeleteEmptySrcDirs bool              `yaml:"delete_empty_src_dirs"`
+	OneWay             bool              `yaml:"one_way"`
+	Filters            Filters           `yaml:"filters"`
+	Options            map[string]string `yaml:"options"`
+	Pre                []string          `yaml:"pre"`
+	Post               []string          `yaml:"post"`
+}
+
+// Jobs is the contents of a job file
+type Jobs struct {
+	Parallel bool              `yaml:"parallel"`
+	Filters  Filters           `yaml:"filters"`
+	Options  map[string]string `yaml:"options"`
+	Jobs     []*Job            `yaml:"jobs"`
+}
+
+// Result is the outcome of running a Job
+type Result struct {
+	Name      string
+	Operation string
+	Err       error
+	Duration  time.Duration
+	HaveStats bool  // set if the stats below are for this job only
+	Bytes     int64 // bytes transferred
+	Transfers int64 // files transferred
+	Errors    int64 // errors
+}
+
+// Parse the job file in data
+func Parse(data []byte) (*Jobs, error) {
+	jobs := new(Jobs)
+	err := yaml.UnmarshalStrict(data, jobs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse job file")
+	}
+	err = jobs.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Load reads and parses the job file at path
+func Load(path string) (*Jobs, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read job file")
+	}
+	return Parse(data)
+}
+
+// Validate checks the jobs are complete and consistent
+func (jobs *Jobs) Validate() error {
+	if len(jobs.Jobs) == 0 {
+		return errors.New("no jobs found")
+	}
+	names := map[string]bool{}
+	for i, job := range jobs.Jobs {
+		if job == nil || job.Name == "" {
+			return errors.Errorf("job %d: no name", i+1)
+		}
+		if names[job.Name] {
+			return errors.Errorf("job %q: duplicate name", job.Name)
+		}
+		names[job.Name] = true
+		switch job.Operation {
+		case opSync, opCopy, opMove, opCheck:
+		case "":
+			return errors.Errorf("job %q: no operation", job.Name)
+		default:
+			return errors.Errorf("job %q: unknown operation %q - must be %s, %s, %s or %s", job.Name, job.Operation, opSync, opCopy, opMove, opCheck)
+		}
+		if job.Source == "" {
+			return errors.Errorf("job %q: no source", job.Name)
+		}
+		if job.Destination == "" {
+			return errors.Errorf("job %q: no destination", job.Name)
+		}
+		// filter.Active and fs.Config are shared by all the jobs so
+		// jobs running at the same time can't have their own
+		if jobs.Parallel && !job.Filters.IsEmpty() {
+			return errors.Errorf("job %q: filters can't be set for each job when running in parallel - set them at the top level", job.Name)
+		}
+		if jobs.Parallel && len(job.Options) != 0 {
+			return errors.Errorf("job %q: options can't be set for each job when running in parallel - set them at the top level", job.Name)
+		}
+	}
+	return nil
+}
+
+// Select returns the jobs with the names given, in the order they
+// are in the job file.  If no names are given then all the jobs are
+// returned.
+func (jobs *Jobs) Select(names []string) ([]*Job, error) {
+	if len(names) == 0 {
+		return jobs.Jobs, nil
+	}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var selected []*Job
+	for _, job := range jobs.Jobs {
+		if wanted[job.Name] {
+			selected = append(selected, job)
+			delete(wanted, job.Name)
+		}
+	}
+	if len(wanted) != 0 {
+		var missing []string
+		for name := range wanted {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return nil, errors.Errorf("jobs not found: %s", strings.Join(missing, ", "))
+	}
+	return selected, nil
+}
+
+// startupOptions are the options which are only read when rclone
+// starts, eg when the HTTP transport is made, so can't be set in a
+// job file
+var startupOptions = map[string]bool{
+	"ask-password":         true,
+	"bind":                 true,
+	"cache-dir":            true,
+	"config":               true,
+	"contimeout":           true,
+	"cpuprofile":           true,
+	"dump":                 true,
+	"dump-bodies":          true,
+	"dump-headers":         true,
+	"log-file":             true,
+	"log-file-max-backups": true,
+	"log-file-max-size":    true,
+	"log-format":           true,
+	"memprofile":           true,
+	"no-check-certificate": true,
+	"no-gzip-encoding":     true,
+	"password-command":     true,
+	"stats":                true,
+	"syslog":               true,
+	"syslog-facility":      true,
+	"timeout":              true,
+	"tpslimit":             true,
+	"tpslimit-burst":       true,
+	"use-json-log":         true,
+	"user-agent":           true,
+}
+
+// setBwLimit sets the bandwidth limit of the running token bucket
+var setBwLimit = accounting.SetBwLimit
+
+// setOptions sets the global flags in options as if they had been
+// given on the command line, checking them in the same way.
+//
+// It returns a function to put them back as they were.
+func setOptions(options map[string]string) (restore func(), err error) {
+	savedConfig := *fs.Config
+	savedFilterOpt := filterflags.Opt
+	var undo []func()
+	restore = func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		*fs.Config = savedConfig
+		fs.ConfigChanged()
+		filterflags.Opt = savedFilterOpt
+	}
+	var names []string
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := options[name]
+		flag := pflag.CommandLine.Lookup(name)
+		if flag == nil {
+			restore()
+			return nil, errors.Errorf("unknown option %q", name)
+		}
+		if startupOptions[name] || name == "rc" || strings.HasPrefix(name, "rc-") {
+			restore()
+			return nil, errors.Errorf("option %q is only read when rclone starts so can't be set in a job file - give it on the command line", name)
+		}
+		switch flag.Value.Type() {
+		case "stringArray", "stringSlice":
+			restore()
+			return nil, errors.Errorf("option %q can't be set in a job file - use filters for filter rules", name)
+		}
+		oldValue, oldChanged := flag.Value.String(), flag.Changed
+		err = flag.Value.Set(value)
+		if err != nil {
+			restore()
+			return nil, errors.Wrapf(err, "bad value %q for option %q", value, name)
+		}
+		flag.Changed = true
+		undo = append(undo, func() {
+			_ = flag.Value.Set(oldValue)
+			flag.Changed = oldChanged
+		})
+	}
+	if len(names) == 0 {
+		return restore, nil
+	}
+	err = configflags.ApplyFlags()
+	if err != nil {
+		restore()
+		return nil, errors.Wrap(err, "bad options")
+	}
+	// The token bucket was made from --bwlimit when rclone started
+	if fs.Config.BwLimit.String() != savedConfig.BwLimit.String() {
+		setBwLimit(fs.Config.BwLimit.LimitAt(time.Now()).Bandwidth)
+		undo = append(undo, func() {
+			setBwLimit(savedConfig.BwLimit.LimitAt(time.Now()).Bandwidth)
+		})
+	}
+	return restore, nil
+}
+
+// newFilter makes a filter from the command line filter flags and
+// the filters passed in
+func newFilter(filters ...*Filters) (*filter.Filter, error) {
+	opt := filterflags.Opt
+	for _, fl := range filters {
+		err := fl.addTo(&opt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filter.NewFilter(&opt)
+}
+
+// runHooks runs the command lines in hooks stopping at the first error
+func runHooks(hooks []string, env []string) error {
+	for _, hook := range hooks {
+		fs.Debugf(nil, "Running hook %q", hook)
+		err := fs.CommandRun(hook, env...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do runs the operation of the job
+func (job *Job) do() error {
+	fsrc, err := fs.NewFs(job.Source)
+	if err != nil {
+		return errors.Wrap(err, "failed to create source")
+	}
+	fdst, err := fs.NewFs(job.Destination)
+	if err != nil {
+		return errors.Wrap(err, "failed to create destination")
+	}
+	switch job.Operation {
+	case opSync:
+		return fssync.Sync(fdst, fsrc)
+	case opCopy:
+		return fssync.CopyDir(fdst, fsrc)
+	case opMove:
+		return fssync.MoveDir(fdst, fsrc, job.DeleteEmptySrcDirs)
+	case opCheck:
+		return operations.Check(&operations.CheckOpt{
+			Fdst:   fdst,
+			Fsrc:   fsrc,
+			OneWay: job.OneWay,
+		})
+	}
+	return errors.Errorf("unknown operation %q", job.Operation)
+}
+
+// run runs the job with its hooks.
+//
+// If parallel isn't set then the job's filters and options are
+// applied and the stats are reset so they are for this job only.
+func (job *Job) run(jobs *Jobs, parallel bool) (result Result) {
+	result = Result{
+		Name:      job.Name,
+		Operation: job.Operation,
+		HaveStats: !parallel,
+	}
+	start := time.Now()
+	if !parallel {
+		accounting.Stats.ResetCounters()
+	}
+	fs.Logf(nil, "Job %q: starting %s of %q to %q", job.Name, job.Operation, job.Source, job.Destination)
+	env := []string{
+		"RCLONE_JOB_NAME=" + job.Name,
+		"RCLONE_JOB_OPERATION=" + job.Operation,
+		"RCLONE_JOB_SOURCE=" + job.Source,
+		"RCLONE_JOB_DESTINATION=" + job.Destination,
+	}
+	err := runHooks(job.Pre, env)
+	if err != nil {
+		err = errors.Wrap(err, "pre hook failed")
+	} else if parallel {
+		err = job.do()
+	} else {
+		err = job.doWithOptions(jobs)
+	}
+	if err == nil && !parallel && accounting.Stats.Errored() {
+		err = accounting.Stats.GetLastError()
+		if err == nil {
+			err = errors.Errorf("%d errors", accounting.Stats.GetErrors())
+		}
+	}
+	jobErr := ""
+	if err != nil {
+		jobErr = err.Error()
+	}
+	postErr := runHooks(job.Post, append(env, "RCLONE_JOB_ERROR="+jobErr))
+	if err == nil && postErr != nil {
+		err = errors.Wrap(postErr, "post hook failed")
+	} else if postErr != nil {
+		fs.Errorf(nil, "Job %q: post hook failed: %v", job.Name, postErr)
+	}
+	result.Err = err
+	result.Duration = time.Since(start)
+	if !parallel {
+		result.Bytes = accounting.Stats.GetBytes()
+		result.Transfers = accounting.Stats.GetTransfers()
+		result.Errors = accounting.Stats.GetErrors()
+	}
+	if err != nil {
+		fs.Errorf(nil, "Job %q: failed: %v", job.Name, err)
+	} else {
+		fs.Logf(nil, "Job %q: finished in %v", job.Name, result.Duration)
+	}
+	return result
+}
+
+// doWithOptions runs the job with its filters and options applied
+func (job *Job) doWithOptions(jobs *Jobs) error {
+	restore, err := setOptions(job.Options)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if !job.Filters.IsEmpty() || len(job.Options) != 0 {
+		f, err := newFilter(&jobs.Filters, &job.Filters)
+		if err != nil {
+			return errors.Wrap(err, "bad filters")
+		}
+		oldActive := filter.Active
+		filter.Active = f
+		defer func() { filter.Active = oldActive }()
+	}
+	return job.do()
+}
+
+// Run runs the jobs passed in, which should come from this job
+// file, returning a Result for each one in the same order.
+//
+// The top level filters and options are applied to all the jobs and
+// they are run one after another, or all at once if Parallel is set.
+func (jobs *Jobs) Run(selected []*Job) (results []Result, err error) {
+	restore, err := setOptions(jobs.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+	f, err := newFilter(&jobs.Filters)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad filters")
+	}
+	oldActive := filter.Active
+	filter.Active = f
+	defer func() { filter.Active = oldActive }()
+
+	results = make([]Result, len(selected))
+	if !jobs.Parallel {
+		for i, job := range selected {
+			results[i] = job.run(jobs, false)
+		}
+		return results, nil
+	}
+	var wg sync.WaitGroup
+	for i, job := range selected {
+		wg.Add(1)
+		go func(i int, job *Job) {
+			defer wg.Done()
+			results[i] = job.run(jobs, true)
+		}(i, job)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Failed returns the number of results which failed
+func Failed(results []Result) (failed int) {
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// WriteSummary writes a table of the results to out
+func WriteSummary(out io.Writer, results []Result) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "Job\tOperation\tStatus\tDuration\tTransferred\tFiles\tErrors")
+	for _, result := range results {
+		status := "OK"
+		if result.Err != nil {
+			status = "FAILED"
+		}
+		bytes, transfers, errs := "-", "-", "-"
+		if result.HaveStats {
+			bytes = fs.SizeSuffix(result.Bytes).Unit("Bytes")
+			transfers = fmt.Sprint(result.Transfers)
+			errs = fmt.Sprint(result.Errors)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%s\t%s\t%s\n", result.Name, result.Operation, status, result.Duration.Truncate(time.Millisecond), bytes, transfers, errs)
+	}
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			_, err = fmt.Fprintf(out, "%s: %v\n", result.Name, result.Err)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
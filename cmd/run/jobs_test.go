@@ -0,0 +1,304 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fstest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		wantErr string
+	}{
+		{"", "no jobs found"},
+		{"potato: true\njobs: []", "field potato not found"},
+		{"jobs:\n- operation: sync", "job 1: no name"},
+		{"jobs:\n- name: a\n  source: x\n  destination: y", `job "a": no operation`},
+		{"jobs:\n- name: a\n  operation: delete\n  source: x\n  destination: y", `job "a": unknown operation "delete"`},
+		{"jobs:\n- name: a\n  operation: sync\n  destination: y", `job "a": no source`},
+		{"jobs:\n- name: a\n  operation: sync\n  source: x", `job "a": no destination`},
+		{"jobs:\n- name: a\n  operation: sync\n  source: x\n  destination: y\n- name: a\n  operation: copy\n  source: x\n  destination: y", `job "a": duplicate name`},
+		{"parallel: true\njobs:\n- name: a\n  operation: sync\n  source: x\n  destination: y\n  filters:\n    exclude: ['*.tmp']", `job "a": filters can't be set`},
+		{"parallel: true\njobs:\n- name: a\n  operation: sync\n  source: x\n  destination: y\n  options:\n    transfers: 2", `job "a": options can't be set`},
+		{"parallel: true\nfilters:\n  exclude: ['*.tmp']\noptions:\n  transfers: 2\njobs:\n- name: a\n  operation: sync\n  source: x\n  destination: y", ""},
+	} {
+		jobs, err := Parse([]byte(test.in))
+		if test.wantErr != "" {
+			require.Error(t, err, test.in)
+			assert.Contains(t, err.Error(), test.wantErr, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+			assert.Equal(t, 1, len(jobs.Jobs))
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	jobs := &Jobs{Jobs: []*Job{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
+	names := func(selected []*Job) (names []string) {
+		for _, job := range selected {
+			names = append(names, job.Name)
+		}
+		return names
+	}
+
+	selected, err := jobs.Select(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, names(selected))
+
+	selected, err = jobs.Select([]string{"c", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, names(selected))
+
+	_, err = jobs.Select([]string{"c", "z", "y"})
+	require.Error(t, err)
+	assert.Equal(t, "jobs not found: y, z", err.Error())
+}
+
+func TestSetOptions(t *testing.T) {
+	fstest.Initialise()
+	oldTransfers := fs.Config.Transfers
+	oldDryRun := fs.Config.DryRun
+
+	restore, err := setOptions(map[string]string{
+		"transfers": "17",
+		"dry-run":   "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 17, fs.Config.Transfers)
+	assert.Equal(t, true, fs.Config.DryRun)
+	restore()
+	assert.Equal(t, oldTransfers, fs.Config.Transfers)
+	assert.Equal(t, oldDryRun, fs.Config.DryRun)
+
+	// options which need converting into the config
+	oldDeleteMode := fs.Config.DeleteMode
+	oldLogLevel := fs.Config.LogLevel
+	restore, err = setOptions(map[string]string{
+		"delete-after": "true",
+		"quiet":        "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fs.DeleteModeAfter, fs.Config.DeleteMode)
+	assert.Equal(t, fs.LogLevelError, fs.Config.LogLevel)
+	restore()
+	assert.Equal(t, oldDeleteMode, fs.Config.DeleteMode)
+	assert.Equal(t, oldLogLevel, fs.Config.LogLevel)
+
+	for _, options := range []map[string]string{
+		{"potato": "1"},
+		{"transfers": "potato"},
+		{"exclude": "*.tmp"},
+		{"dry-run": "true", "transfers": "potato"},
+		{"tpslimit": "10"},
+		{"dump-headers": "true"},
+		{"rc-addr": "localhost:1234"},
+		{"delete-before": "true", "delete-after": "true"},
+		{"compare-dest": "/tmp/a", "copy-dest": "/tmp/b"},
+		{"suffix": ".bak"},
+		{"ignore-size": "true", "size-only": "true"},
+	} {
+		_, err = setOptions(options)
+		assert.Error(t, err, fmt.Sprint(options))
+		assert.Equal(t, oldTransfers, fs.Config.Transfers)
+		assert.Equal(t, oldDryRun, fs.Config.DryRun)
+		assert.Equal(t, oldDeleteMode, fs.Config.DeleteMode)
+	}
+
+	// the flags of an invalid combination are put back
+	restore, err = setOptions(map[string]string{"delete-before": "true"})
+	require.NoError(t, err)
+	assert.Equal(t, fs.DeleteModeBefore, fs.Config.DeleteMode)
+	restore()
+}
+
+func TestSetOptionsBwLimit(t *testing.T) {
+	fstest.Initialise()
+	oldSetBwLimit := setBwLimit
+	defer func() { setBwLimit = oldSetBwLimit }()
+	var bandwidths []fs.SizeSuffix
+	setBwLimit = func(bandwidth fs.SizeSuffix) {
+		bandwidths = append(bandwidths, bandwidth)
+	}
+	oldBwLimit := fs.Config.BwLimit.String()
+
+	// the running token bucket is changed and put back
+	restore, err := setOptions(map[string]string{"bwlimit": "1M"})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(fs.Config.BwLimit))
+	assert.Equal(t, fs.SizeSuffix(1024*1024), fs.Config.BwLimit[0].Bandwidth)
+	restore()
+	assert.Equal(t, oldBwLimit, fs.Config.BwLimit.String())
+	assert.Equal(t, []fs.SizeSuffix{1024 * 1024, -1}, bandwidths)
+
+	// but not if bwlimit isn't changed
+	bandwidths = nil
+	restore, err = setOptions(map[string]string{"transfers": "3"})
+	require.NoError(t, err)
+	restore()
+	assert.Nil(t, bandwidths)
+}
+
+// writeFiles makes the files passed in under dir
+func writeFiles(t *testing.T, dir string, files ...string) {
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0777))
+		require.NoError(t, ioutil.WriteFile(path, []byte(file), 0666))
+	}
+}
+
+// listFiles returns the sorted names of the files under dir
+func listFiles(t *testing.T, dir string) (files []string) {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if !os.IsNotExist(err) {
+		require.NoError(t, err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs sh")
+	}
+	fstest.Initialise()
+	dir, err := ioutil.TempDir("", "rclone-run")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	src := filepath.Join(dir, "src")
+	writeFiles(t, src, "one.txt", "two.tmp", "three.jpg", "sub/four.txt")
+	hookLog := filepath.Join(dir, "hooks.log")
+	hook := fmt.Sprintf(`sh -c "echo $RCLONE_JOB_NAME $RCLONE_JOB_OPERATION [$RCLONE_JOB_ERROR] >> %s"`, hookLog)
+	oldActive := filter.Active
+
+	jobs, err := Parse([]byte(fmt.Sprintf(`
+filters:
+  filter:
+    - "- *.tmp"
+options:
+  transfers: 1
+jobs:
+  - name: copy-txt
+    operation: copy
+    source: %[1]s/src
+    destination: %[1]s/txt
+    filters:
+      include:
+        - "*.txt"
+    pre:
+      - %[2]s
+    post:
+      - %[2]s
+  - name: sync-all
+    operation: sync
+    source: %[1]s/src
+    destination: %[1]s/all
+  - name: check
+    operation: check
+    source: %[1]s/src
+    destination: %[1]s/txt
+    post:
+      - %[2]s
+  - name: bad-hook
+    operation: copy
+    source: %[1]s/src
+    destination: %[1]s/bad
+    pre:
+      - false
+  - name: move
+    operation: move
+    source: %[1]s/all
+    destination: %[1]s/moved
+    delete_empty_src_dirs: true
+`, dir, hook)))
+	require.NoError(t, err)
+
+	results, err := jobs.Run(jobs.Jobs)
+	require.NoError(t, err)
+	assert.True(t, oldActive == filter.Active, "filter should be restored")
+	require.Equal(t, 5, len(results))
+
+	assert.Equal(t, []string{"one.txt", "sub/four.txt"}, listFiles(t, filepath.Join(dir, "txt")))
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, int64(2), results[0].Transfers)
+	assert.True(t, results[0].HaveStats)
+
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, int64(3), results[1].Transfers)
+
+	assert.Error(t, results[2].Err, "three.jpg should be missing")
+
+	assert.Error(t, results[3].Err)
+	assert.Contains(t, results[3].Err.Error(), "pre hook failed")
+	assert.Nil(t, listFiles(t, filepath.Join(dir, "bad")))
+
+	assert.NoError(t, results[4].Err)
+	assert.Equal(t, []string{"one.txt", "sub/four.txt", "three.jpg"}, listFiles(t, filepath.Join(dir, "moved")))
+	assert.Nil(t, listFiles(t, filepath.Join(dir, "all")))
+	assert.Equal(t, 2, Failed(results))
+
+	log, err := ioutil.ReadFile(hookLog)
+	require.NoError(t, err)
+	assert.Equal(t, "copy-txt copy []\ncopy-txt copy []\ncheck check [1 differences found]\n", string(log))
+
+	// Now run some in parallel
+	jobs.Parallel = true
+	jobs.Jobs[0].Filters = Filters{}
+	jobs.Jobs[0].Pre = nil
+	jobs.Jobs[0].Post = nil
+	jobs.Jobs[1].Destination = filepath.Join(dir, "all2")
+	results, err = jobs.Run(jobs.Jobs[:2])
+	require.NoError(t, err)
+	require.Equal(t, 2, len(results))
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+		assert.False(t, result.HaveStats)
+	}
+	assert.Equal(t, []string{"one.txt", "sub/four.txt", "three.jpg"}, listFiles(t, filepath.Join(dir, "txt")))
+	assert.Equal(t, []string{"one.txt", "sub/four.txt", "three.jpg"}, listFiles(t, filepath.Join(dir, "all2")))
+}
+
+func TestWriteSummary(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSummary(&buf, []Result{
+		{Name: "one", Operation: "sync", HaveStats: true, Bytes: 2048, Transfers: 3},
+		{Name: "two", Operation: "check", HaveStats: true, Errors: 1, Err: errors.New("potato")},
+		{Name: "three", Operation: "copy"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `Job    Operation  Status  Duration  Transferred  Files  Errors
+one    sync       OK      0s        2 kBytes     3      0
+two    check      FAILED  0s        0 Bytes      0      1
+three  copy       OK      0s        -            -      -
+two: potato
+`, buf.String())
+}
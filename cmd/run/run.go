@@ -0,0 +1,122 @@
+package run
+
+import (
+	"os"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "run jobs.yaml [job]*",
+	Short: `Run the sync, copy, move and check jobs in a job file.`,
+	Long: `
+Run the jobs defined in a YAML job file.  This is useful for scheduled
+backups where several syncs need to be run, eg from cron, as all the
+jobs and their settings can be kept in one place.
+
+If any job names are given after the job file then only those jobs are
+run, otherwise all of them are.
+
+Each job has a name, an operation which is one of sync, copy, move or
+check, a source and a destination.  It can also have filter rules,
+options, and commands to run before and after it.  Here is an example
+
+    # Run the jobs one after another (the default) or all at once
+    parallel: false
+    # Filter rules for all the jobs
+    filters:
+      exclude:
+        - "*.tmp"
+    # Options for all the jobs
+    options:
+      transfers: 8
+    jobs:
+      - name: photos
+        operation: sync
+        source: /home/user/photos
+        destination: remote:backup/photos
+        filters:
+          min_size: 10k
+          exclude:
+            - "/thumbnails/**"
+        options:
+          bwlimit: 1M
+          backup-dir: remote:backup/old-photos
+        pre:
+          - /usr/local/bin/mount-photos
+        post:
+          - /usr/local/bin/notify "photos done"
+      - name: check-photos
+        operation: check
+        source: /home/user/photos
+        destination: remote:backup/photos
+        one_way: true
+
+The filters are the same as the filter flags with "-" replaced by "_",
+eg exclude_from for --exclude-from.  The rules are added after any
+filter flags on the command line, and the job's rules are added after
+the ones for all the jobs.
+
+The options are global flags without the "--", eg checkers for
+--checkers, set as if they were given on the command line and checked
+in the same way, so for example suffix can only be used with
+backup-dir.  Options which can be repeated, like the filter flags,
+can't be used, and nor can options which are only read when rclone
+starts, such as timeout, tpslimit, dump or log-file.
+
+The pre commands are run in order before the job and if any of them
+fail the job fails without being run.  The post commands are run after
+the job even if it failed.  Each command is run directly, not through a
+shell, and arguments with spaces in can be quoted with "".  These
+environment variables are set for them
+
+  * RCLONE_JOB_NAME - the name of the job
+  * RCLONE_JOB_OPERATION - its operation
+  * RCLONE_JOB_SOURCE - its source
+  * RCLONE_JOB_DESTINATION - its destination
+  * RCLONE_JOB_ERROR - for post commands, the error if the job failed
+
+A move job can set delete_empty_src_dirs and a check job can set
+one_way like the flags for those commands.
+
+When all the jobs have finished a summary is printed showing whether
+each job succeeded with the amount of data and files transferred and
+the number of errors.  rclone run exits with a non zero status if any
+job failed.
+
+If parallel is set then all the jobs are run at the same time.  As
+the options and filters are global they can only be set for all the
+jobs in this case, and the stats can't be shown for each job.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1e6, command, args)
+		cmd.Run(false, false, command, func() error {
+			jobs, err := Load(args[0])
+			if err != nil {
+				return err
+			}
+			selected, err := jobs.Select(args[1:])
+			if err != nil {
+				return err
+			}
+			results, err := jobs.Run(selected)
+			if err != nil {
+				return err
+			}
+			err = WriteSummary(os.Stdout, results)
+			if err != nil {
+				return err
+			}
+			if failed := Failed(results); failed > 0 {
+				return errors.Errorf("%d of %d jobs failed", failed, len(results))
+			}
+			return nil
+		})
+	},
+}
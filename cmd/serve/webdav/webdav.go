@@ -4,8 +4,12 @@ package webdav
 // override for getcontenttype property?
 
 import (
+	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/cmd/serve/httplib"
@@ -35,6 +39,10 @@ remote over HTTP via the webdav protocol. This can be viewed with a
 webdav client or you can make a remote of type webdav to read and
 write it.
 
+If a file is uploaded with an X-OC-Mtime header, as sent by the
+webdav remote with vendor set to "rclone" (and by Owncloud clients),
+then its modification time will be set from that header.
+
 NB at the moment each directory listing reads the start of each file
 which is undesirable: see https://github.com/golang/go/issues/22577
 ` + httplib.Help + vfs.Help,
@@ -62,9 +70,10 @@ which is undesirable: see https://github.com/golang/go/issues/22577
 // might apply". In particular, whether or not renaming a file or directory
 // overwriting another existing file or directory is an error is OS-dependent.
 type WebDAV struct {
-	f   fs.Fs
-	vfs *vfs.VFS
-	srv *httplib.Server
+	f       fs.Fs
+	vfs     *vfs.VFS
+	srv     *httplib.Server
+	handler http.Handler
 }
 
 // check interface
@@ -77,16 +86,73 @@ func newWebDAV(f fs.Fs, opt *httplib.Options) *WebDAV {
 		vfs: vfs.New(f, &vfsflags.Opt),
 	}
 
-	handler := &webdav.Handler{
+	w.handler = &webdav.Handler{
 		FileSystem: w,
 		LockSystem: webdav.NewMemLS(),
 		Logger:     w.logRequest, // FIXME
 	}
 
-	w.srv = httplib.NewServer(handler, opt)
+	w.srv = httplib.NewServer(w, opt)
 	return w
 }
 
+// ServeHTTP serves a webdav request, setting the modification time
+// of uploaded or copied files if an X-OC-Mtime header was supplied
+func (w *WebDAV) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if mtime := r.Header.Get("X-OC-Mtime"); mtime != "" {
+		switch r.Method {
+		case "PUT":
+			rw = &ocMtimeWriter{ResponseWriter: rw, w: w, name: r.URL.Path, mtime: mtime}
+		case "COPY", "MOVE":
+			if u, err := url.Parse(r.Header.Get("Destination")); err == nil {
+				rw = &ocMtimeWriter{ResponseWriter: rw, w: w, name: u.Path, mtime: mtime}
+			}
+		}
+	}
+	w.handler.ServeHTTP(rw, r)
+}
+
+// ocMtimeWriter sets the modification time of the file written from
+// the X-OC-Mtime header before the response is sent, so the client
+// sees the new time as soon as the request completes.
+type ocMtimeWriter struct {
+	http.ResponseWriter
+	w     *WebDAV
+	name  string // path of the file written
+	mtime string // value of the X-OC-Mtime header
+}
+
+// WriteHeader sets the modification time if the request succeeded
+// then writes the header
+func (ow *ocMtimeWriter) WriteHeader(code int) {
+	if code >= 200 && code < 300 {
+		err := ow.w.setOCMtime(ow.name, ow.mtime)
+		if err != nil {
+			fs.Errorf(ow.name, "Failed to set modification time from X-OC-Mtime: %v", err)
+		} else {
+			ow.Header().Set("X-OC-Mtime", "accepted")
+		}
+	}
+	ow.ResponseWriter.WriteHeader(code)
+}
+
+// setOCMtime sets the modification time of name from the X-OC-Mtime
+// header value which is in seconds since the epoch with an optional
+// fractional part
+func (w *WebDAV) setOCMtime(name string, value string) error {
+	secs, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	whole, frac := math.Modf(secs)
+	modTime := time.Unix(int64(whole), int64(frac*1e9))
+	node, err := w.vfs.Stat(name)
+	if err != nil {
+		return err
+	}
+	return node.SetModTime(modTime)
+}
+
 // serve runs the http server - doesn't return
 func (w *WebDAV) serve() {
 	err := w.srv.Serve()
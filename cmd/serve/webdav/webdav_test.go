@@ -47,24 +47,28 @@ func TestWebDav(t *testing.T) {
 	err = os.Chdir("../../../backend/webdav")
 	assert.NoError(t, err, "failed to cd to webdav remote")
 
-	// Run the webdav tests with an on the fly remote
-	args := []string{"test"}
-	if testing.Verbose() {
-		args = append(args, "-v")
+	// Run the webdav tests with an on the fly remote for each vendor
+	for _, vendor := range []string{"other", "rclone"} {
+		t.Run(vendor, func(t *testing.T) {
+			args := []string{"test"}
+			if testing.Verbose() {
+				args = append(args, "-v")
+			}
+			if *fstest.Verbose {
+				args = append(args, "-verbose")
+			}
+			args = append(args, "-remote", "webdavtest:")
+			cmd := exec.Command("go", args...)
+			cmd.Env = append(os.Environ(),
+				"RCLONE_CONFIG_WEBDAVTEST_TYPE=webdav",
+				"RCLONE_CONFIG_WEBDAVTEST_URL="+testURL,
+				"RCLONE_CONFIG_WEBDAVTEST_VENDOR="+vendor,
+			)
+			out, err := cmd.CombinedOutput()
+			if len(out) != 0 {
+				t.Logf("\n----------\n%s----------\n", string(out))
+			}
+			assert.NoError(t, err, "Running webdav integration tests")
+		})
 	}
-	if *fstest.Verbose {
-		args = append(args, "-verbose")
-	}
-	args = append(args, "-remote", "webdavtest:")
-	cmd := exec.Command("go", args...)
-	cmd.Env = append(os.Environ(),
-		"RCLONE_CONFIG_WEBDAVTEST_TYPE=webdav",
-		"RCLONE_CONFIG_WEBDAVTEST_URL="+testURL,
-		"RCLONE_CONFIG_WEBDAVTEST_VENDOR=other",
-	)
-	out, err := cmd.CombinedOutput()
-	if len(out) != 0 {
-		t.Logf("\n----------\n%s----------\n", string(out))
-	}
-	assert.NoError(t, err, "Running webdav integration tests")
 }
@@ -14,9 +14,25 @@ import (
 	"github.com/pkg/errors"
 )
 
-// ErrorMaxTransferLimitReached is returned from Read when the max
-// transfer limit is reached.
-var ErrorMaxTransferLimitReached = fserrors.FatalError(errors.New("Max transfer limit reached as set by --max-transfer"))
+var (
+	// ErrorMaxTransferLimitReached is returned from Read when the max
+	// transfer limit is reached with --cutoff-mode hard.
+	ErrorMaxTransferLimitReached = fserrors.FatalError(errors.New("Max transfer limit reached as set by --max-transfer"))
+
+	// ErrorMaxTransferLimitReachedGraceful is returned before
+	// starting a new transfer when the max transfer limit is
+	// reached with --cutoff-mode soft or cautious.
+	ErrorMaxTransferLimitReachedGraceful = fserrors.NoRetryError(errors.New("Max transfer limit reached as set by --max-transfer"))
+
+	// ErrorMaxDurationReached is returned from Read when the max
+	// duration is reached with --cutoff-mode hard.
+	ErrorMaxDurationReached = fserrors.FatalError(errors.New("Max transfer duration reached as set by --max-duration"))
+
+	// ErrorMaxDurationReachedGraceful is returned before starting
+	// a new transfer when the max duration is reached with
+	// --cutoff-mode soft or cautious.
+	ErrorMaxDurationReachedGraceful = fserrors.NoRetryError(errors.New("Max transfer duration reached as set by --max-duration"))
+)
 
 // Account limits and accounts for one transfer
 type Account struct {
@@ -41,6 +57,7 @@ type Account struct {
 	closed  bool               // set if the file is closed
 	exit    chan struct{}      // channel that will be closed when transfer is finished
 	withBuf bool               // is using a buffered in
+	remotes []*fs.ConfigInfo   // configs of the remotes which override --bwlimit or --buffer-size
 }
 
 // NewAccountSizeName makes a Account reader for an io.ReadCloser of
@@ -55,7 +72,10 @@ func NewAccountSizeName(in io.ReadCloser, size int64, name string) *Account {
 		exit:   make(chan struct{}),
 		avg:    ewma.NewMovingAverage(),
 		lpTime: time.Now(),
-		max:    int64(fs.Config.MaxTransfer),
+		max:    -1,
+	}
+	if fs.Config.CutoffMode == fs.CutoffModeHard {
+		acc.max = int64(fs.Config.MaxTransfer)
 	}
 	go acc.averageLoop()
 	Stats.inProgress.set(acc.name, acc)
@@ -67,12 +87,50 @@ func NewAccount(in io.ReadCloser, obj fs.Object) *Account {
 	return NewAccountSizeName(in, obj.Size(), obj.Remote())
 }
 
+// WithRemotes makes the transfer use the --bwlimit and --buffer-size
+// of any of the remotes passed in which override them in their config
+// (see fs.GetConfig).
+//
+// If more than one remote overrides --bwlimit then the transfer is
+// limited by all of them.  The global --bwlimit doesn't apply to
+// transfers to or from a remote which overrides it.
+func (acc *Account) WithRemotes(remotes ...fs.Info) *Account {
+	for _, f := range remotes {
+		if f == nil {
+			continue
+		}
+		ci := fs.GetConfigFs(f)
+		if ci == fs.Config {
+			continue
+		}
+		if ci.BwLimit.String() != fs.Config.BwLimit.String() || ci.BufferSize != fs.Config.BufferSize {
+			acc.remotes = append(acc.remotes, ci)
+		}
+	}
+	return acc
+}
+
+// bufferSize returns the size of the buffer to use for the transfer,
+// the smallest of any remotes overriding it, or --buffer-size
+func (acc *Account) bufferSize() fs.SizeSuffix {
+	bufferSize := fs.Config.BufferSize
+	overridden := false
+	for _, ci := range acc.remotes {
+		if ci.BufferSize != fs.Config.BufferSize && (!overridden || ci.BufferSize < bufferSize) {
+			bufferSize = ci.BufferSize
+			overridden = true
+		}
+	}
+	return bufferSize
+}
+
 // WithBuffer - If the file is above a certain size it adds an Async reader
 func (acc *Account) WithBuffer() *Account {
 	acc.withBuf = true
 	var buffers int
-	if acc.size >= int64(fs.Config.BufferSize) || acc.size == -1 {
-		buffers = int(int64(fs.Config.BufferSize) / asyncreader.BufferSize)
+	bufferSize := acc.bufferSize()
+	if acc.size >= int64(bufferSize) || acc.size == -1 {
+		buffers = int(int64(bufferSize) / asyncreader.BufferSize)
 	} else {
 		buffers = int(acc.size / asyncreader.BufferSize)
 	}
@@ -147,21 +205,27 @@ func (acc *Account) averageLoop() {
 	}
 }
 
-// read bytes from the io.Reader passed in and account them
-func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
+// checkRead checks the transfer limit before a read, starting the
+// clock if this is the first one
+func (acc *Account) checkRead() error {
 	acc.statmu.Lock()
+	defer acc.statmu.Unlock()
 	if acc.max >= 0 && Stats.GetBytes() >= acc.max {
-		acc.statmu.Unlock()
-		return 0, ErrorMaxTransferLimitReached
+		return ErrorMaxTransferLimitReached
+	}
+	if fs.Config.CutoffMode == fs.CutoffModeHard && Stats.MaxDurationReached() {
+		return ErrorMaxDurationReached
 	}
 	// Set start time.
 	if acc.start.IsZero() {
 		acc.start = time.Now()
 	}
-	acc.statmu.Unlock()
-
-	n, err = in.Read(p)
+	return nil
+}
 
+// accountRead updates the stats for n bytes read and limits the
+// bandwidth
+func (acc *Account) accountRead(n int) {
 	// Update Stats
 	acc.statmu.Lock()
 	acc.lpBytes += n
@@ -170,8 +234,30 @@ func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 
 	Stats.Bytes(int64(n))
 
-	limitBandwidth(n)
-	return
+	limitBandwidth(n, acc.remotes)
+}
+
+// read bytes from the io.Reader passed in and account them
+func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
+	err = acc.checkRead()
+	if err != nil {
+		return 0, err
+	}
+	n, err = in.Read(p)
+	acc.accountRead(n)
+	return n, err
+}
+
+// AccountRead accounts for n bytes having been read by the caller
+// rather than through Read.  This is used where the data is read
+// in several streams at once.
+func (acc *Account) AccountRead(n int) error {
+	err := acc.checkRead()
+	if err != nil {
+		return err
+	}
+	acc.accountRead(n)
+	return nil
 }
 
 // Read bytes from the object - see io.Reader
@@ -191,6 +277,9 @@ func (acc *Account) Close() error {
 	acc.closed = true
 	close(acc.exit)
 	Stats.inProgress.clear(acc.name)
+	if acc.close == nil {
+		return nil
+	}
 	return acc.close.Close()
 }
 
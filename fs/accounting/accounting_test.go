@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/asyncreader"
@@ -208,3 +209,104 @@ func TestAccountMaxTransfer(t *testing.T) {
 	assert.Equal(t, ErrorMaxTransferLimitReached, err)
 	assert.True(t, fserrors.IsFatalError(err))
 }
+
+func TestAccountMaxTransferSoft(t *testing.T) {
+	old, oldMode := fs.Config.MaxTransfer, fs.Config.CutoffMode
+	fs.Config.MaxTransfer = 15
+	fs.Config.CutoffMode = fs.CutoffModeSoft
+	defer func() {
+		fs.Config.MaxTransfer, fs.Config.CutoffMode = old, oldMode
+	}()
+	Stats.ResetCounters()
+
+	in := ioutil.NopCloser(bytes.NewBuffer(make([]byte, 100)))
+	acc := NewAccountSizeName(in, 1, "test")
+
+	// Reads carry on past the limit in soft mode
+	var b = make([]byte, 10)
+	for i := 0; i < 3; i++ {
+		n, err := acc.Read(b)
+		assert.Equal(t, 10, n)
+		assert.NoError(t, err)
+	}
+}
+
+func TestAccountMaxDuration(t *testing.T) {
+	old := fs.Config.MaxDuration
+	defer func() {
+		fs.Config.MaxDuration = old
+	}()
+
+	fs.Config.MaxDuration = 0
+	assert.False(t, Stats.MaxDurationReached())
+	fs.Config.MaxDuration = time.Hour
+	assert.False(t, Stats.MaxDurationReached())
+	fs.Config.MaxDuration = time.Nanosecond
+	assert.True(t, Stats.MaxDurationReached())
+
+	in := ioutil.NopCloser(bytes.NewBuffer(make([]byte, 100)))
+	acc := NewAccountSizeName(in, 1, "test")
+	var b = make([]byte, 10)
+	n, err := acc.Read(b)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, ErrorMaxDurationReached, err)
+	assert.True(t, fserrors.IsFatalError(err))
+
+	// Resetting the counters restarts the timer
+	fs.Config.MaxDuration = time.Hour
+	s := NewStats()
+	s.transferStart = time.Now().Add(-2 * time.Hour)
+	assert.True(t, s.MaxDurationReached())
+	s.ResetCounters()
+	assert.False(t, s.MaxDurationReached())
+}
+
+func TestGetBytesWithPending(t *testing.T) {
+	Stats.ResetCounters()
+	// other tests may have left transfers in progress
+	base := Stats.GetBytesWithPending()
+
+	in := ioutil.NopCloser(bytes.NewBuffer(make([]byte, 100)))
+	acc := NewAccountSizeName(in, 100, "pending-test")
+	assert.Equal(t, base+100, Stats.GetBytesWithPending())
+
+	var b = make([]byte, 10)
+	_, err := acc.Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, base+100, Stats.GetBytesWithPending())
+	assert.Equal(t, int64(10), Stats.GetBytes())
+
+	require.NoError(t, acc.Close())
+	assert.Equal(t, base+10, Stats.GetBytesWithPending())
+}
+
+func TestAccountRemoteOverrides(t *testing.T) {
+	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+	acc := NewAccountSizeName(in, 1, "test")
+	defer func() { assert.NoError(t, acc.Close()) }()
+	assert.Equal(t, fs.Config.BufferSize, acc.bufferSize())
+
+	// remotes not overriding the buffer size are ignored
+	unlimited := *fs.Config
+	acc.remotes = []*fs.ConfigInfo{&unlimited}
+	assert.Equal(t, fs.Config.BufferSize, acc.bufferSize())
+	assert.Nil(t, getRemoteTokenBucket(&unlimited))
+
+	// the smallest override is used
+	small, smaller := *fs.Config, *fs.Config
+	small.BufferSize = 64 * 1024
+	smaller.BufferSize = 32 * 1024
+	acc.remotes = []*fs.ConfigInfo{&unlimited, &small, &smaller}
+	assert.Equal(t, fs.SizeSuffix(32*1024), acc.bufferSize())
+
+	// token buckets are kept per remote and remade if the limit changes
+	limited := *fs.Config
+	require.NoError(t, limited.BwLimit.Set("1M"))
+	tb := getRemoteTokenBucket(&limited)
+	require.NotNil(t, tb)
+	assert.Equal(t, tb, getRemoteTokenBucket(&limited))
+	require.NoError(t, limited.BwLimit.Set("2M"))
+	tb2 := getRemoteTokenBucket(&limited)
+	require.NotNil(t, tb2)
+	assert.False(t, tb == tb2)
+}
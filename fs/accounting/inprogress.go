@@ -39,3 +39,18 @@ func (ip *inProgress) get(name string) *Account {
 	defer ip.mu.Unlock()
 	return ip.m[name]
 }
+
+// pending returns the number of bytes still to be read by the
+// transfers in progress
+func (ip *inProgress) pending() (bytes int64) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	for _, acc := range ip.m {
+		acc.statmu.Lock()
+		if acc.size > acc.bytes {
+			bytes += acc.size - acc.bytes
+		}
+		acc.statmu.Unlock()
+	}
+	return bytes
+}
@@ -81,16 +81,19 @@ type StatsInfo struct {
 	renameQueueSize   int64
 	deletes           int64
 	start             time.Time
+	transferStart     time.Time
 	inProgress        *inProgress
 }
 
 // NewStats cretates an initialised StatsInfo
 func NewStats() *StatsInfo {
+	now := time.Now()
 	return &StatsInfo{
-		checking:     newStringSet(fs.Config.Checkers),
-		transferring: newStringSet(fs.Config.Transfers),
-		start:        time.Now(),
-		inProgress:   newInProgress(),
+		checking:      newStringSet(fs.Config.Checkers),
+		transferring:  newStringSet(fs.Config.Transfers),
+		start:         now,
+		transferStart: now,
+		inProgress:    newInProgress(),
 	}
 }
 
@@ -221,7 +224,12 @@ Elapsed time:  %10v
 
 // Log outputs the StatsInfo to the log
 func (s *StatsInfo) Log() {
-	fs.LogLevelPrintf(fs.Config.StatsLogLevel, nil, "%v\n", s)
+	if fs.Config.UseJSONLog {
+		out, _ := s.RemoteStats(nil)
+		fs.LogLevelPrintf(fs.Config.StatsLogLevel, nil, "%v%v\n", s, fs.LogValueHide("stats", out))
+	} else {
+		fs.LogLevelPrintf(fs.Config.StatsLogLevel, nil, "%v\n", s)
+	}
 }
 
 // Bytes updates the stats for bytes bytes
@@ -238,6 +246,23 @@ func (s *StatsInfo) GetBytes() int64 {
 	return s.bytes
 }
 
+// GetBytesWithPending returns the number of bytes transferred so far
+// plus the bytes still to be transferred by the transfers in progress
+func (s *StatsInfo) GetBytesWithPending() int64 {
+	return s.GetBytes() + s.inProgress.pending()
+}
+
+// MaxDurationReached returns true if --max-duration is set and that
+// long has elapsed since the counters were last reset
+func (s *StatsInfo) MaxDurationReached() bool {
+	if fs.Config.MaxDuration <= 0 {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.transferStart) >= fs.Config.MaxDuration
+}
+
 // Errors updates the stats for errors
 func (s *StatsInfo) Errors(errors int64) {
 	s.mu.Lock()
@@ -267,7 +292,8 @@ func (s *StatsInfo) Deletes(deletes int64) int64 {
 	return s.deletes
 }
 
-// ResetCounters sets the counters (bytes, checks, errors, transfers) to 0
+// ResetCounters sets the counters (bytes, checks, errors, transfers)
+// to 0 and restarts the --max-duration timer
 func (s *StatsInfo) ResetCounters() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -276,6 +302,7 @@ func (s *StatsInfo) ResetCounters() {
 	s.checks = 0
 	s.transfers = 0
 	s.deletes = 0
+	s.transferStart = time.Now()
 }
 
 // ResetErrors sets the errors count to 0
@@ -99,9 +99,59 @@ func StartTokenTicker() {
 	}()
 }
 
+// remoteTokenBucket is the token bucket for a remote which overrides
+// --bwlimit
+type remoteTokenBucket struct {
+	bandwidth   fs.SizeSuffix // the bandwidth tokenBucket was made with
+	tokenBucket *rate.Limiter // nil if unlimited
+}
+
+var (
+	remoteTokenBucketsMu sync.Mutex
+	remoteTokenBuckets   = map[*fs.ConfigInfo]*remoteTokenBucket{}
+)
+
+// getRemoteTokenBucket returns the token bucket for the remote with
+// config ci for the current time slot of its timetable, or nil if it
+// is unlimited.
+func getRemoteTokenBucket(ci *fs.ConfigInfo) *rate.Limiter {
+	bandwidth := ci.BwLimit.LimitAt(time.Now()).Bandwidth
+	remoteTokenBucketsMu.Lock()
+	defer remoteTokenBucketsMu.Unlock()
+	tb := remoteTokenBuckets[ci]
+	if tb == nil || tb.bandwidth != bandwidth {
+		tb = &remoteTokenBucket{bandwidth: bandwidth}
+		if bandwidth > 0 {
+			tb.tokenBucket = newTokenBucket(bandwidth)
+		}
+		remoteTokenBuckets[ci] = tb
+	}
+	return tb.tokenBucket
+}
+
 // limitBandwith sleeps for the correct amount of time for the passage
-// of n bytes according to the current bandwidth limit
-func limitBandwidth(n int) {
+// of n bytes according to the current bandwidth limit.
+//
+// If any of remotes override --bwlimit then their limits are used
+// instead of the global one.
+func limitBandwidth(n int, remotes []*fs.ConfigInfo) {
+	overridden := false
+	for _, ci := range remotes {
+		if ci.BwLimit.String() == fs.Config.BwLimit.String() {
+			continue
+		}
+		overridden = true
+		if tb := getRemoteTokenBucket(ci); tb != nil {
+			err := tb.WaitN(context.Background(), n)
+			if err != nil {
+				fs.Errorf(nil, "Token bucket error: %v", err)
+			}
+		}
+	}
+	if overridden {
+		return
+	}
+
 	tokenBucketMu.Lock()
 
 	// Limit the transfer speed if required
@@ -0,0 +1,216 @@
+// Structures and utilities for backend config
+//
+// The config of a backend is done as a state machine so that it can
+// be driven by the command line prompts or by the remote control
+// API.  Each step is given the current state and the result of the
+// last question and returns the next question to ask, or nil when
+// the config is finished.
+
+package fs
+
+import (
+	"strings"
+
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/pkg/errors"
+)
+
+// ConfigOAuth should be called to do the OAuth
+//
+// This is a function pointer set by lib/oauthutil to decouple it
+// from the fs
+var ConfigOAuth func(name string, m configmap.Mapper, ri *RegInfo, in ConfigIn) (*ConfigOut, error)
+
+// ConfigIn is passed to the Config function of a backend
+//
+// It contains the state the config is in and the result of the
+// question asked in the last step, if any.
+type ConfigIn struct {
+	State          string // the state to run
+	Result         string // the result of the last Option asked
+	NonInteractive bool   // set if the config can't wait for the user, eg over the rc
+}
+
+// ConfigOut is returned from the Config function of a backend
+//
+// If Option is set then it should be asked and the answer passed in
+// as the Result with State.  If Option is not set then State is run
+// next immediately with Result.  If State is "" then the config is
+// finished.
+type ConfigOut struct {
+	State  string      // the state to run next
+	Option *Option     // the question to ask the user, if any
+	OAuth  interface{} `json:"-"` // the OAuth options to use for the "*oauth" states
+	Error  string      // an error to show the user, if any
+	Result string      // passed to State if Option isn't set
+}
+
+// ConfigGoto goes to the state passed in without asking a question
+func ConfigGoto(state string) (*ConfigOut, error) {
+	return &ConfigOut{State: state}, nil
+}
+
+// ConfigResult goes to the state passed in with the result passed in
+func ConfigResult(state, result string) (*ConfigOut, error) {
+	return &ConfigOut{State: state, Result: result}, nil
+}
+
+// ConfigError goes to the state passed in, showing the error to the
+// user
+func ConfigError(state string, errorText string) (*ConfigOut, error) {
+	return &ConfigOut{State: state, Error: errorText}, nil
+}
+
+// ConfigConfirm asks the user a yes or no question named name with
+// the default passed in.  The result will be "true" or "false".
+func ConfigConfirm(state string, Default bool, name string, help string) (*ConfigOut, error) {
+	return &ConfigOut{
+		State: state,
+		Option: &Option{
+			Name:    name,
+			Help:    help,
+			Default: Default,
+			Examples: []OptionExample{{
+				Value: "true",
+				Help:  "Yes",
+			}, {
+				Value: "false",
+				Help:  "No",
+			}},
+			Exclusive: true,
+		},
+	}, nil
+}
+
+// ConfigInput asks the user for a non empty string named name
+func ConfigInput(state string, name string, help string) (*ConfigOut, error) {
+	return &ConfigOut{
+		State: state,
+		Option: &Option{
+			Name:     name,
+			Help:     help,
+			Default:  "",
+			Required: true,
+		},
+	}, nil
+}
+
+// ConfigChoose asks the user to choose one of n items named name,
+// or type in their own value.
+//
+// getItem is called for each item to get its value and help.
+func ConfigChoose(state string, name string, help string, n int, getItem func(i int) (itemValue string, itemHelp string)) (*ConfigOut, error) {
+	out := &ConfigOut{
+		State: state,
+		Option: &Option{
+			Name:     name,
+			Help:     help,
+			Default:  "",
+			Required: true,
+			Examples: make(OptionExamples, n),
+		},
+	}
+	for i := range out.Option.Examples {
+		out.Option.Examples[i].Value, out.Option.Examples[i].Help = getItem(i)
+	}
+	return out, nil
+}
+
+// ConfigChooseFixed is the same as ConfigChoose but the user must
+// choose one of the items.
+func ConfigChooseFixed(state string, name string, help string, items []OptionExample) (*ConfigOut, error) {
+	out, err := ConfigChoose(state, name, help, len(items), func(i int) (string, string) {
+		return items[i].Value, items[i].Help
+	})
+	out.Option.Exclusive = true
+	return out, err
+}
+
+// StatePush pushes values onto the front of state, so a sub state
+// machine can carry them or the state to return to.
+//
+// Any "," in the values are replaced with "，" (a full width comma)
+// as "," is used as the separator.
+func StatePush(state string, values ...string) string {
+	parts := make([]string, 0, len(values)+1)
+	for _, value := range values {
+		parts = append(parts, strings.Replace(value, ",", "，", -1))
+	}
+	if state != "" {
+		parts = append(parts, state)
+	}
+	return strings.Join(parts, ",")
+}
+
+// StatePop pops the first value off state returning the rest of the
+// state and the value.
+func StatePop(state string) (newState string, value string) {
+	comma := strings.IndexRune(state, ',')
+	if comma < 0 {
+		return "", state
+	}
+	return state[comma+1:], state[:comma]
+}
+
+// backendConfigStep runs a single step of the config state machine
+func backendConfigStep(name string, m configmap.Mapper, ri *RegInfo, in ConfigIn) (out *ConfigOut, err error) {
+	Debugf(name, "config in: state=%q, result=%q", in.State, in.Result)
+	if strings.HasPrefix(in.State, "*oauth") {
+		if ConfigOAuth == nil {
+			return nil, errors.New("no OAuth handler configured")
+		}
+		out, err = ConfigOAuth(name, m, ri, in)
+	} else {
+		out, err = ri.Config(name, m, in)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if out != nil {
+		Debugf(name, "config out: state=%q, option=%v, result=%q", out.State, out.Option != nil, out.Result)
+	}
+	return out, nil
+}
+
+// BackendConfig runs the config state machine for the remote name
+// of type ri starting from in until it needs to ask a question,
+// returning the question.  It returns nil when the config is
+// finished.
+//
+// Call it with an empty ConfigIn to start the config and then with
+// the State of the ConfigOut returned and the answer to its Option
+// as the Result to continue it.
+//
+// Any Error in the steps run without asking a question is returned
+// in the ConfigOut.
+func BackendConfig(name string, m configmap.Mapper, ri *RegInfo, in ConfigIn) (*ConfigOut, error) {
+	if ri.Config == nil {
+		return nil, nil
+	}
+	var configError string
+	for {
+		out, err := backendConfigStep(name, m, ri, in)
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			return nil, nil
+		}
+		if out.Error != "" {
+			configError = out.Error
+		}
+		if out.Option != nil {
+			if out.Error == "" {
+				out.Error = configError
+			}
+			return out, nil
+		}
+		if out.State == "" {
+			if configError != "" {
+				return nil, errors.New(configError)
+			}
+			return nil, nil
+		}
+		in = ConfigIn{State: out.State, Result: out.Result, NonInteractive: in.NonInteractive}
+	}
+}
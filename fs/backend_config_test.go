@@ -0,0 +1,86 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatePushPop(t *testing.T) {
+	state := StatePush("", "next")
+	assert.Equal(t, "next", state)
+	state = StatePush(state, "first", "a,b")
+	assert.Equal(t, "first,a，b,next", state)
+
+	state, value := StatePop(state)
+	assert.Equal(t, "first", value)
+	state, value = StatePop(state)
+	assert.Equal(t, "a，b", value)
+	state, value = StatePop(state)
+	assert.Equal(t, "next", value)
+	assert.Equal(t, "", state)
+}
+
+func TestBackendConfig(t *testing.T) {
+	m := configmap.Simple{}
+	ri := &RegInfo{
+		Name: "backend_config_test",
+		Config: func(name string, m configmap.Mapper, in ConfigIn) (*ConfigOut, error) {
+			assert.Equal(t, "remote", name)
+			switch in.State {
+			case "":
+				return ConfigResult("start", "potato")
+			case "start":
+				m.Set("start", in.Result)
+				return ConfigConfirm("confirmed", true, "config_ok", "Is it OK?")
+			case "confirmed":
+				if in.Result == "false" {
+					return ConfigError("", "not confirmed")
+				}
+				return ConfigError("choose", "try choosing")
+			case "choose":
+				return ConfigChooseFixed("chosen", "config_choice", "Choose", []OptionExample{{Value: "a"}, {Value: "b"}})
+			case "chosen":
+				m.Set("choice", in.Result)
+				return nil, nil
+			}
+			return nil, nil
+		},
+	}
+
+	// runs until the first question
+	out, err := BackendConfig("remote", m, ri, ConfigIn{})
+	require.NoError(t, err)
+	require.NotNil(t, out)
+	assert.Equal(t, "confirmed", out.State)
+	assert.Equal(t, "config_ok", out.Option.Name)
+	assert.Equal(t, true, out.Option.Default)
+	assert.True(t, out.Option.Exclusive)
+	assert.Equal(t, "", out.Error)
+	assert.Equal(t, "potato", m["start"])
+
+	// errors with no question to ask are returned as errors
+	_, err = BackendConfig("remote", m, ri, ConfigIn{State: out.State, Result: "false"})
+	assert.EqualError(t, err, "not confirmed")
+
+	// errors are passed on with the next question
+	out, err = BackendConfig("remote", m, ri, ConfigIn{State: out.State, Result: "true"})
+	require.NoError(t, err)
+	require.NotNil(t, out)
+	assert.Equal(t, "chosen", out.State)
+	assert.Equal(t, "try choosing", out.Error)
+	assert.Equal(t, 2, len(out.Option.Examples))
+
+	// finished
+	out, err = BackendConfig("remote", m, ri, ConfigIn{State: out.State, Result: "b"})
+	require.NoError(t, err)
+	assert.Nil(t, out)
+	assert.Equal(t, "b", m["choice"])
+
+	// no Config
+	out, err = BackendConfig("remote", m, &RegInfo{Name: "no_config"}, ConfigIn{})
+	require.NoError(t, err)
+	assert.Nil(t, out)
+}
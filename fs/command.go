@@ -0,0 +1,79 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// splitCommandLine splits commandLine into arguments on spaces.
+// Arguments containing spaces can be quoted with "" as in a CSV
+// file, eg `pass "my secret"`.
+func splitCommandLine(commandLine string) ([]string, error) {
+	r := csv.NewReader(strings.NewReader(commandLine))
+	r.Comma = ' '
+	fields, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	args := fields[:0]
+	for _, field := range fields {
+		if field != "" {
+			args = append(args, field)
+		}
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
+}
+
+// CommandOutput runs the command line passed in and returns what it
+// writes to stdout with any trailing line endings removed.
+//
+// The command line is split on spaces and arguments containing spaces
+// can be quoted with "".  The command is run directly, not through a
+// shell.  Its stdin and stderr are those of rclone so it can prompt
+// the user if necessary.
+func CommandOutput(commandLine string) (string, error) {
+	args, err := splitCommandLine(commandLine)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse command %q", commandLine)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to run command %q", args[0])
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// CommandRun runs the command line passed in with its output going
+// to rclone's stdout and stderr.
+//
+// The command line is split in the same way as for CommandOutput.
+// Any environment variables in env, in the form "KEY=value", are
+// added to rclone's environment for the command.
+func CommandRun(commandLine string, env ...string) error {
+	args, err := splitCommandLine(commandLine)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse command %q", commandLine)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return errors.Wrapf(err, "failed to run command %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package fs
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, true},
+		{"   ", nil, true},
+		{"pass", []string{"pass"}, false},
+		{"pass show rclone", []string{"pass", "show", "rclone"}, false},
+		{"  pass   show  rclone  ", []string{"pass", "show", "rclone"}, false},
+		{`vault read "secret/my rclone"`, []string{"vault", "read", "secret/my rclone"}, false},
+		{`echo "say ""hello"""`, []string{"echo", `say "hello"`}, false},
+		{`echo "unterminated`, nil, true},
+	} {
+		got, err := splitCommandLine(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+			assert.Equal(t, test.want, got, test.in)
+		}
+	}
+}
+
+func TestCommandOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs echo and false")
+	}
+	out, err := CommandOutput(`echo "hello  world"`)
+	require.NoError(t, err)
+	assert.Equal(t, "hello  world", out)
+
+	_, err = CommandOutput("false")
+	assert.Error(t, err)
+
+	_, err = CommandOutput("")
+	assert.Error(t, err)
+}
+
+func TestCommandRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs sh")
+	}
+	require.NoError(t, CommandRun(`sh -c "test $RCLONE_TEST = potato"`, "RCLONE_TEST=potato"))
+	assert.Error(t, CommandRun(`sh -c "test $RCLONE_TEST = potato"`, "RCLONE_TEST=sausage"))
+	assert.Error(t, CommandRun(""))
+}
@@ -34,6 +34,14 @@ var (
 
 	// ConfigProvider is the config key used for provider options
 	ConfigProvider = "provider"
+
+	// ConfigInherits is the config key used to name the remote
+	// whose config a remote inherits
+	ConfigInherits = "inherits"
+
+	// ConfigGlobalPrefix is the prefix of config keys which
+	// override global options for a remote, eg "global.tpslimit"
+	ConfigGlobalPrefix = "global."
 )
 
 // ConfigInfo is filesystem config options
@@ -55,7 +63,8 @@ type ConfigInfo struct {
 	InsecureSkipVerify    bool // Skip server certificate verification
 	DeleteMode            DeleteMode
 	MaxDelete             int64
-	TrackRenames          bool // Track file renames.
+	TrackRenames          bool   // Track file renames.
+	TrackRenamesStrategy  string // Comma separated list of strategies used to track renames
 	LowLevelRetries       int
 	UpdateOlder           bool // Skip files that are newer on the destination
 	NoGzip                bool // Disable compression
@@ -66,6 +75,9 @@ type ConfigInfo struct {
 	DataRateUnit          string
 	BackupDir             string
 	Suffix                string
+	SuffixKeepExtension   bool
+	CompareDest           string
+	CopyDest              string
 	UseListR              bool
 	BufferSize            SizeSuffix
 	BwLimit               BwTimetable
@@ -79,10 +91,32 @@ type ConfigInfo struct {
 	StreamingUploadCutoff SizeSuffix
 	StatsFileNameLength   int
 	AskPassword           bool
+	PasswordCommand       string // command to run to get the config password
 	UseServerModTime      bool
 	MaxTransfer           SizeSuffix
 	MaxBacklog            int
 	StatsOneLine          bool
+	MultiThreadCutoff     SizeSuffix
+	MultiThreadStreams    int
+	OrderBy               string
+	CheckFirst            bool
+	MaxDuration           time.Duration
+	CutoffMode            CutoffMode
+	UseJSONLog            bool
+
+	// the name and overrides of the remote this is the config of
+	// if it was made by GetConfigMap, or "" if not
+	remote string
+}
+
+// RemoteKey returns the name and global option overrides of the
+// remote this is the config of if it was made by GetConfig or
+// GetConfigMap, or "" if not.
+//
+// The config of a remote is remade when Config changes so this can
+// be used to cache things made from it.
+func (ci *ConfigInfo) RemoteKey() string {
+	return ci.remote
 }
 
 // NewConfig creates a new config with everything set to the default
@@ -112,6 +146,10 @@ func NewConfig() *ConfigInfo {
 	c.TPSLimitBurst = 1
 	c.MaxTransfer = -1
 	c.MaxBacklog = 10000
+	c.MultiThreadCutoff = SizeSuffix(250 * 1024 * 1024)
+	c.MultiThreadStreams = 4
+	c.CutoffMode = CutoffModeDefault
+	c.TrackRenamesStrategy = "hash"
 
 	return c
 }
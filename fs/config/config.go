@@ -21,12 +21,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
 	"github.com/Unknwon/goconfig"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/driveletter"
@@ -65,6 +67,35 @@ var (
 	// configFile is the global config data structure. Don't read it directly, use getConfigData()
 	configFile *goconfig.ConfigFile
 
+	// configMu protects configFile, configChanges, configFileInfo
+	// and configLastCheck
+	configMu sync.Mutex
+
+	// configChanges are the changes made to configFile since it
+	// was last saved.  These are applied to the config file on
+	// disk if it was changed by another process.
+	configChanges []configChange
+
+	// configFileInfo is the info of the config file when it was
+	// last loaded or saved, or nil if it didn't exist
+	configFileInfo os.FileInfo
+
+	// configLastCheck is when the config file was last checked
+	// for changes by another process
+	configLastCheck time.Time
+
+	// configCheckInterval is how often to check the config file
+	// for changes by another process
+	configCheckInterval = time.Second
+
+	// configLockTimeout is how long to wait for another process
+	// to release the lock on the config file
+	configLockTimeout = 10 * time.Second
+
+	// configLockRetry is how often to try to take the lock on the
+	// config file while waiting for it
+	configLockRetry = 100 * time.Millisecond
+
 	// ConfigPath points to the config file
 	ConfigPath = makeConfigPath()
 
@@ -98,9 +129,182 @@ func getConfigData() *goconfig.ConfigFile {
 	if configFile == nil {
 		LoadConfig()
 	}
+	configMu.Lock()
+	defer configMu.Unlock()
+	if time.Since(configLastCheck) >= configCheckInterval {
+		configLastCheck = time.Now()
+		if configFileChanged() {
+			unlock, err := lockConfigFile()
+			if err != nil {
+				fs.Errorf(nil, "Not reloading changed config file: %v", err)
+			} else {
+				reloadConfigIfChanged()
+				unlock()
+			}
+		}
+	}
 	return configFile
 }
 
+// errFileLocked is returned by lockFile if another process holds the
+// lock
+var errFileLocked = errors.New("file is locked by another process")
+
+// lockConfigFile takes an exclusive advisory lock on the config file
+// so that other rclone processes can't change it while this process
+// reads, merges and writes it.  It returns a function to release the
+// lock.
+//
+// The lock is only held while the config file is read or written and
+// never while asking for the config password or running the
+// --password-command, so if another process holds it for more than
+// configLockTimeout then an error is returned.
+//
+// The lock is taken on a separate lock file next to the config file
+// as the config file itself is replaced when it is saved.  If the
+// lock can't be taken for any other reason, for example if the config
+// directory is read only, then this is logged and no lock is taken.
+//
+// Call with configMu held as a process can't take the lock twice.
+func lockConfigFile() (unlock func(), err error) {
+	noop := func() {}
+	f, err := os.OpenFile(ConfigPath+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		fs.Debugf(nil, "Failed to open config lock file: %v", err)
+		return noop, nil
+	}
+	deadline := time.Now().Add(configLockTimeout)
+	for {
+		err = lockFile(f)
+		if err != errFileLocked || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(configLockRetry)
+	}
+	if err == errFileLocked {
+		_ = f.Close()
+		return nil, errors.Errorf("timed out after %v waiting for another rclone process to release the lock on config file %q", configLockTimeout, ConfigPath)
+	}
+	if err != nil {
+		fs.Debugf(nil, "Failed to lock config file: %v", err)
+		_ = f.Close()
+		return noop, nil
+	}
+	return func() {
+		err := unlockFile(f)
+		if err != nil {
+			fs.Debugf(nil, "Failed to unlock config file: %v", err)
+		}
+		_ = f.Close()
+	}, nil
+}
+
+// configChange is a change made to the config by this process
+type configChange struct {
+	section string
+	key     string // if "" then the section is deleted
+	value   string
+	delete  bool // set if the key is deleted
+}
+
+// apply the change to c
+func (change *configChange) apply(c *goconfig.ConfigFile) {
+	switch {
+	case change.key == "":
+		c.DeleteSection(change.section)
+	case change.delete:
+		c.DeleteKey(change.section, change.key)
+	default:
+		c.SetValue(change.section, change.key, change.value)
+	}
+}
+
+// makeConfigChange makes change to the config and records it so it
+// can be merged with the config file on disk when it is saved.
+//
+// The change is applied to configFile with configMu held as the
+// config file may be reloaded by another goroutine at any time
+// before that.
+func makeConfigChange(change configChange) {
+	_ = getConfigData() // load or reload the config if needed
+	configMu.Lock()
+	defer configMu.Unlock()
+	change.apply(configFile)
+	configChanges = append(configChanges, change)
+}
+
+// setConfigValue sets the key in section to value
+func setConfigValue(section, key, value string) {
+	makeConfigChange(configChange{section: section, key: key, value: value})
+}
+
+// deleteConfigKey deletes the key in section returning true if it
+// existed
+func deleteConfigKey(section, key string) bool {
+	if _, err := getConfigData().GetValue(section, key); err != nil {
+		return false
+	}
+	makeConfigChange(configChange{section: section, key: key, delete: true})
+	return true
+}
+
+// deleteConfigSection deletes section and all its keys
+func deleteConfigSection(section string) {
+	makeConfigChange(configChange{section: section})
+}
+
+// statConfigFile returns the info of the config file, or nil if it
+// doesn't exist
+func statConfigFile() os.FileInfo {
+	info, err := os.Stat(ConfigPath)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// configFileChanged returns true if the config file on disk has
+// changed since it was last loaded or saved by this process.
+//
+// Call with configMu held.
+func configFileChanged() bool {
+	info := statConfigFile()
+	if info == nil || configFileInfo == nil {
+		return info != configFileInfo
+	}
+	return !info.ModTime().Equal(configFileInfo.ModTime()) || info.Size() != configFileInfo.Size()
+}
+
+// reloadConfigIfChanged reloads the config file if it has been
+// changed by another process, applying the changes made by this
+// process which haven't been saved yet on top.
+//
+// This never asks for the config password or runs the
+// --password-command.
+//
+// Call with configMu held and the config file locked.
+func reloadConfigIfChanged() {
+	if configFile == nil || !configFileChanged() {
+		return
+	}
+	info := statConfigFile()
+	newConfigFile, err := doLoadConfigFile(false)
+	if err == errorConfigFileNotFound {
+		newConfigFile, _ = goconfig.LoadFromReader(&bytes.Buffer{})
+	} else if err != nil {
+		fs.Errorf(nil, "Failed to reload changed config file %q: %v", ConfigPath, err)
+		// Don't try again until it changes again
+		configFileInfo = info
+		return
+	}
+	fs.Debugf(nil, "Reloaded config file %q as it was changed by another process", ConfigPath)
+	for i := range configChanges {
+		configChanges[i].apply(newConfigFile)
+	}
+	configFile = newConfigFile
+	configFileInfo = info
+}
+
 // Return the path to the configuration file
 func makeConfigPath() string {
 	// Find user's home directory
@@ -186,7 +390,25 @@ func makeConfigPath() string {
 func LoadConfig() {
 	// Load configuration file.
 	var err error
-	configFile, err = loadConfigFile()
+	configMu.Lock()
+	unlock := func() {}
+	if statConfigFile() != nil {
+		// Don't make a lock file unless there is a config file
+		unlock, err = lockConfigFile()
+		if err != nil {
+			log.Fatalf("Failed to load config file %q: %v", ConfigPath, err)
+		}
+	}
+	// Only hold the lock while reading the file, not while
+	// decrypting it which may ask for the password
+	b, err := readConfigFile()
+	configFileInfo = statConfigFile()
+	unlock()
+	if err == nil {
+		configFile, err = parseConfigFile(b, true)
+	}
+	configChanges = nil
+	configLastCheck = time.Now()
 	if err == errorConfigFileNotFound {
 		fs.Logf(nil, "Config file %q not found - using defaults", ConfigPath)
 		configFile, _ = goconfig.LoadFromReader(&bytes.Buffer{})
@@ -195,6 +417,7 @@ func LoadConfig() {
 	} else {
 		fs.Debugf(nil, "Using config file from %q", ConfigPath)
 	}
+	configMu.Unlock()
 
 	// Start the token bucket limiter
 	accounting.StartTokenBucket()
@@ -211,6 +434,26 @@ var errorConfigFileNotFound = errors.New("config file not found")
 // loadConfigFile will load a config file, and
 // automatically decrypt it.
 func loadConfigFile() (*goconfig.ConfigFile, error) {
+	return doLoadConfigFile(true)
+}
+
+// doLoadConfigFile will load a config file, and automatically
+// decrypt it.
+//
+// If interactive is false then it won't ask the user for the
+// password, won't run the --password-command and won't forget the
+// current password if it doesn't decrypt the config file.
+func doLoadConfigFile(interactive bool) (*goconfig.ConfigFile, error) {
+	b, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigFile(b, interactive)
+}
+
+// readConfigFile reads the contents of the config file returning
+// errorConfigFileNotFound if it doesn't exist
+func readConfigFile() ([]byte, error) {
 	b, err := ioutil.ReadFile(ConfigPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -218,7 +461,13 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		}
 		return nil, err
 	}
+	return b, nil
+}
 
+// parseConfigFile parses the contents of the config file read by
+// readConfigFile, decrypting it if necessary.  See doLoadConfigFile
+// for the meaning of interactive.
+func parseConfigFile(b []byte, interactive bool) (*goconfig.ConfigFile, error) {
 	// Find first non-empty line
 	r := bufio.NewReader(bytes.NewBuffer(b))
 	for {
@@ -253,10 +502,12 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		return nil, errors.New("Configuration data too short")
 	}
 	envpw := os.Getenv("RCLONE_CONFIG_PASS")
+	usePasswordCommand := interactive && fs.Config.PasswordCommand != ""
 
+	oldConfigKey := configKey
 	var out []byte
 	for {
-		if envKeyFile := os.Getenv("_RCLONE_CONFIG_KEY_FILE"); len(envKeyFile) > 0 {
+		if envKeyFile := os.Getenv("_RCLONE_CONFIG_KEY_FILE"); len(envKeyFile) > 0 && len(configKey) == 0 {
 			fs.Debugf(nil, "attempting to obtain configKey from temp file %s", envKeyFile)
 			obscuredKey, err := ioutil.ReadFile(envKeyFile)
 			if err != nil {
@@ -273,6 +524,17 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 			configKey = []byte(obscure.MustReveal(string(obscuredKey)))
 			fs.Debugf(nil, "using _RCLONE_CONFIG_KEY_FILE for configKey")
 		} else {
+			if len(configKey) == 0 && usePasswordCommand {
+				password, err := fs.CommandOutput(fs.Config.PasswordCommand)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to get configuration password from --password-command")
+				}
+				err = setConfigPassword(password)
+				if err != nil {
+					return nil, errors.Wrap(err, "bad configuration password from --password-command")
+				}
+				fs.Debugf(nil, "Using --password-command password.")
+			}
 			if len(configKey) == 0 && envpw != "" {
 				err := setConfigPassword(envpw)
 				if err != nil {
@@ -282,8 +544,8 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 				}
 			}
 			if len(configKey) == 0 {
-				if !fs.Config.AskPassword {
-					return nil, errors.New("unable to decrypt configuration and not allowed to ask for password - set RCLONE_CONFIG_PASS to your configuration password")
+				if !fs.Config.AskPassword || !interactive {
+					return nil, errors.New("unable to decrypt configuration and not allowed to ask for password - set RCLONE_CONFIG_PASS to your configuration password or use --password-command")
 				}
 				getConfigPassword("Enter configuration password:")
 			}
@@ -303,6 +565,14 @@ func loadConfigFile() (*goconfig.ConfigFile, error) {
 		}
 
 		// Retry
+		if !interactive {
+			configKey = oldConfigKey
+			return nil, errors.New("couldn't decrypt configuration, most likely wrong password")
+		}
+		if usePasswordCommand {
+			configKey = nil
+			return nil, errors.New("couldn't decrypt configuration with the password from --password-command, most likely wrong password")
+		}
 		fs.Errorf(nil, "Couldn't decrypt configuration, most likely wrong password.")
 		configKey = nil
 		envpw = ""
@@ -434,7 +704,33 @@ func changeConfigPassword() {
 
 // saveConfig saves configuration file.
 // if configKey has been set, the file will be encrypted.
+//
+// The config file is locked while it is saved.  If it was changed by
+// another process since it was loaded then it is reloaded and only
+// the changes made by this process are saved.
 func saveConfig() error {
+	_ = getConfigData()
+	configMu.Lock()
+	defer configMu.Unlock()
+	unlock, err := lockConfigFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	reloadConfigIfChanged()
+	err = writeConfigFile()
+	if err != nil {
+		return err
+	}
+	configFileInfo = statConfigFile()
+	configChanges = nil
+	return nil
+}
+
+// writeConfigFile writes configFile to the config file.
+//
+// Call with configMu held.
+func writeConfigFile() error {
 	dir, name := filepath.Split(ConfigPath)
 	f, err := ioutil.TempFile(dir, name)
 	if err != nil {
@@ -447,7 +743,7 @@ func saveConfig() error {
 	}()
 
 	var buf bytes.Buffer
-	err = goconfig.SaveConfigData(getConfigData(), &buf)
+	err = goconfig.SaveConfigData(configFile, &buf)
 	if err != nil {
 		return errors.Errorf("Failed to save config file: %v", err)
 	}
@@ -534,31 +830,27 @@ func SaveConfig() {
 }
 
 // SetValueAndSave sets the key to the value and saves just that
-// value in the config file.  It loads the old config file in from
-// disk first and overwrites the given value only.
+// value in the config file.  If the config file was changed by
+// another process it is reloaded first and the given value is set
+// in the reloaded version.
+//
+// If the remote isn't in the config file, for example if it was
+// defined with environment variables, then the value is only set in
+// memory and an error is returned.
+//
+// name may be the name reported by a remote made with connection
+// string parameters, eg "remote{1a2b3c4d}", in which case the value is
+// set in the config of the remote it was made from.
 func SetValueAndSave(name, key, value string) (err error) {
-	// Set the value in config in case we fail to reload it
-	getConfigData().SetValue(name, key, value)
-	// Reload the config file
-	reloadedConfigFile, err := loadConfigFile()
-	if err == errorConfigFileNotFound {
-		// Config file not written yet so ignore reload
-		return nil
-	} else if err != nil {
-		return err
-	}
-	_, err = reloadedConfigFile.GetSection(name)
+	name, _ = fspath.ParseConfigName(name)
+	_, err = getConfigData().GetSection(name)
 	if err != nil {
-		// Section doesn't exist yet so ignore reload
+		// Section doesn't exist in the config file so don't save it
+		getConfigData().SetValue(name, key, value)
 		return err
 	}
-	// Update the config file with the reloaded version
-	configFile = reloadedConfigFile
-	// Set the value in the reloaded version
-	reloadedConfigFile.SetValue(name, key, value)
-	// Save it again
-	SaveConfig()
-	return nil
+	setConfigValue(name, key, value)
+	return saveConfig()
 }
 
 // ShowRemotes shows an overview of the config file
@@ -571,7 +863,7 @@ func ShowRemotes() {
 	fmt.Printf("%-20s %s\n", "Name", "Type")
 	fmt.Printf("%-20s %s\n", "====", "====")
 	for _, remote := range remotes {
-		fmt.Printf("%-20s %s\n", remote, FileGet(remote, "type"))
+		fmt.Printf("%-20s %s\n", remote, RemoteType(remote))
 	}
 }
 
@@ -740,7 +1032,7 @@ func OkRemote(name string) bool {
 	case 'e':
 		return false
 	case 'd':
-		getConfigData().DeleteSection(name)
+		deleteConfigSection(name)
 		return true
 	default:
 		fs.Errorf(nil, "Bad choice %c", i)
@@ -751,7 +1043,7 @@ func OkRemote(name string) bool {
 // MustFindByName finds the RegInfo for the remote name passed in or
 // exits with a fatal error.
 func MustFindByName(name string) *fs.RegInfo {
-	fsType := FileGet(name, "type")
+	fsType := RemoteType(name)
 	if fsType == "" {
 		log.Fatalf("Couldn't find type of fs for %q", name)
 	}
@@ -759,12 +1051,42 @@ func MustFindByName(name string) *fs.RegInfo {
 }
 
 // RemoteConfig runs the config helper for the remote if needed
-func RemoteConfig(name string) {
+func RemoteConfig(name string) error {
 	fmt.Printf("Remote config\n")
 	f := MustFindByName(name)
-	if f.Config != nil {
-		m := fs.ConfigMap(f, name)
-		f.Config(name, m)
+	if f.Config == nil {
+		return nil
+	}
+	return PostConfig(name, fs.ConfigMap(f, name), f)
+}
+
+// PostConfig runs the config state machine of the backend ri for the
+// remote name, asking the user the questions on the terminal.
+//
+// If AutoConfirm is set then the default answer is used for each
+// question.
+func PostConfig(name string, m configmap.Mapper, ri *fs.RegInfo) error {
+	in := fs.ConfigIn{}
+	for {
+		out, err := fs.BackendConfig(name, m, ri, in)
+		if err != nil {
+			return err
+		}
+		if out == nil {
+			return nil
+		}
+		if out.Error != "" {
+			fmt.Println(out.Error)
+		}
+		in.State = out.State
+		if fs.Config.AutoConfirm {
+			in.Result = fmt.Sprint(out.Option.Default)
+			if in.Result == "" && out.Option.Required {
+				return errors.Errorf("can't answer %q automatically as it has no default", out.Option.Name)
+			}
+		} else {
+			in.Result = ChooseOption(out.Option, name)
+		}
 	}
 }
 
@@ -865,7 +1187,7 @@ func ChooseOption(o *fs.Option, name string) string {
 					help = append(help, example.Help)
 				}
 			}
-			in = Choose(o.Name, values, help, true)
+			in = Choose(o.Name, values, help, !o.Exclusive)
 		} else {
 			fmt.Printf("%s> ", o.Name)
 			in = ReadLine()
@@ -896,9 +1218,12 @@ func UpdateRemote(name string, keyValues []string) error {
 	}
 	// Set the config
 	for i := 0; i < len(keyValues); i += 2 {
-		getConfigData().SetValue(name, keyValues[i], keyValues[i+1])
+		setConfigValue(name, keyValues[i], keyValues[i+1])
+	}
+	err := RemoteConfig(name)
+	if err != nil {
+		return err
 	}
-	RemoteConfig(name)
 	ShowRemote(name)
 	SaveConfig()
 	return nil
@@ -908,14 +1233,18 @@ func UpdateRemote(name string, keyValues []string) error {
 // parameters which are key, value pairs.  If update is set then it
 // adds the new keys rather than replacing all of them.
 func CreateRemote(name string, provider string, keyValues []string) error {
+	err := CheckRemoteName(name)
+	if err != nil {
+		return err
+	}
 	// Suppress Confirm
 	fs.Config.AutoConfirm = true
 	// Delete the old config if it exists
-	getConfigData().DeleteSection(name)
+	deleteConfigSection(name)
 	// Set the type
-	getConfigData().SetValue(name, "type", provider)
+	setConfigValue(name, "type", provider)
 	// Show this is automatically configured
-	getConfigData().SetValue(name, ConfigAutomatic, "yes")
+	setConfigValue(name, ConfigAutomatic, "yes")
 	// Set the remaining values
 	return UpdateRemote(name, keyValues)
 }
@@ -930,8 +1259,11 @@ func PasswordRemote(name string, keyValues []string) error {
 	fs.Config.AutoConfirm = true
 	passwd := obscure.MustObscure(keyValues[1])
 	if passwd != "" {
-		getConfigData().SetValue(name, keyValues[0], passwd)
-		RemoteConfig(name)
+		setConfigValue(name, keyValues[0], passwd)
+		err := RemoteConfig(name)
+		if err != nil {
+			return err
+		}
 		ShowRemote(name)
 		SaveConfig()
 	}
@@ -969,22 +1301,30 @@ func fsOption() *fs.Option {
 	return o
 }
 
+// CheckRemoteName returns an error if name can't be used as the name
+// of a remote
+func CheckRemoteName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("can't use empty name")
+	case driveletter.IsDriveLetter(name):
+		return errors.Errorf("can't use %q as it can be confused with a drive letter", name)
+	case !fspath.NameMatcher.MatchString(name):
+		return errors.Errorf("can't use %q as it has invalid characters in it", name)
+	}
+	return nil
+}
+
 // NewRemoteName asks the user for a name for a remote
 func NewRemoteName() (name string) {
 	for {
 		fmt.Printf("name> ")
 		name = ReadLine()
-		parts := fspath.Matcher.FindStringSubmatch(name + ":")
-		switch {
-		case name == "":
-			fmt.Printf("Can't use empty name.\n")
-		case driveletter.IsDriveLetter(name):
-			fmt.Printf("Can't use %q as it can be confused with a drive letter.\n", name)
-		case parts == nil:
-			fmt.Printf("Can't use %q as it has invalid characters in it.\n", name)
-		default:
+		err := CheckRemoteName(name)
+		if err == nil {
 			return name
 		}
+		fmt.Printf("%v.\n", err)
 	}
 }
 
@@ -1040,10 +1380,10 @@ func NewRemote(name string) {
 		}
 		break
 	}
-	getConfigData().SetValue(name, "type", newType)
+	setConfigValue(name, "type", newType)
 
 	editOptions(ri, name, true)
-	RemoteConfig(name)
+	mustRemoteConfig(name)
 	if OkRemote(name) {
 		SaveConfig()
 		return
@@ -1062,12 +1402,21 @@ func EditRemote(ri *fs.RegInfo, name string) {
 		}
 	}
 	SaveConfig()
-	RemoteConfig(name)
+	mustRemoteConfig(name)
+}
+
+// mustRemoteConfig runs the config helper for the remote exiting with
+// a fatal error if it fails
+func mustRemoteConfig(name string) {
+	err := RemoteConfig(name)
+	if err != nil {
+		log.Fatalf("Failed to configure %q: %v", name, err)
+	}
 }
 
 // DeleteRemote gets the user to delete a remote
 func DeleteRemote(name string) {
-	getConfigData().DeleteSection(name)
+	deleteConfigSection(name)
 	SaveConfig()
 }
 
@@ -1078,7 +1427,7 @@ func copyRemote(name string) string {
 	// Copy the keys
 	for _, key := range getConfigData().GetKeyList(name) {
 		value := getConfigData().MustValue(name, key, "")
-		getConfigData().SetValue(newName, key, value)
+		setConfigValue(newName, key, value)
 	}
 	return newName
 }
@@ -1088,7 +1437,7 @@ func RenameRemote(name string) {
 	fmt.Printf("Enter new name for %q remote.\n", name)
 	newName := copyRemote(name)
 	if name != newName {
-		getConfigData().DeleteSection(name)
+		deleteConfigSection(name)
 		SaveConfig()
 	}
 }
@@ -1222,13 +1571,16 @@ func Authorize(args []string) {
 	defer DeleteRemote(name)
 
 	// Indicate that we want fully automatic configuration.
-	getConfigData().SetValue(name, ConfigAutomatic, "yes")
+	setConfigValue(name, ConfigAutomatic, "yes")
 	if len(args) == 3 {
-		getConfigData().SetValue(name, ConfigClientID, args[1])
-		getConfigData().SetValue(name, ConfigClientSecret, args[2])
+		setConfigValue(name, ConfigClientID, args[1])
+		setConfigValue(name, ConfigClientSecret, args[2])
 	}
 	m := fs.ConfigMap(f, name)
-	f.Config(name, m)
+	err := PostConfig(name, m, f)
+	if err != nil {
+		log.Fatalf("Failed to authorize: %v", err)
+	}
 }
 
 // FileGetFlag gets the config key under section returning the
@@ -1251,11 +1603,18 @@ func FileGet(section, key string, defaultVal ...string) string {
 	return getConfigData().MustValue(section, key, defaultVal...)
 }
 
+// RemoteType returns the type of the remote name, or "" if it isn't
+// set.  The type may be inherited from another remote.
+func RemoteType(name string) string {
+	fsType, _ := fs.ConfigMap(nil, name).Get("type")
+	return fsType
+}
+
 // FileSet sets the key in section to value.  It doesn't save
 // the config file.
 func FileSet(section, key, value string) {
 	if value != "" {
-		getConfigData().SetValue(section, key, value)
+		setConfigValue(section, key, value)
 	} else {
 		FileDeleteKey(section, key)
 	}
@@ -1265,7 +1624,7 @@ func FileSet(section, key, value string) {
 // It returns true if the key was deleted,
 // or returns false if the section or key didn't exist.
 func FileDeleteKey(section, key string) bool {
-	return getConfigData().DeleteKey(section, key)
+	return deleteConfigKey(section, key)
 }
 
 var matchEnv = regexp.MustCompile(`^RCLONE_CONFIG_(.*?)_TYPE=.*$`)
@@ -0,0 +1,18 @@
+// Read, write and edit the config file
+// File locking for systems without support.
+
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package config
+
+import "os"
+
+// lockFile does nothing on this system
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing on this system
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config/obscure"
@@ -21,6 +23,7 @@ func TestCRUD(t *testing.T) {
 	defer func() {
 		err := os.Remove(path)
 		assert.NoError(t, err)
+		_ = os.Remove(path + ".lock")
 	}()
 	assert.NoError(t, tempFile.Close())
 
@@ -81,6 +84,102 @@ func TestCRUD(t *testing.T) {
 	assert.Equal(t, []string{}, configFile.GetSectionList())
 }
 
+// writeConfigExternally writes contents to the config file as if
+// another process had done it
+func writeConfigExternally(t *testing.T, contents string) {
+	require.NoError(t, ioutil.WriteFile(ConfigPath, []byte(contents), 0600))
+	// make sure the modification time changes
+	modTime := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(ConfigPath, modTime, modTime))
+}
+
+func TestSaveConfigMerge(t *testing.T) {
+	defer setupTestConfig(t)()
+
+	setConfigValue("one", "type", "local")
+	SaveConfig()
+
+	writeConfigExternally(t, "[one]\ntype = local\nextra = external\n\n[two]\ntype = local\n")
+
+	// Only the changed key should be saved
+	require.NoError(t, SetValueAndSave("one", "token", "potato"))
+
+	c, err := loadConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, c.GetSectionList())
+	assert.Equal(t, "external", c.MustValue("one", "extra"))
+	assert.Equal(t, "potato", c.MustValue("one", "token"))
+
+	// Can't save to remotes which aren't in the config file
+	assert.Error(t, SetValueAndSave("three", "token", "potato"))
+	c, err = loadConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, c.GetSectionList())
+}
+
+func TestConfigReload(t *testing.T) {
+	defer setupTestConfig(t)()
+	oldConfigCheckInterval := configCheckInterval
+	configCheckInterval = 0
+	defer func() { configCheckInterval = oldConfigCheckInterval }()
+
+	setConfigValue("one", "type", "local")
+	SaveConfig()
+	assert.Equal(t, []string{"one"}, getConfigData().GetSectionList())
+
+	// Changes not saved yet
+	setConfigValue("mine", "type", "local")
+	deleteConfigSection("one")
+
+	// Changes made by another process should be picked up
+	writeConfigExternally(t, "[one]\ntype = local\n\n[two]\ntype = local\n")
+	assert.Equal(t, "local", FileGet("two", "type"))
+	assert.Equal(t, []string{"two", "mine"}, getConfigData().GetSectionList())
+
+	// And both saved
+	SaveConfig()
+	c, err := loadConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two", "mine"}, c.GetSectionList())
+}
+
+func TestConfigLockTimeout(t *testing.T) {
+	defer setupTestConfig(t)()
+	oldConfigLockTimeout := configLockTimeout
+	configLockTimeout = 100 * time.Millisecond
+	defer func() { configLockTimeout = oldConfigLockTimeout }()
+
+	setConfigValue("one", "type", "local")
+	require.NoError(t, saveConfig())
+
+	// Hold the lock as another process would
+	f, err := os.OpenFile(ConfigPath+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+	require.NoError(t, lockFile(f))
+	unlock, err := lockConfigFile()
+	if err == nil {
+		unlock()
+		t.Skip("file locking not supported")
+	}
+	assert.Contains(t, err.Error(), "timed out")
+
+	// Saving fails with the same error rather than waiting forever
+	setConfigValue("one", "token", "potato")
+	err = saveConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+
+	// Once the lock is released it works again
+	require.NoError(t, unlockFile(f))
+	require.NoError(t, saveConfig())
+	c, err := loadConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, "potato", c.MustValue("one", "token"))
+}
+
 // Test some error cases
 func TestReveal(t *testing.T) {
 	for _, test := range []struct {
@@ -140,6 +239,46 @@ func TestConfigLoadEncrypted(t *testing.T) {
 	assert.Equal(t, expect, keys)
 }
 
+func TestConfigLoadEncryptedPasswordCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs echo")
+	}
+	oldConfigPath := ConfigPath
+	oldPasswordCommand := fs.Config.PasswordCommand
+	ConfigPath = "./testdata/encrypted.conf"
+	defer func() {
+		ConfigPath = oldConfigPath
+		fs.Config.PasswordCommand = oldPasswordCommand
+		configKey = nil // reset password
+	}()
+
+	// Correct password
+	configKey = nil
+	fs.Config.PasswordCommand = "echo asdf"
+	c, err := loadConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nounc", "unc"}, c.GetSectionList())
+
+	// The command isn't run when reloading the config file as the
+	// config file is locked then
+	configKey = nil
+	_, err = doLoadConfigFile(false)
+	assert.Error(t, err)
+	assert.Nil(t, configKey)
+
+	// Wrong password doesn't retry
+	configKey = nil
+	fs.Config.PasswordCommand = "echo potato"
+	_, err = loadConfigFile()
+	assert.Error(t, err)
+
+	// Command fails
+	configKey = nil
+	fs.Config.PasswordCommand = "/this/command/does/not/exist"
+	_, err = loadConfigFile()
+	assert.Error(t, err)
+}
+
 func TestConfigLoadEncryptedFailures(t *testing.T) {
 	var err error
 
@@ -0,0 +1,154 @@
+// Remote control for the config
+
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "config/create",
+		Fn:    rcCreate,
+		Title: "Create the config for a remote.",
+		Help: `
+This takes the following parameters
+
+- name - name of remote
+- type - type of the new remote
+- overwrite - set to true to replace an existing remote of the same name
+- continue - set to true to answer a question from a previous call
+- state - the state from a previous call, if continuing
+- result - the answer to the question from a previous call, if continuing
+
+Any other parameters are stored as config values of the remote, eg
+
+    rclone rc config/create name=mydrive type=drive scope=drive
+
+Config values ending in "_command" which run a command can't be set
+this way - add them to the config file instead.
+
+It is an error if the remote already exists unless overwrite=true is
+given, in which case its old config is removed.
+
+If the backend needs to ask a question to finish the config, for
+example to choose a team drive, then the question is returned as
+"option" with its name, help and examples along with the "state".
+Call config/create again with the same name, continue=true, the
+"state" and the answer as "result" to continue the config.  This
+repeats until the returned "state" is empty.
+
+Any error to show the user is returned in "error".
+
+The call never waits for the user.  If the remote uses OAuth with
+rclone's local web server then the question asks the user to visit a
+URL and log in, then the web server receives the code in the
+background.  Answer the question once the user has done this.
+
+See [rclone config create](/commands/rclone_config_create/) for more
+information.
+`,
+	})
+}
+
+// rcReservedParams are the parameters to config/create which aren't
+// config values
+var rcReservedParams = map[string]struct{}{
+	"name":      {},
+	"type":      {},
+	"overwrite": {},
+	"continue":  {},
+	"state":     {},
+	"result":    {},
+}
+
+// rcGetBool reads key from in as a bool
+func rcGetBool(in rc.Params, key string) (bool, error) {
+	switch value := in[key].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return value, nil
+	case string:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, errors.Wrapf(err, "couldn't parse %q", key)
+		}
+		return b, nil
+	default:
+		return false, errors.Errorf("expecting bool for %q but got %T", key, value)
+	}
+}
+
+// Create or continue the config of a remote
+func rcCreate(in rc.Params) (out rc.Params, err error) {
+	name := in.GetString("name")
+	if name == "" {
+		return nil, errors.New("need name parameter")
+	}
+	if err := CheckRemoteName(name); err != nil {
+		return nil, err
+	}
+	continueConfig, err := rcGetBool(in, "continue")
+	if err != nil {
+		return nil, err
+	}
+	configIn := fs.ConfigIn{NonInteractive: true}
+	if continueConfig {
+		configIn.State = in.GetString("state")
+		configIn.Result = in.GetString("result")
+	} else {
+		fsType := in.GetString("type")
+		if fsType == "" {
+			return nil, errors.New("need type parameter")
+		}
+		if _, err := fs.Find(fsType); err != nil {
+			return nil, err
+		}
+		overwrite, err := rcGetBool(in, "overwrite")
+		if err != nil {
+			return nil, err
+		}
+		for key := range in {
+			if strings.HasSuffix(key, fs.ConfigCommandSuffix) {
+				return nil, errors.Errorf("%q can't be set with the rc - set it in the config file", key)
+			}
+		}
+		if _, err := getConfigData().GetSection(name); err == nil && !overwrite {
+			return nil, errors.Errorf("remote %q already exists - set overwrite=true to replace it", name)
+		}
+		deleteConfigSection(name)
+		setConfigValue(name, "type", fsType)
+		for key := range in {
+			if _, found := rcReservedParams[key]; !found {
+				FileSet(name, key, in.GetString(key))
+			}
+		}
+	}
+	fsType := RemoteType(name)
+	if fsType == "" {
+		return nil, errors.Errorf("couldn't find type of remote %q", name)
+	}
+	ri, err := fs.Find(fsType)
+	if err != nil {
+		return nil, err
+	}
+	configOut, err := fs.BackendConfig(name, fs.ConfigMap(ri, name), ri, configIn)
+	SaveConfig()
+	if err != nil {
+		return nil, err
+	}
+	if configOut == nil {
+		return rc.Params{"state": ""}, nil
+	}
+	return rc.Params{
+		"state":  configOut.State,
+		"option": configOut.Option,
+		"error":  configOut.Error,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBackendConfig is a config state machine for testing
+func testBackendConfig(name string, m configmap.Mapper, in fs.ConfigIn) (*fs.ConfigOut, error) {
+	switch in.State {
+	case "":
+		return fs.ConfigConfirm("confirmed", true, "config_ok", "Is it OK?")
+	case "confirmed":
+		if in.Result == "false" {
+			return nil, nil
+		}
+		return fs.ConfigInput("colour", "config_colour", "Favourite colour?")
+	case "colour":
+		m.Set("colour", in.Result)
+		return nil, nil
+	}
+	return nil, nil
+}
+
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:   "config_rc_test",
+		Config: testBackendConfig,
+	})
+}
+
+// setupTestConfig makes an empty temporary config file returning a
+// function to tidy up
+func setupTestConfig(t *testing.T) func() {
+	configKey = nil // reset password
+	tempFile, err := ioutil.TempFile("", "rc.conf")
+	require.NoError(t, err)
+	path := tempFile.Name()
+	require.NoError(t, tempFile.Close())
+
+	oldOsStdout := os.Stdout
+	oldConfigPath := ConfigPath
+	oldConfig := fs.Config
+	oldConfigFile := configFile
+	oldReadLine := ReadLine
+	os.Stdout = nil
+	ConfigPath = path
+	fs.Config = &fs.ConfigInfo{}
+	configFile = nil
+	LoadConfig()
+	return func() {
+		os.Stdout = oldOsStdout
+		ConfigPath = oldConfigPath
+		ReadLine = oldReadLine
+		fs.Config = oldConfig
+		configFile = oldConfigFile
+		assert.NoError(t, os.Remove(path))
+		_ = os.Remove(path + ".lock")
+	}
+}
+
+func TestRcCreate(t *testing.T) {
+	defer setupTestConfig(t)()
+
+	_, err := rcCreate(rc.Params{"type": "config_rc_test"})
+	assert.Error(t, err)
+	_, err = rcCreate(rc.Params{"name": "test"})
+	assert.Error(t, err)
+	_, err = rcCreate(rc.Params{"name": "test", "type": "not a backend"})
+	assert.Error(t, err)
+	for _, name := range []string{"a,b", "a:b", "a/b"} {
+		_, err = rcCreate(rc.Params{"name": name, "type": "config_rc_test"})
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "can't use", name)
+	}
+
+	out, err := rcCreate(rc.Params{
+		"name":   "test",
+		"type":   "config_rc_test",
+		"potato": "jersey royal",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "confirmed", out["state"])
+	option := out["option"].(*fs.Option)
+	assert.Equal(t, "config_ok", option.Name)
+	assert.Equal(t, "config_rc_test", FileGet("test", "type"))
+	assert.Equal(t, "jersey royal", FileGet("test", "potato"))
+
+	out, err = rcCreate(rc.Params{
+		"name":     "test",
+		"continue": "true",
+		"state":    out["state"],
+		"result":   "true",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "colour", out["state"])
+
+	out, err = rcCreate(rc.Params{
+		"name":     "test",
+		"continue": true,
+		"state":    out["state"],
+		"result":   "blue",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"state": ""}, out)
+	assert.Equal(t, "blue", FileGet("test", "colour"))
+
+	// check the config was saved
+	configFile, err = loadConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, "blue", FileGet("test", "colour"))
+	assert.Equal(t, "jersey royal", FileGet("test", "potato"))
+
+	// An existing remote isn't replaced without overwrite
+	_, err = rcCreate(rc.Params{
+		"name": "test",
+		"type": "config_rc_test",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+	assert.Equal(t, "blue", FileGet("test", "colour"))
+	_, err = rcCreate(rc.Params{
+		"name":      "test",
+		"type":      "config_rc_test",
+		"overwrite": "potato",
+	})
+	require.Error(t, err)
+	assert.Equal(t, "blue", FileGet("test", "colour"))
+
+	_, err = rcCreate(rc.Params{
+		"name":      "test",
+		"type":      "config_rc_test",
+		"overwrite": true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "", FileGet("test", "colour"))
+	assert.Equal(t, "", FileGet("test", "overwrite"))
+
+	// Commands can't be set over the rc
+	_, err = rcCreate(rc.Params{
+		"name":           "command",
+		"type":           "config_rc_test",
+		"potato_command": "echo potato",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "potato_command")
+	assert.Equal(t, "", FileGet("command", "type"))
+}
+
+func TestPostConfig(t *testing.T) {
+	defer setupTestConfig(t)()
+	getConfigData().SetValue("test", "type", "config_rc_test")
+	ri, err := fs.Find("config_rc_test")
+	require.NoError(t, err)
+
+	i := 0
+	ReadLine = func() string {
+		answers := []string{
+			"true", // it is OK
+			"red",  // favourite colour
+		}
+		i = i + 1
+		return answers[i-1]
+	}
+	require.NoError(t, PostConfig("test", fs.ConfigMap(ri, "test"), ri))
+	assert.Equal(t, "red", FileGet("test", "colour"))
+
+	// with AutoConfirm the defaults are used which fails when
+	// there isn't one
+	fs.Config.AutoConfirm = true
+	ReadLine = func() string {
+		t.Fatal("shouldn't be asked")
+		return ""
+	}
+	err = PostConfig("test", fs.ConfigMap(ri, "test"), ri)
+	assert.EqualError(t, err, `can't answer "config_colour" automatically as it has no default`)
+}
@@ -0,0 +1,167 @@
+// Validate the config of remotes
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/pkg/errors"
+)
+
+// internalKeys are the keys stored in the config file which aren't
+// backend options
+var internalKeys = map[string]struct{}{
+	"type":            {},
+	fs.ConfigInherits: {},
+	ConfigToken:       {},
+	ConfigAutomatic:   {},
+}
+
+// fileValue reads key from the config file for the remote name,
+// taking into account the remotes it inherits from.
+func fileValue(name, key string) (value string, found bool) {
+	for _, section := range fs.InheritedSections(name) {
+		value, found = FileGetFlag(section, key)
+		value = fs.ExpandConfigValue(value)
+		if found && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// ValidateRemote checks the config file section of the remote name,
+// and the sections it inherits from, against the options of its
+// backend.
+//
+// It returns errs for problems which will stop the remote working,
+// such as a value which can't be parsed or a missing required option,
+// and warnings for things which might be mistakes, such as unknown
+// options.
+func ValidateRemote(name string) (errs []error, warnings []string) {
+	fsType := RemoteType(name)
+	if fsType == "" {
+		return []error{errors.New("type not set")}, nil
+	}
+	ri, err := fs.Find(fsType)
+	if err != nil {
+		return []error{errors.Errorf("unknown type %q", fsType)}, nil
+	}
+	options := make(map[string]*fs.Option, len(ri.Options))
+	for i := range ri.Options {
+		options[ri.Options[i].Name] = &ri.Options[i]
+	}
+
+	// Check the keys are known
+	for _, section := range fs.InheritedSections(name) {
+		if _, err := getConfigData().GetSection(section); err != nil {
+			if section != name {
+				errs = append(errs, errors.Errorf("inherits from %q which doesn't exist", section))
+			}
+			continue
+		}
+		for _, key := range getConfigData().GetKeyList(section) {
+			if _, found := internalKeys[key]; found {
+				continue
+			}
+			if _, found := options[strings.TrimSuffix(key, "_command")]; !found {
+				warnings = append(warnings, fmt.Sprintf("unknown option %q in section %q", key, section))
+			}
+		}
+	}
+
+	// Check the values of the options
+	provider, _ := fileValue(name, fs.ConfigProvider)
+	for _, o := range ri.Options {
+		if !matchProvider(o.Provider, provider) {
+			continue
+		}
+		value, found := fileValue(name, o.Name)
+		if !found {
+			_, hasCommand := fileValue(name, o.Name+"_command")
+			if o.Required && !hasCommand && o.String() == "" {
+				errs = append(errs, errors.Errorf("option %q is required but not set", o.Name))
+			}
+			continue
+		}
+		if o.IsPassword {
+			if _, err := obscure.Reveal(value); err != nil {
+				errs = append(errs, errors.Errorf("option %q: password isn't obscured - use \"rclone obscure\" to obscure it", o.Name))
+			}
+			continue
+		}
+		if o.Default != nil {
+			if _, err := configstruct.StringToInterface(o.Default, value); err != nil {
+				errs = append(errs, errors.Wrapf(err, "option %q", o.Name))
+				continue
+			}
+		}
+		if o.Exclusive && len(o.Examples) > 0 {
+			valid := false
+			for _, example := range o.Examples {
+				if example.Value == value {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				errs = append(errs, errors.Errorf("option %q: %q is not one of the allowed values", o.Name, value))
+			}
+		}
+	}
+	return errs, warnings
+}
+
+// Validate checks the config of the remotes passed in, or all the
+// remotes if none are passed in, printing any problems found.
+//
+// Remotes without a type which other remotes inherit from are
+// treated as templates and only checked as part of the remotes which
+// inherit from them.
+//
+// It returns an error if any of the remotes have errors.
+func Validate(remotes []string) error {
+	if len(remotes) == 0 {
+		remotes = FileSections()
+		sort.Strings(remotes)
+		templates := map[string]bool{}
+		for _, remote := range remotes {
+			for _, section := range fs.InheritedSections(remote)[1:] {
+				templates[section] = true
+			}
+		}
+		var nonTemplates []string
+		for _, remote := range remotes {
+			if templates[remote] && RemoteType(remote) == "" {
+				fmt.Printf("%s: template - checked with the remotes which inherit from it\n", remote)
+				continue
+			}
+			nonTemplates = append(nonTemplates, remote)
+		}
+		remotes = nonTemplates
+	}
+	failed := 0
+	for _, remote := range remotes {
+		errs, warnings := ValidateRemote(remote)
+		for _, warning := range warnings {
+			fmt.Printf("%s: WARNING: %s\n", remote, warning)
+		}
+		for _, err := range errs {
+			fmt.Printf("%s: ERROR: %v\n", remote, err)
+		}
+		if len(errs) != 0 {
+			failed++
+		} else {
+			fmt.Printf("%s: OK\n", remote)
+		}
+	}
+	if failed != 0 {
+		return errors.Errorf("%d of %d remotes failed validation", failed, len(remotes))
+	}
+	return nil
+}
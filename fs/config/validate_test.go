@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name: "config_validate_test",
+		Options: []fs.Option{{
+			Name:     "bucket",
+			Required: true,
+		}, {
+			Name:    "chunk_size",
+			Default: fs.SizeSuffix(5 * 1024 * 1024),
+		}, {
+			Name:    "retries",
+			Default: 3,
+		}, {
+			Name:       "pass",
+			IsPassword: true,
+		}, {
+			Name:      "acl",
+			Default:   "private",
+			Exclusive: true,
+			Examples: []fs.OptionExample{
+				{Value: "private"},
+				{Value: "public"},
+			},
+		}},
+	})
+}
+
+func TestValidateRemote(t *testing.T) {
+	defer setupTestConfig(t)()
+	require.NoError(t, os.Setenv("RCLONE_TEST_VALIDATE_BUCKET", "potato"))
+	defer func() { _ = os.Unsetenv("RCLONE_TEST_VALIDATE_BUCKET") }()
+
+	setValues := func(name string, values map[string]string) {
+		for key, value := range values {
+			setConfigValue(name, key, value)
+		}
+	}
+	setValues("base", map[string]string{
+		"type":       "config_validate_test",
+		"chunk_size": "10M",
+	})
+	setValues("good", map[string]string{
+		"inherits": "base",
+		"bucket":   "${RCLONE_TEST_VALIDATE_BUCKET}",
+		"retries":  "5",
+		"pass":     obscure.MustObscure("secret"),
+		"acl":      "public",
+	})
+	setValues("command", map[string]string{
+		"type":           "config_validate_test",
+		"bucket_command": "echo bucket",
+		"potato":         "unknown",
+	})
+	setValues("template", map[string]string{
+		"chunk_size": "potato",
+	})
+	setValues("bad", map[string]string{
+		"inherits": "template",
+		"type":     "config_validate_test",
+		"bucket":   "${RCLONE_TEST_VALIDATE_NOT_SET}",
+		"retries":  "many",
+		"pass":     "not obscured",
+		"acl":      "everyone",
+	})
+	setValues("missing", map[string]string{
+		"type":     "config_validate_test",
+		"inherits": "notfound",
+		"bucket":   "bucket",
+	})
+	setValues("unknown", map[string]string{
+		"type": "config_validate_test_unknown",
+	})
+	setValues("notype", map[string]string{
+		"bucket": "bucket",
+	})
+
+	errs, warnings := ValidateRemote("good")
+	assert.Len(t, errs, 0)
+	assert.Len(t, warnings, 0)
+
+	errs, warnings = ValidateRemote("command")
+	assert.Len(t, errs, 0)
+	assert.Equal(t, []string{`unknown option "potato" in section "command"`}, warnings)
+
+	errs, warnings = ValidateRemote("bad")
+	assert.Len(t, warnings, 0)
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	assert.Len(t, got, 5)
+	assert.Contains(t, got, `option "bucket" is required but not set`)
+	assert.Contains(t, got[1], `option "chunk_size"`)
+	assert.Contains(t, got[2], `option "retries"`)
+	assert.Contains(t, got[3], `option "pass"`)
+	assert.Equal(t, `option "acl": "everyone" is not one of the allowed values`, got[4])
+
+	errs, _ = ValidateRemote("missing")
+	require.Len(t, errs, 1)
+	assert.Equal(t, `inherits from "notfound" which doesn't exist`, errs[0].Error())
+
+	errs, _ = ValidateRemote("unknown")
+	require.Len(t, errs, 1)
+	assert.Equal(t, `unknown type "config_validate_test_unknown"`, errs[0].Error())
+
+	errs, _ = ValidateRemote("notype")
+	require.Len(t, errs, 1)
+	assert.Equal(t, "type not set", errs[0].Error())
+
+	err := Validate([]string{"good", "command"})
+	assert.NoError(t, err)
+	// base isn't a template as it has a type so is missing bucket
+	err = Validate(nil)
+	require.Error(t, err)
+	assert.Equal(t, "5 of 7 remotes failed validation", err.Error())
+}
@@ -0,0 +1,262 @@
+// Per remote overrides of the global config
+
+package fs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/pkg/errors"
+)
+
+// globalOverrides are the global options which can be overridden in
+// the config of a remote with ConfigGlobalPrefix, and how to set them
+var globalOverrides = map[string]func(ci *ConfigInfo, value string) error{
+	"transfers": func(ci *ConfigInfo, value string) (err error) {
+		ci.Transfers, err = strconv.Atoi(value)
+		return err
+	},
+	"checkers": func(ci *ConfigInfo, value string) (err error) {
+		ci.Checkers, err = strconv.Atoi(value)
+		return err
+	},
+	"fast_list": func(ci *ConfigInfo, value string) (err error) {
+		ci.UseListR, err = strconv.ParseBool(value)
+		return err
+	},
+	"tpslimit": func(ci *ConfigInfo, value string) (err error) {
+		ci.TPSLimit, err = strconv.ParseFloat(value, 64)
+		return err
+	},
+	"tpslimit_burst": func(ci *ConfigInfo, value string) (err error) {
+		ci.TPSLimitBurst, err = strconv.Atoi(value)
+		return err
+	},
+	"bwlimit": func(ci *ConfigInfo, value string) error {
+		var bwLimit BwTimetable
+		err := bwLimit.Set(value)
+		ci.BwLimit = bwLimit
+		return err
+	},
+	"buffer_size": func(ci *ConfigInfo, value string) error {
+		return ci.BufferSize.Set(value)
+	},
+	"timeout": func(ci *ConfigInfo, value string) (err error) {
+		ci.Timeout, err = ParseDuration(value)
+		return err
+	},
+	"contimeout": func(ci *ConfigInfo, value string) (err error) {
+		ci.ConnectTimeout, err = ParseDuration(value)
+		return err
+	},
+}
+
+// GlobalOverrides returns the sorted names of the global options
+// which can be overridden in the config of a remote.
+func GlobalOverrides() (names []string) {
+	for name := range globalOverrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// remoteConfig is a config with the global options overridden by a
+// remote
+type remoteConfig struct {
+	config     *ConfigInfo // the Config ci was made from
+	generation int64       // the value of configGeneration when ci was made
+	ci         *ConfigInfo // nil if none of the overrides were valid
+}
+
+var (
+	configGeneration int64 // incremented by ConfigChanged
+	remoteConfigsMu  sync.Mutex
+	remoteConfigs    = map[string]*remoteConfig{} // keyed by remote name and overrides
+)
+
+// ConfigChanged should be called after Config is changed once rclone
+// has started, eg to apply the options of a job, so that the configs
+// of the remotes which override global options are made again from
+// it.
+func ConfigChanged() {
+	atomic.AddInt64(&configGeneration, 1)
+}
+
+// readOverrides reads the values of the global options overridden in
+// m returning them as "key=value" in key order
+func readOverrides(m configmap.Getter) (overrides []string) {
+	for _, key := range GlobalOverrides() {
+		value, ok := m.Get(ConfigGlobalPrefix + key)
+		if ok && value != "" {
+			overrides = append(overrides, key+"="+value)
+		}
+	}
+	return overrides
+}
+
+// GetConfig returns the config to use for operations on the remote
+// name, as returned by Fs.Name(), reading its overrides from the
+// config file and the environment.
+//
+// If the config of the remote overrides any global options, eg
+// "global.tpslimit = 5", then this is a copy of Config with them
+// applied, otherwise it is Config itself.  The same copy is returned
+// until the overrides change or ConfigChanged is called, so changes
+// made by the options of a job are seen.
+//
+// Use GetConfigFs for an Fs as its connection string may override
+// global options too.
+func GetConfig(name string) *ConfigInfo {
+	if name == "" {
+		return Config
+	}
+	return GetConfigMap(name, ConfigMap(nil, name))
+}
+
+// GetConfigMap returns the config to use for operations on the remote
+// name whose config is m, as passed to the NewFs of its backend, so
+// that overrides in its connection string are used too.
+//
+// See GetConfig for details.
+func GetConfigMap(name string, m configmap.Getter) *ConfigInfo {
+	overrides := readOverrides(m)
+	if len(overrides) == 0 {
+		return Config
+	}
+	key := name + "\x00" + strings.Join(overrides, "\x00")
+	remoteConfigsMu.Lock()
+	defer remoteConfigsMu.Unlock()
+	generation := atomic.LoadInt64(&configGeneration)
+	rc, found := remoteConfigs[key]
+	if !found || rc.config != Config || rc.generation != generation {
+		rc = &remoteConfig{config: Config, generation: generation}
+		for _, override := range overrides {
+			i := strings.IndexRune(override, '=')
+			option, value := override[:i], override[i+1:]
+			newCi := *Config
+			if rc.ci != nil {
+				newCi = *rc.ci
+			}
+			err := globalOverrides[option](&newCi, value)
+			if err != nil {
+				Errorf(name, "Ignoring bad value for %s%s: %v", ConfigGlobalPrefix, option, errors.Wrapf(err, "%q", value))
+				continue
+			}
+			Debugf(name, "Overriding global %s with %q", option, value)
+			newCi.remote = key
+			rc.ci = &newCi
+		}
+		remoteConfigs[key] = rc
+	}
+	if rc.ci == nil {
+		return Config
+	}
+	return rc.ci
+}
+
+// getConfigFs returns the config to use for operations on f
+//
+// If f was made by NewFs then the overrides are read from the config
+// it was made with, which includes its connection string.
+func getConfigFs(f Info) *ConfigInfo {
+	if m := f.Features().configMap; m != nil {
+		return GetConfigMap(f.Name(), m)
+	}
+	return GetConfig(f.Name())
+}
+
+// GetConfigFs returns the config to use for an operation involving
+// all the remotes passed in, eg a sync between two remotes.
+//
+// The overrides of each remote are read from its config, including
+// its connection string if it was made with NewFs.
+//
+// If only one of the remotes overrides global options then its config
+// is used.  If more than one does then their overrides are merged as
+// described in mergeOverrides.
+func GetConfigFs(remotes ...Info) *ConfigInfo {
+	var cis []*ConfigInfo
+	for _, f := range remotes {
+		if f == nil {
+			continue
+		}
+		if ci := getConfigFs(f); ci != Config && (len(cis) == 0 || ci != cis[0]) {
+			cis = append(cis, ci)
+		}
+	}
+	switch len(cis) {
+	case 0:
+		return Config
+	case 1:
+		return cis[0]
+	}
+	newCi := *cis[0]
+	newCi.remote = ""
+	for _, ci := range cis[1:] {
+		mergeOverrides(&newCi, ci)
+	}
+	return &newCi
+}
+
+// mergeOverrides merges the global options overridden in ci into
+// merged, a copy of the config of another remote, so the result suits
+// both remotes.
+//
+// Where only ci overrides an option its value is used.  Where both do
+// the most restrictive value is used, so
+//
+//	transfers, checkers, tpslimit_burst, buffer_size  the lowest
+//	tpslimit, bwlimit  the lowest limit now, not counting off
+//	fast_list          only if both set it
+//	timeout, contimeout  the longest
+//
+// The bandwidth limit, buffer size and timeouts of each remote are
+// still used for its own transfers and connections.
+func mergeOverrides(merged, ci *ConfigInfo) {
+	if ci.Transfers != Config.Transfers && (merged.Transfers == Config.Transfers || ci.Transfers < merged.Transfers) {
+		merged.Transfers = ci.Transfers
+	}
+	if ci.Checkers != Config.Checkers && (merged.Checkers == Config.Checkers || ci.Checkers < merged.Checkers) {
+		merged.Checkers = ci.Checkers
+	}
+	if ci.TPSLimitBurst != Config.TPSLimitBurst && (merged.TPSLimitBurst == Config.TPSLimitBurst || ci.TPSLimitBurst < merged.TPSLimitBurst) {
+		merged.TPSLimitBurst = ci.TPSLimitBurst
+	}
+	if ci.BufferSize != Config.BufferSize && (merged.BufferSize == Config.BufferSize || ci.BufferSize < merged.BufferSize) {
+		merged.BufferSize = ci.BufferSize
+	}
+	if ci.TPSLimit != Config.TPSLimit && (merged.TPSLimit == Config.TPSLimit || lowerLimit(float64(ci.TPSLimit), float64(merged.TPSLimit))) {
+		merged.TPSLimit = ci.TPSLimit
+	}
+	if ci.BwLimit.String() != Config.BwLimit.String() {
+		now := time.Now()
+		if merged.BwLimit.String() == Config.BwLimit.String() || lowerLimit(float64(ci.BwLimit.LimitAt(now).Bandwidth), float64(merged.BwLimit.LimitAt(now).Bandwidth)) {
+			merged.BwLimit = ci.BwLimit
+		}
+	}
+	if ci.UseListR != Config.UseListR {
+		if merged.UseListR == Config.UseListR {
+			merged.UseListR = ci.UseListR
+		} else {
+			merged.UseListR = merged.UseListR && ci.UseListR
+		}
+	}
+	if ci.Timeout != Config.Timeout && (merged.Timeout == Config.Timeout || ci.Timeout > merged.Timeout) {
+		merged.Timeout = ci.Timeout
+	}
+	if ci.ConnectTimeout != Config.ConnectTimeout && (merged.ConnectTimeout == Config.ConnectTimeout || ci.ConnectTimeout > merged.ConnectTimeout) {
+		merged.ConnectTimeout = ci.ConnectTimeout
+	}
+}
+
+// lowerLimit returns true if the rate limit a is lower than b where a
+// limit of 0 or less is off
+func lowerLimit(a, b float64) bool {
+	return a > 0 && (b <= 0 || a < b)
+}
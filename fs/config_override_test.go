@@ -0,0 +1,172 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+)
+
+// testInfo is a minimal Info for testing GetConfigFs
+type testInfo string
+
+func (f testInfo) Name() string             { return string(f) }
+func (f testInfo) Root() string             { return "" }
+func (f testInfo) String() string           { return string(f) }
+func (f testInfo) Precision() time.Duration { return time.Second }
+func (f testInfo) Hashes() hash.Set         { return hash.Set(hash.None) }
+func (f testInfo) Features() *Features      { return &Features{} }
+
+// testFeaturesInfo is a testInfo with its own Features
+type testFeaturesInfo struct {
+	testInfo
+	features Features
+}
+
+func (f *testFeaturesInfo) Features() *Features { return &f.features }
+
+func TestGetConfig(t *testing.T) {
+	oldConfigFileGet := ConfigFileGet
+	defer func() { ConfigFileGet = oldConfigFileGet }()
+	configFile := map[string]map[string]string{
+		"override-slow": {
+			"global.transfers":   "2",
+			"global.checkers":    "3",
+			"global.tpslimit":    "5",
+			"global.bwlimit":     "1M",
+			"global.fast_list":   "true",
+			"global.buffer_size": "1k",
+			"global.timeout":     "10s",
+			"global.contimeout":  "5s",
+		},
+		"override-fast": {
+			"global.transfers": "32",
+			"global.checkers":  "1",
+		},
+		"override-other": {
+			"global.transfers":   "8",
+			"global.tpslimit":    "2",
+			"global.bwlimit":     "2M",
+			"global.buffer_size": "4k",
+			"global.timeout":     "20s",
+			"global.contimeout":  "1s",
+		},
+		"override-bad": {
+			"global.transfers": "potato",
+			"global.checkers":  "7",
+		},
+		"override-none": {
+			"type": "local",
+		},
+	}
+	ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := configFile[section][key]
+		return value, ok
+	}
+
+	assert.True(t, Config == GetConfig(""))
+	assert.True(t, Config == GetConfig("override-none"))
+
+	ci := GetConfig("override-slow")
+	assert.False(t, Config == ci)
+	assert.True(t, ci == GetConfig("override-slow"), "should be cached")
+	assert.Equal(t, 2, ci.Transfers)
+	assert.Equal(t, 3, ci.Checkers)
+	assert.Equal(t, 5.0, ci.TPSLimit)
+	assert.Equal(t, SizeSuffix(1024*1024), ci.BwLimit.LimitAt(time.Now()).Bandwidth)
+	assert.Equal(t, true, ci.UseListR)
+	assert.Equal(t, SizeSuffix(1024), ci.BufferSize)
+	assert.Equal(t, 10*time.Second, ci.Timeout)
+	assert.Equal(t, 5*time.Second, ci.ConnectTimeout)
+	assert.Equal(t, Config.LowLevelRetries, ci.LowLevelRetries)
+
+	// bad values are ignored
+	ci = GetConfig("override-bad")
+	assert.Equal(t, Config.Transfers, ci.Transfers)
+	assert.Equal(t, 7, ci.Checkers)
+
+	// combining remotes
+	assert.True(t, Config == GetConfigFs(testInfo("override-none"), nil))
+	assert.True(t, GetConfig("override-fast") == GetConfigFs(testInfo("override-fast"), testInfo("override-none")))
+	ci = GetConfigFs(testInfo("override-fast"), testInfo("override-slow"))
+	assert.Equal(t, 2, ci.Transfers)
+	assert.Equal(t, 1, ci.Checkers)
+	assert.Equal(t, 32, GetConfig("override-fast").Transfers, "shouldn't modify the originals")
+	assert.Equal(t, "", ci.RemoteKey())
+
+	// options only one remote overrides are kept
+	assert.Equal(t, 5.0, ci.TPSLimit)
+	assert.Equal(t, SizeSuffix(1024*1024), ci.BwLimit.LimitAt(time.Now()).Bandwidth)
+	assert.Equal(t, true, ci.UseListR)
+	assert.Equal(t, SizeSuffix(1024), ci.BufferSize)
+	assert.Equal(t, 10*time.Second, ci.Timeout)
+	assert.Equal(t, 5*time.Second, ci.ConnectTimeout)
+
+	// options both remotes override are merged
+	ci = GetConfigFs(testInfo("override-other"), testInfo("override-slow"))
+	assert.Equal(t, 2, ci.Transfers)
+	assert.Equal(t, 3, ci.Checkers)
+	assert.Equal(t, 2.0, ci.TPSLimit)
+	assert.Equal(t, SizeSuffix(1024*1024), ci.BwLimit.LimitAt(time.Now()).Bandwidth)
+	assert.Equal(t, true, ci.UseListR)
+	assert.Equal(t, SizeSuffix(1024), ci.BufferSize)
+	assert.Equal(t, 20*time.Second, ci.Timeout)
+	assert.Equal(t, 5*time.Second, ci.ConnectTimeout)
+}
+
+func TestGetConfigChanges(t *testing.T) {
+	oldConfigFileGet := ConfigFileGet
+	oldConfig := Config
+	defer func() {
+		ConfigFileGet = oldConfigFileGet
+		Config = oldConfig
+	}()
+	newConfig := *Config
+	Config = &newConfig
+	configFile := map[string]map[string]string{
+		"override-change": {
+			"global.transfers": "2",
+		},
+		"override-none": {
+			"type": "local",
+		},
+	}
+	ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := configFile[section][key]
+		return value, ok
+	}
+
+	ci := GetConfig("override-change")
+	assert.Equal(t, 2, ci.Transfers)
+
+	// changes to the global config are seen
+	Config.Checkers = 42
+	assert.True(t, ci == GetConfig("override-change"), "should be cached until ConfigChanged is called")
+	ConfigChanged()
+	ci2 := GetConfig("override-change")
+	assert.False(t, ci == ci2)
+	assert.Equal(t, 2, ci2.Transfers)
+	assert.Equal(t, 42, ci2.Checkers)
+	assert.True(t, ci2 == GetConfig("override-change"), "should be cached")
+
+	// changes to the config file are seen
+	configFile["override-change"]["global.transfers"] = "3"
+	assert.Equal(t, 3, GetConfig("override-change").Transfers)
+	delete(configFile["override-change"], "global.transfers")
+	assert.True(t, Config == GetConfig("override-change"))
+
+	// overrides in a connection string are used
+	assert.Equal(t, 4, GetConfig("override-none,global.transfers=4").Transfers)
+	m := ConfigMap(nil, "override-none,global.transfers=5")
+	assert.Equal(t, 5, GetConfigMap("override-none", m).Transfers)
+
+	// and read from the config the Fs was made with
+	f := &testFeaturesInfo{testInfo: "override-none{1a2b3c4d}"}
+	assert.True(t, Config == GetConfigFs(f))
+	f.features.configMap = ConfigMap(nil, "override-none")
+	assert.True(t, Config == GetConfigFs(f))
+	f.features.configMap = m
+	assert.Equal(t, 5, GetConfigFs(f).Transfers)
+	assert.Equal(t, "override-none{1a2b3c4d}\x00transfers=5", GetConfigFs(f).RemoteKey())
+}
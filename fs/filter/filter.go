@@ -269,11 +269,10 @@ func (f *Filter) Add(Include bool, glob string) error {
 //
 // These are
 //
-//   + glob
-//   - glob
-//   !
-//
-// '+' includes the glob, '-' excludes it and '!' resets the filter list
+//	rule    meaning
+//	+ glob  include the glob
+//	- glob  exclude the glob
+//	!       reset the filter list
 //
 // Line comments may be introduced with '#' or ';'
 func (f *Filter) AddRule(rule string) error {
@@ -438,6 +437,19 @@ func (f *Filter) Include(remote string, size int64, modTime time.Time) bool {
 	return f.includeRemote(remote)
 }
 
+// IncludeRemote returns whether this remote passes the filter rules
+// and --files-from.  Unlike Include it doesn't apply the size and
+// modification time filters so it can be used for paths which don't
+// have an object.
+func (f *Filter) IncludeRemote(remote string) bool {
+	// filesFrom takes precedence
+	if f.files != nil {
+		_, include := f.files[remote]
+		return include
+	}
+	return f.includeRemote(remote)
+}
+
 // IncludeObject returns whether this object should be included into
 // the sync or not. This is a convenience function to avoid calling
 // o.ModTime(), which is an expensive operation.
@@ -151,6 +151,20 @@ func TestNewFilterIncludeFiles(t *testing.T) {
 	assert.False(t, f.InActive())
 }
 
+func TestFilterIncludeRemote(t *testing.T) {
+	f, err := NewFilter(nil)
+	require.NoError(t, err)
+	require.NoError(t, f.Add(false, "*.jpg"))
+	f.Opt.MinSize = 100
+	assert.True(t, f.IncludeRemote("file.png"))
+	assert.False(t, f.IncludeRemote("file.jpg"))
+
+	err = f.AddFile("file1.png")
+	require.NoError(t, err)
+	assert.True(t, f.IncludeRemote("file1.png"))
+	assert.False(t, f.IncludeRemote("file2.png"))
+}
+
 func TestNewFilterIncludeFilesDirs(t *testing.T) {
 	f, err := NewFilter(nil)
 	require.NoError(t, err)
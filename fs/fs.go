@@ -10,12 +10,14 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/fspath"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/pkg/errors"
@@ -63,6 +65,7 @@ var (
 	ErrorDirectoryNotEmpty           = errors.New("directory not empty")
 	ErrorImmutableModified           = errors.New("immutable file modified")
 	ErrorPermissionDenied            = errors.New("permission denied")
+	ErrorCommandNotFound             = errors.New("command not found")
 )
 
 // RegInfo provides information about a filesystem
@@ -77,10 +80,23 @@ type RegInfo struct {
 	// object, then it should return a Fs which which points to
 	// the parent of that object and ErrorIsFile.
 	NewFs func(name string, root string, config configmap.Mapper) (Fs, error) `json:"-"`
-	// Function to call to help with config
-	Config func(name string, config configmap.Mapper) `json:"-"`
+	// Function to call to help with config - see ConfigIn and
+	// ConfigOut for how it is driven
+	Config func(name string, config configmap.Mapper, in ConfigIn) (*ConfigOut, error) `json:"-"`
 	// Options for the Fs configuration
 	Options Options
+	// The command help, if any
+	CommandHelp []CommandHelp
+}
+
+// CommandHelp describes a single backend Command
+//
+// These are automatically inserted in the docs
+type CommandHelp struct {
+	Name  string            // Name of the command, eg "restore"
+	Short string            // Single line description
+	Long  string            // Long multi-line description
+	Opts  map[string]string // maps option name to a single line help
 }
 
 // Options is a slice of configuration Option for a backend
@@ -123,6 +139,7 @@ type Option struct {
 	IsPassword bool             // set if the option is a password
 	NoPrefix   bool             // set if the option for this should not use the backend prefix
 	Advanced   bool             // set if this is an advanced config option
+	Exclusive  bool             // set if the answer can only be one of the examples
 }
 
 // Gets the current current value which is the default if not set
@@ -474,6 +491,28 @@ type Features struct {
 
 	// About gets quota information from the Fs
 	About func() (*Usage, error)
+
+	// OpenWriterAt opens with a handle for random access writes
+	//
+	// Pass in the remote desired and the size if known.
+	//
+	// It truncates any existing object
+	OpenWriterAt func(remote string, size int64) (WriterAtCloser, error)
+
+	// Command the backend to run a named command
+	//
+	// The command run is name
+	// args may be used to read arguments from
+	// opts may be used to read optional arguments from
+	//
+	// The result should be capable of being JSON encoded
+	// If it is a string or a []string it will be shown to the user
+	// otherwise it will be JSON encoded and shown to the user like that
+	Command func(name string, arg []string, opt map[string]string) (interface{}, error)
+
+	// the config the Fs was made with by NewFs, used by
+	// GetConfigFs to read the global options it overrides
+	configMap configmap.Getter
 }
 
 // Disable nil's out the named feature.  If it isn't found then it
@@ -482,6 +521,9 @@ func (ft *Features) Disable(name string) *Features {
 	v := reflect.ValueOf(ft).Elem()
 	vType := v.Type()
 	for i := 0; i < v.NumField(); i++ {
+		if vType.Field(i).PkgPath != "" {
+			continue // not exported so not a feature
+		}
 		vName := vType.Field(i).Name
 		field := v.Field(i)
 		if strings.EqualFold(name, vName) {
@@ -502,11 +544,37 @@ func (ft *Features) List() (out []string) {
 	v := reflect.ValueOf(ft).Elem()
 	vType := v.Type()
 	for i := 0; i < v.NumField(); i++ {
+		if vType.Field(i).PkgPath != "" {
+			continue // not exported so not a feature
+		}
 		out = append(out, vType.Field(i).Name)
 	}
 	return out
 }
 
+// Enabled returns a map of feature names to whether they are
+// enabled or not
+func (ft *Features) Enabled() (features map[string]bool) {
+	v := reflect.ValueOf(ft).Elem()
+	vType := v.Type()
+	features = make(map[string]bool, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if vType.Field(i).PkgPath != "" {
+			continue // not exported so not a feature
+		}
+		vName := vType.Field(i).Name
+		field := v.Field(i)
+		if field.Kind() == reflect.Func {
+			// Can't compare functions
+			features[vName] = !field.IsNil()
+		} else {
+			zero := reflect.Zero(field.Type())
+			features[vName] = field.Interface() != zero.Interface()
+		}
+	}
+	return features
+}
+
 // DisableList nil's out the comma separated list of named features.
 // If it isn't found then it will log a message.
 func (ft *Features) DisableList(list []string) *Features {
@@ -566,6 +634,12 @@ func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(Abouter); ok {
 		ft.About = do.About
 	}
+	if do, ok := f.(OpenWriterAter); ok {
+		ft.OpenWriterAt = do.OpenWriterAt
+	}
+	if do, ok := f.(Commander); ok {
+		ft.Command = do.Command
+	}
 	return ft.DisableList(Config.DisableFeatures)
 }
 
@@ -622,6 +696,12 @@ func (ft *Features) Mask(f Fs) *Features {
 	if mask.About == nil {
 		ft.About = nil
 	}
+	if mask.OpenWriterAt == nil {
+		ft.OpenWriterAt = nil
+	}
+	if mask.Command == nil {
+		ft.Command = nil
+	}
 	return ft.DisableList(Config.DisableFeatures)
 }
 
@@ -815,6 +895,39 @@ type Abouter interface {
 	About() (*Usage, error)
 }
 
+// OpenWriterAter is an optional interface for Fs
+type OpenWriterAter interface {
+	// OpenWriterAt opens with a handle for random access writes
+	//
+	// Pass in the remote desired and the size if known.
+	//
+	// It truncates any existing object
+	OpenWriterAt(remote string, size int64) (WriterAtCloser, error)
+}
+
+// Commander is an interface to wrap the Command function
+type Commander interface {
+	// Command the backend to run a named command
+	//
+	// The command run is name
+	// args may be used to read arguments from
+	// opts may be used to read optional arguments from
+	//
+	// The result should be capable of being JSON encoded
+	// If it is a string or a []string it will be shown to the user
+	// otherwise it will be JSON encoded and shown to the user like that
+	//
+	// If the command isn't known it should return
+	// ErrorCommandNotFound
+	Command(name string, arg []string, opt map[string]string) (interface{}, error)
+}
+
+// WriterAtCloser wraps io.WriterAt and io.Closer
+type WriterAtCloser interface {
+	io.WriterAt
+	io.Closer
+}
+
 // ObjectsChan is a channel of Objects
 type ObjectsChan chan Object
 
@@ -854,15 +967,34 @@ func MustFind(name string) *RegInfo {
 
 // ParseRemote deconstructs a path into configName, fsPath, looking up
 // the fsName in the config file (returning NotFoundInConfigFile if not found)
+//
+// If the configName starts with a : then it names the backend to use
+// directly, eg ":s3:bucket", and the config file isn't consulted.
+//
+// Any connection string parameters, eg "remote,key=value:path", are
+// left in configName so it can be used to make a new remote path.
 func ParseRemote(path string) (fsInfo *RegInfo, configName, fsPath string, err error) {
-	configName, fsPath = fspath.Parse(path)
+	configName, fsPath, err = fspath.Parse(path)
+	if err != nil {
+		return nil, "", "", err
+	}
 	var fsName string
 	var ok bool
 	if configName != "" {
-		m := ConfigMap(nil, configName)
-		fsName, ok = m.Get("type")
-		if !ok {
-			return nil, "", "", ErrorNotFoundInConfigFile
+		name, params := fspath.ParseConfigName(configName)
+		for key := range params {
+			if strings.HasSuffix(key, ConfigCommandSuffix) {
+				return nil, "", "", errors.Errorf("%q can't be set in a connection string - set it in the config file", key)
+			}
+		}
+		if strings.HasPrefix(name, ":") {
+			fsName = name[1:]
+		} else {
+			m := ConfigMap(nil, name)
+			fsName, ok = m.Get("type")
+			if !ok {
+				return nil, "", "", ErrorNotFoundInConfigFile
+			}
 		}
 	} else {
 		fsName = "local"
@@ -929,7 +1061,89 @@ func (section getConfigFile) Get(key string) (value string, ok bool) {
 	if value == "" {
 		ok = false
 	}
-	return value, ok
+	return ExpandConfigValue(value), ok
+}
+
+// matchConfigEnvVar matches ${NAME} in config values
+var matchConfigEnvVar = regexp.MustCompile(`\$\{(\w+)\}`)
+
+// ExpandConfigValue expands any ${NAME} in the config value passed in
+// with the value of the environment variable NAME, or "" if it isn't
+// set.
+func ExpandConfigValue(value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return matchConfigEnvVar.ReplaceAllStringFunc(value, func(match string) string {
+		return os.Getenv(match[2 : len(match)-1])
+	})
+}
+
+// InheritedSections returns the config file sections the config of
+// the remote name is read from.  This is name followed by the remote
+// named in its "inherits" key, if any, then the remote that inherits
+// from and so on.
+func InheritedSections(name string) (sections []string) {
+	seen := map[string]bool{}
+	for name != "" {
+		if seen[name] {
+			Errorf(nil, "Config for remote %q inherits from itself via %q - ignoring", sections[0], name)
+			break
+		}
+		seen[name] = true
+		sections = append(sections, name)
+		name, _ = ConfigFileGet(name, ConfigInherits)
+		name = strings.TrimSuffix(strings.TrimSpace(name), ":")
+	}
+	return sections
+}
+
+// ConfigCommandSuffix is the suffix of a config key whose value is a
+// command to run to read the config item without the suffix.
+const ConfigCommandSuffix = "_command"
+
+// A configmap.Getter to read a config item from the output of the
+// command in the key_command config item, if set.
+//
+// The key_command is only read from the config file sections as
+// running a command from a connection string or an rc parameter
+// would let anyone who can pass in a remote run arbitrary commands.
+type commandValues struct {
+	fsInfo   *RegInfo
+	sections []string
+}
+
+// Get a config item by running its command if possible
+func (c *commandValues) Get(key string) (value string, ok bool) {
+	if strings.HasSuffix(key, ConfigCommandSuffix) {
+		return "", false
+	}
+	var command string
+	for _, section := range c.sections {
+		command, ok = getConfigFile(section).Get(key + ConfigCommandSuffix)
+		if ok {
+			break
+		}
+	}
+	if !ok || command == "" {
+		return "", false
+	}
+	value, err := CommandOutput(command)
+	if err != nil {
+		Errorf(nil, "Failed to read config %q: %v", key, err)
+		return "", false
+	}
+	// Passwords are stored obscured so obscure this one too
+	for i := range c.fsInfo.Options {
+		if option := &c.fsInfo.Options[i]; option.Name == key && option.IsPassword {
+			value, err = obscure.Obscure(value)
+			if err != nil {
+				Errorf(nil, "Failed to obscure config %q: %v", key, err)
+				return "", false
+			}
+		}
+	}
+	return value, true
 }
 
 // ConfigMap creates a configmap.Map from the *RegInfo and the
@@ -937,12 +1151,34 @@ func (section getConfigFile) Get(key string) (value string, ok bool) {
 //
 // If fsInfo is nil then the returned configmap.Map should only be
 // used for reading non backend specific parameters, such as "type".
+//
+// The configName may contain connection string parameters, eg
+// "remote,key=value", which override all the other sources.  If it
+// starts with a : then the config file isn't read or written.
+//
+// If a key isn't set by parameters, flags or environment variables
+// but key_command is set in the config file, eg
+// "secret_access_key_command", then the key is read from the output
+// of that command.
+//
+// Keys not in the config file section of the remote are read from the
+// remote named in its "inherits" key, if set.  Any ${NAME} in values
+// read from the config file are replaced with the environment
+// variable NAME.
 func ConfigMap(fsInfo *RegInfo, configName string) (config *configmap.Map) {
+	configName, params := fspath.ParseConfigName(configName)
+	onTheFly := strings.HasPrefix(configName, ":")
+
 	// Create the config
 	config = configmap.New()
 
 	// Read the config, more specific to least specific
 
+	// connection string parameters
+	if params != nil {
+		config.AddGetter(params)
+	}
+
 	// flag values
 	if fsInfo != nil {
 		config.AddGetter(&regInfoValues{fsInfo, false})
@@ -956,8 +1192,17 @@ func ConfigMap(fsInfo *RegInfo, configName string) (config *configmap.Map) {
 		config.AddGetter(optionEnvVars(fsInfo.Prefix))
 	}
 
-	// config file
-	config.AddGetter(getConfigFile(configName))
+	// output of key_command and the config file and the remotes
+	// it inherits from
+	if !onTheFly {
+		sections := InheritedSections(configName)
+		if fsInfo != nil {
+			config.AddGetter(&commandValues{fsInfo, sections})
+		}
+		for _, section := range sections {
+			config.AddGetter(getConfigFile(section))
+		}
+	}
 
 	// default values
 	if fsInfo != nil {
@@ -965,7 +1210,9 @@ func ConfigMap(fsInfo *RegInfo, configName string) (config *configmap.Map) {
 	}
 
 	// Set Config
-	config.AddSetter(setConfigFile(configName))
+	if !onTheFly {
+		config.AddSetter(setConfigFile(configName))
+	}
 	return config
 }
 
@@ -975,6 +1222,11 @@ func ConfigMap(fsInfo *RegInfo, configName string) (config *configmap.Map) {
 //
 // Remotes are looked up in the config file.  If the remote isn't
 // found then NotFoundInConfigFile will be returned.
+//
+// The connection string parameters are included in config so the
+// configName returned has them replaced with a short hash of them, eg
+// "remote{1a2b3c4d}".  This is the name the Fs reports so remotes made
+// with different parameters aren't treated as the same remote.
 func ConfigFs(path string) (fsInfo *RegInfo, configName, fsPath string, config *configmap.Map, err error) {
 	// Parse the remote path
 	fsInfo, configName, fsPath, err = ParseRemote(path)
@@ -982,6 +1234,7 @@ func ConfigFs(path string) (fsInfo *RegInfo, configName, fsPath string, config *
 		return
 	}
 	config = ConfigMap(fsInfo, configName)
+	configName = fspath.ParamsConfigName(fspath.ParseConfigName(configName))
 	return
 }
 
@@ -992,6 +1245,11 @@ func ConfigFs(path string) (fsInfo *RegInfo, configName, fsPath string, config *
 // Remotes are looked up in the config file.  If the remote isn't
 // found then NotFoundInConfigFile will be returned.
 //
+// The path may also be of the form :backend:path to use a backend
+// without a config file entry, and the remote name may be followed
+// by connection string parameters to override the config, eg
+// "remote,chunk_size=10M:path" or ":s3,provider=Minio:bucket".
+//
 // On Windows avoid single character remote names as they can be mixed
 // up with drive letters.
 func NewFs(path string) (Fs, error) {
@@ -999,7 +1257,11 @@ func NewFs(path string) (Fs, error) {
 	if err != nil {
 		return nil, err
 	}
-	return fsInfo.NewFs(configName, fsPath, config)
+	f, err := fsInfo.NewFs(configName, fsPath, config)
+	if f != nil {
+		f.Features().configMap = config
+	}
+	return f, err
 }
 
 // TemporaryLocalFs creates a local FS in the OS's temporary directory.
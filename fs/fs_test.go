@@ -1,11 +1,16 @@
 package fs
 
 import (
+	"os"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFeaturesDisable(t *testing.T) {
@@ -70,3 +75,206 @@ func TestOption(t *testing.T) {
 	err = d.Set("sdfsdf")
 	assert.Error(t, err)
 }
+
+func TestConfigMapConnectionString(t *testing.T) {
+	oldConfigFileGet := ConfigFileGet
+	defer func() { ConfigFileGet = oldConfigFileGet }()
+	ConfigFileGet = func(section, key string) (string, bool) {
+		if section == "remote" && (key == "a" || key == "b") {
+			return "file-" + key, true
+		}
+		return "", false
+	}
+	fsInfo := &RegInfo{
+		Name: "potato",
+		Options: []Option{
+			{Name: "a", Default: ""},
+			{Name: "b", Default: ""},
+			{Name: "c", Default: "default-c"},
+		},
+	}
+
+	get := func(m configmap.Getter, key string) string {
+		value, _ := m.Get(key)
+		return value
+	}
+
+	m := ConfigMap(fsInfo, "remote")
+	assert.Equal(t, "file-a", get(m, "a"))
+	assert.Equal(t, "file-b", get(m, "b"))
+	assert.Equal(t, "default-c", get(m, "c"))
+
+	m = ConfigMap(fsInfo, `remote,a=param-a,c="param,c"`)
+	assert.Equal(t, "param-a", get(m, "a"))
+	assert.Equal(t, "file-b", get(m, "b"))
+	assert.Equal(t, "param,c", get(m, "c"))
+
+	// on the fly backends don't read the config file
+	m = ConfigMap(fsInfo, ":remote,a=param-a")
+	assert.Equal(t, "param-a", get(m, "a"))
+	assert.Equal(t, "", get(m, "b"))
+	assert.Equal(t, "default-c", get(m, "c"))
+}
+
+func TestConfigMapCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test needs echo")
+	}
+	oldConfigFileGet := ConfigFileGet
+	defer func() { ConfigFileGet = oldConfigFileGet }()
+	configFile := map[string]string{
+		"user":         "file-user",
+		"user_command": "echo command-user",
+		"pass_command": `echo "secret password"`,
+		"bad_command":  "/this/command/does/not/exist",
+		"bad":          "file-bad",
+	}
+	ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := configFile[key]
+		return value, ok
+	}
+	fsInfo := &RegInfo{
+		Name: "potato",
+		Options: []Option{
+			{Name: "user", Default: ""},
+			{Name: "pass", Default: "", IsPassword: true},
+			{Name: "bad", Default: ""},
+		},
+	}
+
+	m := ConfigMap(fsInfo, "remote")
+	value, ok := m.Get("user")
+	assert.True(t, ok)
+	assert.Equal(t, "command-user", value)
+
+	// passwords are obscured
+	value, ok = m.Get("pass")
+	assert.True(t, ok)
+	assert.Equal(t, "secret password", obscure.MustReveal(value))
+
+	// falls back to the config file if the command fails
+	value, ok = m.Get("bad")
+	assert.True(t, ok)
+	assert.Equal(t, "file-bad", value)
+
+	// connection string parameters override the command
+	m = ConfigMap(fsInfo, "remote,user=param-user")
+	value, _ = m.Get("user")
+	assert.Equal(t, "param-user", value)
+
+	// commands are only read from the config file
+	configFile = map[string]string{}
+	envKey := ConfigToEnv("remote", "bad_command")
+	require.NoError(t, os.Setenv(envKey, "/this/command/does/not/exist"))
+	defer func() {
+		assert.NoError(t, os.Unsetenv(envKey))
+	}()
+	for _, configName := range []string{
+		"remote",
+		`remote,user_command="echo param-user"`,
+		`:remote,user_command="echo param-user"`,
+	} {
+		m = ConfigMap(fsInfo, configName)
+		value, _ = m.Get("user")
+		assert.Equal(t, "", value, configName)
+		value, _ = m.Get("bad")
+		assert.Equal(t, "", value, configName)
+	}
+}
+
+func TestParseRemoteCommand(t *testing.T) {
+	for _, path := range []string{
+		`:potato,user_command="touch /tmp/pwned":path`,
+		`remote,pass_command='echo secret':path`,
+		`:potato,foo_command=true:`,
+	} {
+		_, _, _, err := ParseRemote(path)
+		require.Error(t, err, path)
+		assert.Contains(t, err.Error(), "_command", path)
+	}
+}
+
+func TestFeaturesEnabled(t *testing.T) {
+	ft := new(Features)
+	ft.CaseInsensitive = true
+	ft.Command = func(name string, arg []string, opt map[string]string) (interface{}, error) {
+		return nil, nil
+	}
+	enabled := ft.Enabled()
+	assert.Equal(t, len(ft.List()), len(enabled))
+	assert.True(t, enabled["CaseInsensitive"])
+	assert.True(t, enabled["Command"])
+	assert.False(t, enabled["DuplicateFiles"])
+	assert.False(t, enabled["Purge"])
+}
+
+func TestConfigMapInherits(t *testing.T) {
+	oldConfigFileGet := ConfigFileGet
+	defer func() { ConfigFileGet = oldConfigFileGet }()
+	configFile := map[string]map[string]string{
+		"remote": {
+			"inherits": "base:",
+			"user":     "remote-user",
+			"dir":      "${RCLONE_TEST_CONFIG_DIR}/files",
+		},
+		"base": {
+			"inherits": "root",
+			"user":     "base-user",
+			"region":   "base-region",
+		},
+		"root": {
+			"region":   "root-region",
+			"endpoint": "root-endpoint",
+		},
+		"loop1": {"inherits": "loop2"},
+		"loop2": {"inherits": "loop1", "user": "loop-user"},
+	}
+	ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := configFile[section][key]
+		return value, ok
+	}
+	require.NoError(t, os.Setenv("RCLONE_TEST_CONFIG_DIR", "/potato"))
+	defer func() { _ = os.Unsetenv("RCLONE_TEST_CONFIG_DIR") }()
+
+	assert.Equal(t, []string{"remote", "base", "root"}, InheritedSections("remote"))
+	assert.Equal(t, []string{"loop1", "loop2"}, InheritedSections("loop1"))
+
+	m := ConfigMap(nil, "remote")
+	for _, test := range []struct {
+		key  string
+		want string
+		ok   bool
+	}{
+		{"user", "remote-user", true},
+		{"region", "base-region", true},
+		{"endpoint", "root-endpoint", true},
+		{"dir", "/potato/files", true},
+		{"missing", "", false},
+	} {
+		value, ok := m.Get(test.key)
+		assert.Equal(t, test.ok, ok, test.key)
+		assert.Equal(t, test.want, value, test.key)
+	}
+
+	value, ok := ConfigMap(nil, "loop1").Get("user")
+	assert.True(t, ok)
+	assert.Equal(t, "loop-user", value)
+}
+
+func TestExpandConfigValue(t *testing.T) {
+	require.NoError(t, os.Setenv("RCLONE_TEST_EXPAND", "potato"))
+	defer func() { _ = os.Unsetenv("RCLONE_TEST_EXPAND") }()
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"${RCLONE_TEST_EXPAND}", "potato"},
+		{"a/${RCLONE_TEST_EXPAND}/b/${RCLONE_TEST_EXPAND}", "a/potato/b/potato"},
+		{"${RCLONE_TEST_EXPAND_NOT_SET}", ""},
+		{"$RCLONE_TEST_EXPAND", "$RCLONE_TEST_EXPAND"},
+	} {
+		assert.Equal(t, test.want, ExpandConfigValue(test.in), test.in)
+	}
+}
@@ -22,20 +22,31 @@ const (
 )
 
 var (
-	transport   http.RoundTripper
-	noTransport sync.Once
-	tpsBucket   *rate.Limiter // for limiting number of http transactions per second
+	transport          http.RoundTripper
+	noTransport        sync.Once
+	tpsBucket          *rate.Limiter // for limiting number of http transactions per second
+	remoteTransportsMu sync.Mutex
+	remoteTransports   = map[string]*Transport{} // transports for remotes which override the global config by ConfigInfo.RemoteKey
 )
 
+// newTPSBucket makes a token bucket to limit the HTTP transactions
+// per second as set in ci, or returns nil if there is no limit
+func newTPSBucket(ci *fs.ConfigInfo) *rate.Limiter {
+	if ci.TPSLimit <= 0 {
+		return nil
+	}
+	tpsBurst := ci.TPSLimitBurst
+	if tpsBurst < 1 {
+		tpsBurst = 1
+	}
+	return rate.NewLimiter(rate.Limit(ci.TPSLimit), tpsBurst)
+}
+
 // StartHTTPTokenBucket starts the token bucket if necessary
 func StartHTTPTokenBucket() {
-	if fs.Config.TPSLimit > 0 {
-		tpsBurst := fs.Config.TPSLimitBurst
-		if tpsBurst < 1 {
-			tpsBurst = 1
-		}
-		tpsBucket = rate.NewLimiter(rate.Limit(fs.Config.TPSLimit), tpsBurst)
-		fs.Infof(nil, "Starting HTTP transaction limiter: max %g transactions/s with burst %d", fs.Config.TPSLimit, tpsBurst)
+	tpsBucket = newTPSBucket(fs.Config)
+	if tpsBucket != nil {
+		fs.Infof(nil, "Starting HTTP transaction limiter: max %g transactions/s with burst %d", fs.Config.TPSLimit, tpsBucket.Burst())
 	}
 }
 
@@ -115,27 +126,54 @@ func dialContextTimeout(ctx context.Context, network, address string, ci *fs.Con
 	return newTimeoutConn(c, ci.Timeout)
 }
 
+// newHTTPTransport makes an http.Transport with the timeouts in ci
+// wrapped in our own Transport
+func newHTTPTransport(ci *fs.ConfigInfo) *Transport {
+	// Start with a sensible set of defaults then override.
+	// This also means we get new stuff when it gets added to go
+	t := new(http.Transport)
+	setDefaults(t, http.DefaultTransport.(*http.Transport))
+	t.Proxy = http.ProxyFromEnvironment
+	t.MaxIdleConnsPerHost = 2 * (ci.Checkers + ci.Transfers + 1)
+	t.MaxIdleConns = 2 * t.MaxIdleConnsPerHost
+	t.TLSHandshakeTimeout = ci.ConnectTimeout
+	t.ResponseHeaderTimeout = ci.Timeout
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: ci.InsecureSkipVerify}
+	t.DisableCompression = ci.NoGzip
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialContextTimeout(ctx, network, addr, ci)
+	}
+	t.IdleConnTimeout = 60 * time.Second
+	t.ExpectContinueTimeout = ci.ConnectTimeout
+	// Wrap that http.Transport in our own transport
+	return newTransport(ci, t)
+}
+
 // NewTransport returns an http.RoundTripper with the correct timeouts
+//
+// The transport for the global config is shared.  A remote which
+// overrides global options (see fs.GetConfig) gets its own transport
+// with its own timeouts and, if it overrides --tpslimit, its own HTTP
+// transaction limiter.  This is shared by all the Fs made with the
+// same remote name and overrides and, like the transport for the
+// global config, is made from the config the first time it is used.
 func NewTransport(ci *fs.ConfigInfo) http.RoundTripper {
-	noTransport.Do(func() {
-		// Start with a sensible set of defaults then override.
-		// This also means we get new stuff when it gets added to go
-		t := new(http.Transport)
-		setDefaults(t, http.DefaultTransport.(*http.Transport))
-		t.Proxy = http.ProxyFromEnvironment
-		t.MaxIdleConnsPerHost = 2 * (ci.Checkers + ci.Transfers + 1)
-		t.MaxIdleConns = 2 * t.MaxIdleConnsPerHost
-		t.TLSHandshakeTimeout = ci.ConnectTimeout
-		t.ResponseHeaderTimeout = ci.Timeout
-		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: ci.InsecureSkipVerify}
-		t.DisableCompression = ci.NoGzip
-		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return dialContextTimeout(ctx, network, addr, ci)
+	if key := ci.RemoteKey(); key != "" {
+		remoteTransportsMu.Lock()
+		defer remoteTransportsMu.Unlock()
+		t, found := remoteTransports[key]
+		if !found {
+			t = newHTTPTransport(ci)
+			if ci.TPSLimit != fs.Config.TPSLimit || ci.TPSLimitBurst != fs.Config.TPSLimitBurst {
+				t.ownTPSBucket = true
+				t.tpsBucket = newTPSBucket(ci)
+			}
+			remoteTransports[key] = t
 		}
-		t.IdleConnTimeout = 60 * time.Second
-		t.ExpectContinueTimeout = ci.ConnectTimeout
-		// Wrap that http.Transport in our own transport
-		transport = newTransport(ci, t)
+		return t
+	}
+	noTransport.Do(func() {
+		transport = newHTTPTransport(ci)
 	})
 	return transport
 }
@@ -155,6 +193,8 @@ type Transport struct {
 	dump          fs.DumpFlags
 	filterRequest func(req *http.Request)
 	userAgent     string
+	ownTPSBucket  bool          // if set use tpsBucket below rather than the global one
+	tpsBucket     *rate.Limiter // for limiting number of http transactions per second
 }
 
 // newTransport wraps the http.Transport passed in and logs all
@@ -255,8 +295,12 @@ func cleanAuths(buf []byte) []byte {
 // RoundTrip implements the RoundTripper interface.
 func (t *Transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
 	// Get transactions per second token first if limiting
-	if tpsBucket != nil {
-		tbErr := tpsBucket.Wait(req.Context())
+	bucket := tpsBucket
+	if t.ownTPSBucket {
+		bucket = t.tpsBucket
+	}
+	if bucket != nil {
+		tbErr := bucket.Wait(req.Context())
 		if tbErr != nil {
 			fs.Errorf(nil, "HTTP token bucket error: %v", err)
 		}
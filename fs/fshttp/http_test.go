@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -76,3 +79,33 @@ func TestCleanAuths(t *testing.T) {
 		assert.Equal(t, test.want, got, test.in)
 	}
 }
+
+func TestNewTransportRemote(t *testing.T) {
+	global := NewTransport(fs.Config)
+	assert.Equal(t, ptr(global), ptr(NewTransport(fs.Config)))
+
+	// a remote overriding the config gets its own transport
+	ci := fs.GetConfigMap("transport-test", configmap.Simple{"global.timeout": "10s"})
+	remote := NewTransport(ci)
+	assert.NotEqual(t, ptr(global), ptr(remote))
+	assert.Equal(t, ptr(remote), ptr(NewTransport(ci)))
+	tr := remote.(*Transport)
+	assert.Equal(t, 10*time.Second, tr.Transport.ResponseHeaderTimeout)
+	assert.False(t, tr.ownTPSBucket)
+
+	// which is used again when its config is remade after Config
+	// changes rather than making a new one each time
+	oldConfig := *fs.Config
+	defer func() { *fs.Config = oldConfig }()
+	fs.Config.Checkers++
+	fs.ConfigChanged()
+	ci2 := fs.GetConfigMap("transport-test", configmap.Simple{"global.timeout": "10s"})
+	assert.False(t, ci == ci2)
+	assert.Equal(t, ptr(remote), ptr(NewTransport(ci2)))
+
+	// and its own transaction limiter if it overrides --tpslimit
+	tr = NewTransport(fs.GetConfigMap("transport-test", configmap.Simple{"global.tpslimit": "5"})).(*Transport)
+	assert.NotEqual(t, ptr(remote), ptr(tr))
+	assert.True(t, tr.ownTPSBucket)
+	assert.NotNil(t, tr.tpsBucket)
+}
@@ -2,15 +2,56 @@
 package fspath
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/driveletter"
+	"github.com/pkg/errors"
 )
 
-// Matcher is a pattern to match an rclone URL
-var Matcher = regexp.MustCompile(`^([\w_ -]+):(.*)$`)
+const (
+	// remoteName matches the name of a remote
+	remoteName = `[\w_ -]+`
+	// paramValue matches the value of a connection string
+	// parameter - either quoted with " or ' with the quote doubled
+	// to include it, or unquoted without any of ,:"'/ in
+	paramValue = `"(?:[^"]|"")*"|'(?:[^']|'')*'|[^,:"'/]*`
+	// paramKey matches the key of a connection string parameter,
+	// eg "chunk_size" or "global.transfers"
+	paramKey = `[\w.]+`
+	// param matches a ,key=value connection string parameter
+	param = `,` + paramKey + `=(?:` + paramValue + `)`
+)
+
+var (
+	// Matcher is a pattern to match an rclone URL
+	//
+	// The config name may start with a : for an on the fly backend,
+	// eg ":s3", and may be followed by connection string parameters,
+	// eg "remote,chunk_size=10M,region=\"eu-west-1\"".
+	Matcher = regexp.MustCompile(`^(:?` + remoteName + `(?:` + param + `)*):(.*)$`)
+
+	// NameMatcher matches a valid remote name as stored in the
+	// config file
+	NameMatcher = regexp.MustCompile(`^` + remoteName + `$`)
+
+	// paramMatcher matches each of the connection string parameters
+	paramMatcher = regexp.MustCompile(`,(` + paramKey + `)=(` + paramValue + `)`)
+
+	// connectionStringMatcher matches the start of a remote with
+	// connection string parameters
+	connectionStringMatcher = regexp.MustCompile(`^:?` + remoteName + `,` + paramKey + `=`)
+
+	// unquotedLastParamMatcher matches a config name whose last
+	// parameter isn't quoted
+	unquotedLastParamMatcher = regexp.MustCompile(`,` + paramKey + `=[^"',]*$`)
+)
 
 // Parse deconstructs a remote path into configName and fsPath
 //
@@ -19,16 +60,91 @@ var Matcher = regexp.MustCompile(`^([\w_ -]+):(.*)$`)
 // So "remote:path/to/dir" will return "remote", "path/to/dir"
 // and "/path/to/local" will return ("", "/path/to/local")
 //
+// Any connection string parameters are returned as part of
+// configName, so "remote,key=value:path" will return
+// "remote,key=value", "path" - use ParseConfigName to split them up.
+//
+// It returns an error if the path has connection string parameters
+// with an unquoted value containing : or /, eg
+// ":s3,endpoint=http://host:bucket", as these can't be parsed
+// reliably.
+//
 // Note that this will turn \ into / in the fsPath on Windows
-func Parse(path string) (configName, fsPath string) {
+func Parse(path string) (configName, fsPath string, err error) {
 	parts := Matcher.FindStringSubmatch(path)
 	configName, fsPath = "", path
 	if parts != nil && !driveletter.IsDriveLetter(parts[1]) {
 		configName, fsPath = parts[1], parts[2]
+		// An unquoted URL, eg endpoint=http://host, ends the
+		// config name at the first : leaving // on the path
+		if strings.HasPrefix(fsPath, "//") && unquotedLastParamMatcher.MatchString(configName) {
+			return "", "", errors.Errorf("unquoted value containing \":\" in connection string %q - quote it with \" or '", path)
+		}
+	} else if connectionStringMatcher.MatchString(path) && strings.ContainsRune(path, ':') {
+		return "", "", errors.Errorf("can't parse connection string %q - quote any values containing , : / \" or ' with \" or '", path)
 	}
 	// change native directory separators to / if there are any
 	fsPath = filepath.ToSlash(fsPath)
-	return configName, fsPath
+	return configName, fsPath, nil
+}
+
+// ParseConfigName splits a configName as returned by Parse into the
+// name of the remote and the connection string parameters.
+//
+// So "remote,key=value,key2=\"quoted\"" will return "remote" and
+// {"key": "value", "key2": "quoted"}.  If there are no parameters
+// then params will be nil.
+//
+// The name of a remote made with parameters, as made by
+// ParamsConfigName, eg "remote{1a2b3c4d}", returns "remote" and nil.
+func ParseConfigName(configName string) (name string, params configmap.Simple) {
+	i := strings.IndexRune(configName, ',')
+	if i < 0 {
+		if j := strings.IndexRune(configName, '{'); j >= 0 && strings.HasSuffix(configName, "}") {
+			return configName[:j], nil
+		}
+		return configName, nil
+	}
+	name = configName[:i]
+	params = configmap.Simple{}
+	for _, match := range paramMatcher.FindAllStringSubmatch(configName[i:], -1) {
+		params[match[1]] = unquote(match[2])
+	}
+	return name, params
+}
+
+// ParamsConfigName returns the name for the remote name made with
+// the connection string parameters params.
+//
+// If there are any parameters then a short hash of them is added so
+// remotes made with different parameters have different names, eg
+// "remote{1a2b3c4d}", otherwise name is returned unchanged.
+func ParamsConfigName(name string, params configmap.Simple) string {
+	if len(params) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hasher := md5.New()
+	for _, key := range keys {
+		_, _ = hasher.Write([]byte(key + "=" + params[key] + "\x00"))
+	}
+	return name + "{" + hex.EncodeToString(hasher.Sum(nil))[:8] + "}"
+}
+
+// unquote removes the quotes from a connection string parameter value
+// if it has any
+func unquote(value string) string {
+	if len(value) >= 2 {
+		if quote := value[0]; (quote == '"' || quote == '\'') && value[len(value)-1] == quote {
+			q := string(quote)
+			return strings.Replace(value[1:len(value)-1], q+q, q, -1)
+		}
+	}
+	return value
 }
 
 // Split splits a remote into a parent and a leaf
@@ -39,8 +155,14 @@ func Parse(path string) (configName, fsPath string) {
 //
 // The returned values have the property that parent + leaf == remote
 // (except under Windows where \ will be translated into /)
+//
+// If remote can't be parsed then it is split as a local path - the
+// error is returned when parent is used to make an Fs.
 func Split(remote string) (parent string, leaf string) {
-	remoteName, remotePath := Parse(remote)
+	remoteName, remotePath, err := Parse(remote)
+	if err != nil {
+		remoteName, remotePath = "", filepath.ToSlash(remote)
+	}
 	if remoteName != "" {
 		remoteName += ":"
 	}
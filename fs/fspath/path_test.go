@@ -4,25 +4,88 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestParse(t *testing.T) {
 	for _, test := range []struct {
 		in, wantConfigName, wantFsPath string
+		wantErr                        bool
 	}{
-		{"", "", ""},
-		{"/path/to/file", "", "/path/to/file"},
-		{"path/to/file", "", "path/to/file"},
-		{"remote:path/to/file", "remote", "path/to/file"},
-		{"remote:/path/to/file", "remote", "/path/to/file"},
+		{"", "", "", false},
+		{"/path/to/file", "", "/path/to/file", false},
+		{"path/to/file", "", "path/to/file", false},
+		{"remote:path/to/file", "remote", "path/to/file", false},
+		{"remote:/path/to/file", "remote", "/path/to/file", false},
+		{":backend:path/to/file", ":backend", "path/to/file", false},
+		{"remote,a=b:path", "remote,a=b", "path", false},
+		{"remote,a=b,c_d=:path", "remote,a=b,c_d=", "path", false},
+		{"remote,global.transfers=2:path", "remote,global.transfers=2", "path", false},
+		{`:s3,provider=Minio,endpoint="http://host:9000":bucket`, `:s3,provider=Minio,endpoint="http://host:9000"`, "bucket", false},
+		{`remote,a='x,y:z':path:with:colons`, `remote,a='x,y:z'`, "path:with:colons", false},
+		{`remote,a="say ""hi""":path`, `remote,a="say ""hi"""`, "path", false},
+		{"remote,a:path", "", "remote,a:path", false},
+		{"remote,a=b/c:path", "", "", true},
+		{`remote,a="unterminated:path`, "", "", true},
+		{":s3,endpoint=http://host:bucket", "", "", true},
+		{":s3,endpoint=http://host:9000:bucket", "", "", true},
+		{"remote,a=b://path", "", "", true},
+		{`remote,a="b"://path`, `remote,a="b"`, "//path", false},
+		{"remote://path", "remote", "//path", false},
+		{"remote,a=b", "", "remote,a=b", false},
+		{"./remote,a=b:path", "", "./remote,a=b:path", false},
 	} {
-		gotConfigName, gotFsPath := Parse(test.in)
-		assert.Equal(t, test.wantConfigName, gotConfigName)
-		assert.Equal(t, test.wantFsPath, gotFsPath)
+		gotConfigName, gotFsPath, err := Parse(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			assert.NoError(t, err, test.in)
+		}
+		assert.Equal(t, test.wantConfigName, gotConfigName, test.in)
+		assert.Equal(t, test.wantFsPath, gotFsPath, test.in)
 	}
 }
 
+func TestParseConfigName(t *testing.T) {
+	for _, test := range []struct {
+		in         string
+		wantName   string
+		wantParams configmap.Simple
+	}{
+		{"", "", nil},
+		{"remote", "remote", nil},
+		{":backend", ":backend", nil},
+		{"remote,a=b", "remote", configmap.Simple{"a": "b"}},
+		{"remote,a=b,c_d=", "remote", configmap.Simple{"a": "b", "c_d": ""}},
+		{`:s3,provider=Minio,endpoint="http://host:9000"`, ":s3", configmap.Simple{"provider": "Minio", "endpoint": "http://host:9000"}},
+		{`remote,a='x,y:z',b="'"`, "remote", configmap.Simple{"a": "x,y:z", "b": "'"}},
+		{`remote,a="say ""hi""",b='it''s'`, "remote", configmap.Simple{"a": `say "hi"`, "b": "it's"}},
+		{"remote,a=1,a=2", "remote", configmap.Simple{"a": "2"}},
+		{"remote,global.transfers=2", "remote", configmap.Simple{"global.transfers": "2"}},
+		{"remote{1a2b3c4d}", "remote", nil},
+		{":s3{1a2b3c4d}", ":s3", nil},
+		{`remote,a="{x}"`, "remote", configmap.Simple{"a": "{x}"}},
+	} {
+		gotName, gotParams := ParseConfigName(test.in)
+		assert.Equal(t, test.wantName, gotName, test.in)
+		assert.Equal(t, test.wantParams, gotParams, test.in)
+	}
+}
+
+func TestParamsConfigName(t *testing.T) {
+	assert.Equal(t, "remote", ParamsConfigName("remote", nil))
+	assert.Equal(t, "remote", ParamsConfigName("remote", configmap.Simple{}))
+	a := ParamsConfigName("remote", configmap.Simple{"region": "a", "acl": "private"})
+	assert.Regexp(t, `^remote\{[0-9a-f]{8}\}$`, a)
+	assert.Equal(t, a, ParamsConfigName("remote", configmap.Simple{"acl": "private", "region": "a"}))
+	assert.NotEqual(t, a, ParamsConfigName("remote", configmap.Simple{"region": "b", "acl": "private"}))
+	assert.NotEqual(t, a, ParamsConfigName("other", configmap.Simple{"region": "a", "acl": "private"}))
+	name, params := ParseConfigName(a)
+	assert.Equal(t, "remote", name)
+	assert.Nil(t, params)
+}
+
 func TestSplit(t *testing.T) {
 	for _, test := range []struct {
 		remote, wantParent, wantLeaf string
@@ -41,6 +104,9 @@ func TestSplit(t *testing.T) {
 		{"a/b", "a/", "b"},
 		{"root/", "root/", ""},
 		{"a/b/", "a/b/", ""},
+		{":backend:a/b", ":backend:a/", "b"},
+		{`remote,a="x:y/z":a/b`, `remote,a="x:y/z":a/`, "b"},
+		{"remote,a=x/y:a/b", "remote,a=x/y:a/", "b"},
 	} {
 		gotParent, gotLeaf := Split(test.remote)
 		assert.Equal(t, test.wantParent, gotParent, test.remote)
@@ -1,8 +1,13 @@
 package fs
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"path"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -73,9 +78,106 @@ var LogPrint = func(level LogLevel, text string) {
 	log.Print(text)
 }
 
+// LogValueItem describes keyed item for a JSON log entry
+type LogValueItem struct {
+	key    string
+	value  interface{}
+	render bool
+}
+
+// LogValue should be used as an argument to any logging calls to
+// augment the JSON output with more structured information.
+//
+// key is the dictionary parameter used to store value.
+func LogValue(key string, value interface{}) LogValueItem {
+	return LogValueItem{key: key, value: value, render: true}
+}
+
+// LogValueHide should be used as an argument to any logging calls to
+// augment the JSON output with more structured information.
+//
+// key is the dictionary parameter used to store value.
+//
+// String() will return a blank string - this is useful to put items
+// in which don't print into the log.
+func LogValueHide(key string, value interface{}) LogValueItem {
+	return LogValueItem{key: key, value: value, render: false}
+}
+
+// String returns the representation of value. If render is false this
+// is an empty string so LogValueItem entries won't show in the
+// textual representation of logs.
+func (j LogValueItem) String() string {
+	if !j.render {
+		return ""
+	}
+	if do, ok := j.value.(fmt.Stringer); ok {
+		return do.String()
+	}
+	return fmt.Sprint(j.value)
+}
+
+// logCaller returns the file and line of the function which called
+// into the logging functions, eg "operations/operations.go:123"
+func logCaller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		name := frame.Function
+		if strings.HasPrefix(name, "github.com/ncw/rclone/fs.") {
+			// skip the logging functions in this package
+			switch strings.TrimPrefix(name, "github.com/ncw/rclone/fs.") {
+			case "LogPrintf", "LogLevelPrintf", "Errorf", "Logf", "Infof", "Debugf":
+				if more {
+					continue
+				}
+			}
+		}
+		if strings.HasPrefix(name, "github.com/ncw/rclone/fs/log.Trace") && more {
+			continue
+		}
+		dir, file := path.Split(frame.File)
+		return fmt.Sprintf("%s:%d", path.Join(path.Base(dir), file), frame.Line)
+	}
+}
+
+// logJSON returns the log entry as a line of JSON
+func logJSON(level LogLevel, o interface{}, msg string, args []interface{}) string {
+	entry := map[string]interface{}{}
+	for _, arg := range args {
+		if item, ok := arg.(LogValueItem); ok {
+			entry[item.key] = item.value
+		}
+	}
+	entry["level"] = strings.ToLower(level.String())
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["msg"] = msg
+	entry["source"] = logCaller()
+	if o != nil {
+		entry["object"] = fmt.Sprintf("%+v", o)
+		entry["objectType"] = fmt.Sprintf("%T", o)
+	}
+	out, err := json.Marshal(entry)
+	if err != nil {
+		// fall back to logging the message only
+		out, _ = json.Marshal(map[string]interface{}{
+			"level": strings.ToLower(level.String()),
+			"time":  entry["time"],
+			"msg":   msg + fmt.Sprintf(" (failed to encode log entry: %v)", err),
+		})
+	}
+	return string(out)
+}
+
 // LogPrintf produces a log string from the arguments passed in
 func LogPrintf(level LogLevel, o interface{}, text string, args ...interface{}) {
 	out := fmt.Sprintf(text, args...)
+	if Config.UseJSONLog {
+		LogPrint(level, logJSON(level, o, out, args))
+		return
+	}
 	if o != nil {
 		out = fmt.Sprintf("%v: %s", o, out)
 	}
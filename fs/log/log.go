@@ -2,9 +2,7 @@
 package log
 
 import (
-	"io"
 	"log"
-	"os"
 	"reflect"
 	"runtime"
 	"strings"
@@ -15,11 +13,17 @@ import (
 
 // Flags
 var (
-	logFile        = flags.StringP("log-file", "", "", "Log everything to this file")
-	useSyslog      = flags.BoolP("syslog", "", false, "Use Syslog for logging")
-	syslogFacility = flags.StringP("syslog-facility", "", "DAEMON", "Facility for syslog, eg KERN,USER,...")
+	logFile           = flags.StringP("log-file", "", "", "Log everything to this file")
+	logFileMaxSize    = fs.SizeSuffix(0)
+	logFileMaxBackups = flags.IntP("log-file-max-backups", "", 0, "Number of rotated log files to keep, 0 to keep none")
+	useSyslog         = flags.BoolP("syslog", "", false, "Use Syslog for logging")
+	syslogFacility    = flags.StringP("syslog-facility", "", "DAEMON", "Facility for syslog, eg KERN,USER,...")
 )
 
+func init() {
+	flags.VarP(&logFileMaxSize, "log-file-max-size", "", "Rotate the --log-file when it reaches this size, 0 for off")
+}
+
 // fnName returns the name of the calling +2 function
 func fnName() string {
 	pc, _, _, ok := runtime.Caller(2)
@@ -68,16 +72,26 @@ func Trace(o interface{}, format string, a ...interface{}) func(string, ...inter
 func InitLogging() {
 	// Log file output
 	if *logFile != "" {
-		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+		r, err := openRotatingFile(*logFile, int64(logFileMaxSize), *logFileMaxBackups)
 		if err != nil {
 			log.Fatalf("Failed to open log file: %v", err)
 		}
-		_, err = f.Seek(0, io.SeekEnd)
+		log.SetOutput(r)
+		err = redirectStderr(r.f)
 		if err != nil {
-			fs.Errorf(nil, "Failed to seek log file to end: %v", err)
+			fs.Errorf(nil, "Can't redirect stderr to log file: %v", err)
+		} else {
+			// Keep stderr going to the current log file
+			r.onRotate = redirectStderr
+		}
+	}
+
+	// JSON output
+	if fs.Config.UseJSONLog {
+		log.SetFlags(0)
+		fs.LogPrint = func(level fs.LogLevel, text string) {
+			log.Print(text)
 		}
-		log.SetOutput(f)
-		redirectStderr(f)
 	}
 
 	// Syslog output
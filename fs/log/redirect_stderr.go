@@ -7,10 +7,10 @@ package log
 import (
 	"os"
 
-	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
 )
 
 // redirectStderr to the file passed in
-func redirectStderr(f *os.File) {
-	fs.Errorf(nil, "Can't redirect stderr to file")
+func redirectStderr(f *os.File) error {
+	return errors.New("can't redirect stderr to file")
 }
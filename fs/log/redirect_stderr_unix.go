@@ -5,23 +5,30 @@
 package log
 
 import (
-	"log"
 	"os"
 
 	"github.com/ncw/rclone/fs/config"
+	"github.com/pkg/errors"
 
 	"golang.org/x/sys/unix"
 )
 
 // redirectStderr to the file passed in
-func redirectStderr(f *os.File) {
-	passPromptFd, err := unix.Dup(int(os.Stderr.Fd()))
-	if err != nil {
-		log.Fatalf("Failed to duplicate stderr: %v", err)
+//
+// The first time it is called the original stderr is kept for the
+// password prompt.  It may be called again to redirect stderr to a
+// new file.
+func redirectStderr(f *os.File) error {
+	if config.PasswordPromptOutput == os.Stderr {
+		passPromptFd, err := unix.Dup(int(os.Stderr.Fd()))
+		if err != nil {
+			return errors.Wrap(err, "failed to duplicate stderr")
+		}
+		config.PasswordPromptOutput = os.NewFile(uintptr(passPromptFd), "passPrompt")
 	}
-	config.PasswordPromptOutput = os.NewFile(uintptr(passPromptFd), "passPrompt")
-	err = unix.Dup2(int(f.Fd()), int(os.Stderr.Fd()))
+	err := unix.Dup2(int(f.Fd()), int(os.Stderr.Fd()))
 	if err != nil {
-		log.Fatalf("Failed to redirect stderr to file: %v", err)
+		return errors.Wrap(err, "failed to redirect stderr to file")
 	}
+	return nil
 }
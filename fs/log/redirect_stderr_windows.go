@@ -9,9 +9,10 @@
 package log
 
 import (
-	"log"
 	"os"
 	"syscall"
+
+	"github.com/pkg/errors"
 )
 
 var (
@@ -31,9 +32,10 @@ func setStdHandle(stdhandle int32, handle syscall.Handle) error {
 }
 
 // redirectStderr to the file passed in
-func redirectStderr(f *os.File) {
+func redirectStderr(f *os.File) error {
 	err := setStdHandle(syscall.STD_ERROR_HANDLE, syscall.Handle(f.Fd()))
 	if err != nil {
-		log.Fatalf("Failed to redirect stderr to file: %v", err)
+		return errors.Wrap(err, "failed to redirect stderr to file")
 	}
+	return nil
 }
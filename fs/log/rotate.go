@@ -0,0 +1,131 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// rotatingFile is an io.Writer which writes to a log file, rotating
+// it when it gets bigger than maxSize.
+//
+// When the file is rotated it is renamed to path.1, path.1 is renamed
+// to path.2 and so on, keeping at most maxBackups old files.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64 // rotate when the file would exceed this, 0 for never
+	maxBackups int   // number of old files to keep
+	f          *os.File
+	size       int64
+	onRotate   func(f *os.File) error // if set called with the new file after rotating
+}
+
+// openRotatingFile opens the log file at path for appending
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open the log file for appending noting its current size
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to open log file")
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "failed to stat log file")
+	}
+	r.f = f
+	r.size = fi.Size()
+	return nil
+}
+
+// backupName returns the name of the nth backup file
+func (r *rotatingFile) backupName(n int) string {
+	return fmt.Sprintf("%s.%d", r.path, n)
+}
+
+// rotate closes the current file, shuffles the backups along and
+// opens a new file - must be called with the lock held
+//
+// A new file is always opened if possible, even if the backups
+// couldn't be renamed.
+func (r *rotatingFile) rotate() (err error) {
+	err = r.f.Close()
+	r.f = nil
+	if err != nil {
+		err = errors.Wrap(err, "failed to close log file")
+	} else {
+		err = r.shuffle()
+	}
+	openErr := r.open()
+	if openErr != nil {
+		return openErr
+	}
+	if r.onRotate != nil {
+		rotateErr := r.onRotate(r.f)
+		if err == nil {
+			err = rotateErr
+		}
+	}
+	return err
+}
+
+// shuffle renames the log file and its backups along one
+func (r *rotatingFile) shuffle() error {
+	if r.maxBackups <= 0 {
+		err := os.Remove(r.path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to remove log file")
+		}
+		return nil
+	}
+	err := os.Remove(r.backupName(r.maxBackups))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove old log file")
+	}
+	for n := r.maxBackups - 1; n >= 1; n-- {
+		err = os.Rename(r.backupName(n), r.backupName(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to rename old log file")
+		}
+	}
+	err = os.Rename(r.path, r.backupName(1))
+	if err != nil {
+		return errors.Wrap(err, "failed to rename log file")
+	}
+	return nil
+}
+
+// Write p to the log file rotating it first if necessary
+func (r *rotatingFile) Write(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, errors.New("log file not open")
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		err = r.rotate()
+		if r.f == nil {
+			return 0, err
+		}
+		// If the old file couldn't be renamed carry on
+		// writing to the log file so no logs are lost
+	}
+	n, err = r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
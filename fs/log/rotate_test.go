@@ -0,0 +1,106 @@
+package log
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-log-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	path := filepath.Join(dir, "rclone.log")
+
+	// Some existing contents should count towards the size
+	require.NoError(t, ioutil.WriteFile(path, []byte("old\n"), 0640))
+
+	r, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			return "<missing>"
+		}
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "five\n"} {
+		n, err := r.Write([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	require.NoError(t, r.f.Close())
+
+	assert.Equal(t, "four\nfive\n", read(path))
+	assert.Equal(t, "two\nthree\n", read(path+".1"))
+	assert.Equal(t, "old\none\n", read(path+".2"))
+	assert.Equal(t, "<missing>", read(path+".3"))
+}
+
+func TestRotatingFileNoBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-log-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	path := filepath.Join(dir, "rclone.log")
+
+	r, err := openRotatingFile(path, 8, 0)
+	require.NoError(t, err)
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.f.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "three\n", string(data))
+	_, err = os.Stat(path + ".1")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRotatingFileOnRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-log-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	path := filepath.Join(dir, "rclone.log")
+
+	r, err := openRotatingFile(path, 8, 1)
+	require.NoError(t, err)
+	var rotated []*os.File
+	r.onRotate = func(f *os.File) error {
+		rotated = append(rotated, f)
+		return nil
+	}
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, len(rotated))
+	assert.Equal(t, r.f, rotated[0])
+
+	// An error from onRotate doesn't stop the logging
+	r.onRotate = func(f *os.File) error {
+		return errors.New("potato")
+	}
+	_, err = r.Write([]byte("four\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.f.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "four\n", string(data))
+}
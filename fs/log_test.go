@@ -1,6 +1,58 @@
 package fs
 
-import "github.com/spf13/pflag"
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 // Check it satisfies the interface
 var _ pflag.Value = (*LogLevel)(nil)
+
+func TestLogValueItem(t *testing.T) {
+	assert.Equal(t, "potato", LogValue("key", "potato").String())
+	assert.Equal(t, "1k", LogValue("size", SizeSuffix(1024)).String())
+	assert.Equal(t, "", LogValueHide("key", "potato").String())
+	assert.Equal(t, "Copied", fmt.Sprintf("Copied%v", LogValueHide("event", "copied")))
+}
+
+func TestLogJSON(t *testing.T) {
+	oldUseJSONLog, oldLogLevel, oldLogPrint := Config.UseJSONLog, Config.LogLevel, LogPrint
+	defer func() {
+		Config.UseJSONLog, Config.LogLevel, LogPrint = oldUseJSONLog, oldLogLevel, oldLogPrint
+	}()
+	var gotLevel LogLevel
+	var gotText string
+	LogPrint = func(level LogLevel, text string) {
+		gotLevel, gotText = level, text
+	}
+	Config.UseJSONLog = true
+	Config.LogLevel = LogLevelInfo
+
+	Infof("potato.txt", "Copied %d%v", 1, LogValueHide("event", "copied"))
+	assert.Equal(t, LogLevelInfo, gotLevel)
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(gotText), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "Copied 1", entry["msg"])
+	assert.Equal(t, "copied", entry["event"])
+	assert.Equal(t, "potato.txt", entry["object"])
+	assert.Equal(t, "string", entry["objectType"])
+	assert.Regexp(t, `^fs/log_test\.go:\d+$`, entry["source"])
+	_, err := time.Parse(time.RFC3339Nano, entry["time"].(string))
+	assert.NoError(t, err)
+
+	// No object
+	gotText = ""
+	Errorf(nil, "Failed")
+	entry = nil
+	require.NoError(t, json.Unmarshal([]byte(gotText), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Equal(t, "Failed", entry["msg"])
+	assert.NotContains(t, entry, "object")
+}
@@ -0,0 +1,207 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+// timeNow is used to make the time in --suffix - overridden in tests
+var timeNow = time.Now
+
+// splitSuffix splits suffix into the text before the time format,
+// the time format (without the {}) and the text after it.
+//
+// If there isn't a time format then layout is "" and prefix is the
+// whole suffix.
+func splitSuffix(suffix string) (prefix, layout, postfix string) {
+	open := strings.IndexByte(suffix, '{')
+	if open < 0 {
+		return suffix, "", ""
+	}
+	close := strings.IndexByte(suffix[open:], '}')
+	if close < 0 {
+		return suffix, "", ""
+	}
+	close += open
+	return suffix[:open], suffix[open+1 : close], suffix[close+1:]
+}
+
+// SuffixName adds the --suffix to remote for use in --backup-dir.
+//
+// Any time format in {} in the suffix, eg "-{2006-01-02}", is
+// replaced with the current time formatted with that Go time layout.
+//
+// If --suffix-keep-extension is set then the suffix is inserted
+// before the extension of remote, so "file.txt" becomes
+// "file-2006-01-02.txt".
+func SuffixName(remote string) string {
+	if fs.Config.Suffix == "" {
+		return remote
+	}
+	prefix, layout, postfix := splitSuffix(fs.Config.Suffix)
+	suffix := prefix
+	if layout != "" {
+		suffix += timeNow().Format(layout) + postfix
+	}
+	if fs.Config.SuffixKeepExtension {
+		ext := path.Ext(remote)
+		return remote[:len(remote)-len(ext)] + suffix + ext
+	}
+	return remote + suffix
+}
+
+// ParseSuffixName reverses SuffixName, returning the original remote
+// and the time from the suffix.  It returns ok = false if remote
+// wasn't made by SuffixName with the current --suffix or the suffix
+// has no time format.
+func ParseSuffixName(remote string) (original string, t time.Time, ok bool) {
+	prefix, layout, postfix := splitSuffix(fs.Config.Suffix)
+	if layout == "" {
+		return "", t, false
+	}
+	ext := ""
+	rest := remote
+	if fs.Config.SuffixKeepExtension {
+		ext = path.Ext(rest)
+		rest = rest[:len(rest)-len(ext)]
+	}
+	if !strings.HasSuffix(rest, postfix) {
+		return "", t, false
+	}
+	rest = rest[:len(rest)-len(postfix)]
+	// Find the start of the time working back from the end so the
+	// shortest time which parses is found.  The original leaf name
+	// must not be empty.
+	leafStart := len(rest) - len(path.Base(rest))
+	for start := len(rest) - len(prefix); start > leafStart; start-- {
+		if !strings.HasPrefix(rest[start:], prefix) {
+			continue
+		}
+		parsed, err := time.ParseInLocation(layout, rest[start+len(prefix):], time.Local)
+		if err == nil {
+			return rest[:start] + ext, parsed, true
+		}
+	}
+	return "", t, false
+}
+
+// PruneOpt contains the retention rules for PruneBackups.
+//
+// A version is kept if any of the rules keep it.
+type PruneOpt struct {
+	KeepLast   int // keep this many of the most recent versions
+	KeepDaily  int // keep the most recent version for this many days
+	KeepWeekly int // keep the most recent version for this many weeks
+}
+
+// backupVersion is a version of a file in the backup dir
+type backupVersion struct {
+	o    fs.Object
+	t    time.Time
+	keep bool
+}
+
+// keepVersions marks the versions to keep according to opt.  The
+// versions should be sorted newest first.
+func keepVersions(versions []*backupVersion, opt PruneOpt) {
+	for i, v := range versions {
+		if i < opt.KeepLast {
+			v.keep = true
+		}
+	}
+	keepBuckets := func(n int, bucket func(t time.Time) string) {
+		seen := map[string]struct{}{}
+		for _, v := range versions {
+			if len(seen) >= n {
+				break
+			}
+			b := bucket(v.t)
+			if _, found := seen[b]; found {
+				continue
+			}
+			seen[b] = struct{}{}
+			v.keep = true
+		}
+	}
+	keepBuckets(opt.KeepDaily, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepBuckets(opt.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+}
+
+// PruneBackups lists the versions of the files in dir in the backup
+// dir f made using --backup-dir with a time format in --suffix and
+// deletes the ones not kept by the rules in opt.  dir is "" for the
+// root.
+//
+// A line for each version saying whether it was kept or deleted is
+// written to w.  If no rules are set in opt then the versions are
+// listed but not deleted.
+//
+// Obeys includes and excludes.
+func PruneBackups(f fs.Fs, dir string, opt PruneOpt, w io.Writer) error {
+	if _, layout, _ := splitSuffix(fs.Config.Suffix); layout == "" {
+		return errors.New("need a time format in {} in --suffix to find the versions, eg --suffix \"-{2006-01-02}\"")
+	}
+	versionsByRemote := map[string][]*backupVersion{}
+	err := walk.Walk(f, dir, false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		entries.ForObject(func(o fs.Object) {
+			original, t, ok := ParseSuffixName(o.Remote())
+			if !ok {
+				fs.Debugf(o, "Ignoring as not a version")
+				return
+			}
+			versionsByRemote[original] = append(versionsByRemote[original], &backupVersion{o: o, t: t})
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	prune := opt.KeepLast > 0 || opt.KeepDaily > 0 || opt.KeepWeekly > 0
+	remotes := make([]string, 0, len(versionsByRemote))
+	for remote := range versionsByRemote {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	var errCount int
+	for _, remote := range remotes {
+		versions := versionsByRemote[remote]
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].t.After(versions[j].t)
+		})
+		if prune {
+			keepVersions(versions, opt)
+		}
+		for _, v := range versions {
+			action := "keep"
+			if prune && !v.keep {
+				action = "delete"
+			}
+			syncFprintf(w, "%-6s %s %s\n", action, v.t.Format("2006-01-02 15:04:05"), v.o.Remote())
+			if action == "delete" {
+				if DeleteFile(v.o) != nil {
+					errCount++
+				}
+			}
+		}
+	}
+	if errCount > 0 {
+		return errors.Errorf("failed to delete %d versions", errCount)
+	}
+	return nil
+}
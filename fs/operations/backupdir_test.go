@@ -0,0 +1,100 @@
+package operations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSuffix(t *testing.T) {
+	for _, test := range []struct {
+		in                      string
+		prefix, layout, postfix string
+	}{
+		{"", "", "", ""},
+		{".bak", ".bak", "", ""},
+		{"-{2006-01-02}", "-", "2006-01-02", ""},
+		{"-{20060102}.bak", "-", "20060102", ".bak"},
+		{"-{2006", "-{2006", "", ""},
+	} {
+		prefix, layout, postfix := splitSuffix(test.in)
+		assert.Equal(t, test.prefix, prefix, test.in)
+		assert.Equal(t, test.layout, layout, test.in)
+		assert.Equal(t, test.postfix, postfix, test.in)
+	}
+}
+
+func TestSuffixName(t *testing.T) {
+	oldSuffix, oldKeepExtension := fs.Config.Suffix, fs.Config.SuffixKeepExtension
+	defer func() {
+		fs.Config.Suffix, fs.Config.SuffixKeepExtension = oldSuffix, oldKeepExtension
+		timeNow = time.Now
+	}()
+	when := time.Date(2019, 3, 4, 5, 6, 7, 0, time.Local)
+	timeNow = func() time.Time { return when }
+
+	for _, test := range []struct {
+		suffix        string
+		keepExtension bool
+		in            string
+		want          string
+		wantParse     bool
+	}{
+		{"", false, "dir/file.txt", "dir/file.txt", false},
+		{".bak", false, "dir/file.txt", "dir/file.txt.bak", false},
+		{".bak", true, "dir/file.txt", "dir/file.bak.txt", false},
+		{"-{2006-01-02}", false, "dir/file.txt", "dir/file.txt-2019-03-04", true},
+		{"-{2006-01-02}", true, "dir/file.txt", "dir/file-2019-03-04.txt", true},
+		{"-{2006-01-02}", true, "file", "file-2019-03-04", true},
+		{"-{2006-01-02}", true, "archive.tar.gz", "archive.tar-2019-03-04.gz", true},
+		{"-{2006-01-02}", true, "my-file-1.txt", "my-file-1-2019-03-04.txt", true},
+		{".{20060102-150405}.bak", false, "file", "file.20190304-050607.bak", true},
+	} {
+		fs.Config.Suffix = test.suffix
+		fs.Config.SuffixKeepExtension = test.keepExtension
+		got := SuffixName(test.in)
+		assert.Equal(t, test.want, got, test.suffix)
+
+		original, parsedTime, ok := ParseSuffixName(got)
+		assert.Equal(t, test.wantParse, ok, test.suffix)
+		if ok {
+			assert.Equal(t, test.in, original, test.suffix)
+			assert.Equal(t, when.Format(test.suffix), parsedTime.Format(test.suffix), test.suffix)
+		}
+	}
+
+	// Check things which aren't versions don't parse
+	fs.Config.Suffix = "-{2006-01-02}"
+	fs.Config.SuffixKeepExtension = true
+	for _, in := range []string{"file.txt", "file-2019-13-04.txt", "-2019-03-04.txt", "dir/-2019-03-04"} {
+		_, _, ok := ParseSuffixName(in)
+		assert.False(t, ok, in)
+	}
+}
+
+func TestKeepVersions(t *testing.T) {
+	day := func(d, hour int) *backupVersion {
+		return &backupVersion{t: time.Date(2019, 3, d, hour, 0, 0, 0, time.UTC)}
+	}
+	// Newest first - 2019-03-18 is a Monday
+	versions := []*backupVersion{
+		day(20, 12), day(20, 6), day(19, 12), day(18, 12), day(17, 12), day(10, 12), day(3, 12),
+	}
+	keep := func(opt PruneOpt) (kept []bool) {
+		for _, v := range versions {
+			v.keep = false
+		}
+		keepVersions(versions, opt)
+		for _, v := range versions {
+			kept = append(kept, v.keep)
+		}
+		return kept
+	}
+	assert.Equal(t, []bool{true, true, false, false, false, false, false}, keep(PruneOpt{KeepLast: 2}))
+	assert.Equal(t, []bool{true, false, true, true, false, false, false}, keep(PruneOpt{KeepDaily: 3}))
+	assert.Equal(t, []bool{true, false, false, false, true, true, false}, keep(PruneOpt{KeepWeekly: 3}))
+	assert.Equal(t, []bool{true, true, true, false, true, false, false}, keep(PruneOpt{KeepLast: 2, KeepDaily: 2, KeepWeekly: 2}))
+	assert.Equal(t, []bool{false, false, false, false, false, false, false}, keep(PruneOpt{}))
+}
@@ -0,0 +1,211 @@
+package operations
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// ParseSumFile parses a sum file in the format produced by md5sum,
+// sha1sum and friends, returning a map of path to lower case hash.
+//
+// Each line should have the hash, then a space, then either a space
+// (text mode) or a "*" (binary mode), then the path.  Blank lines and
+// lines starting with "#" are ignored.
+func ParseSumFile(in io.Reader) (sums map[string]string, err error) {
+	sums = make(map[string]string)
+	scanner := bufio.NewScanner(in)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexByte(line, ' ')
+		if i <= 0 || i+2 >= len(line) || (line[i+1] != ' ' && line[i+1] != '*') {
+			return nil, errors.Errorf("line %d: badly formed sum line %q", lineNo, line)
+		}
+		sum := strings.ToLower(line[:i])
+		remote := strings.TrimPrefix(line[i+2:], "./")
+		if _, found := sums[remote]; found {
+			return nil, errors.Errorf("line %d: duplicate entry for %q", lineNo, remote)
+		}
+		sums[remote] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// checkSumObject checks o against the sum passed in returning true
+// if it differs.
+//
+// If download is set, or o has no hash of type ht, then o is
+// downloaded and hashed.
+func checkSumObject(o fs.Object, ht hash.Type, sum string, download bool) (differ bool, err error) {
+	accounting.Stats.Checking(o.Remote())
+	defer accounting.Stats.DoneChecking(o.Remote())
+	var got string
+	if !download {
+		got, err = o.Hash(ht)
+		if err != nil {
+			return true, errors.Wrapf(err, "failed to read %v", ht)
+		}
+	}
+	if got == "" {
+		in, err := o.Open()
+		if err != nil {
+			return true, errors.Wrap(err, "failed to open")
+		}
+		acc := accounting.NewAccount(in, o).WithBuffer() // account and buffer the transfer
+		sums, err := hash.StreamTypes(acc, hash.NewHashSet(ht))
+		closeErr := acc.Close()
+		if err != nil {
+			return true, errors.Wrapf(err, "failed to calculate %v", ht)
+		}
+		if closeErr != nil {
+			return true, errors.Wrap(closeErr, "failed to close")
+		}
+		got = sums[ht]
+	}
+	if got != strings.ToLower(sum) {
+		fs.Debugf(o, "%v = %s (sum file %s)", ht, got, sum)
+		return true, nil
+	}
+	return false, nil
+}
+
+// CheckSum checks the files in opt.Fdst against the hashes of type
+// ht read from sumFile.  The sum file takes the place of the source
+// so opt.Fsrc and opt.Check are ignored.
+//
+// Files which are in the sum file but not in opt.Fdst, files which
+// are in opt.Fdst but not in the sum file (unless opt.OneWay is set)
+// and files whose hashes differ are logged, counted as errors and
+// written to opt.Report.
+//
+// Obeys includes and excludes, applying them to the paths in the sum
+// file too.
+//
+// If download is set, or opt.Fdst doesn't support ht, then the files
+// are downloaded and hashed.
+func CheckSum(sumFile io.Reader, ht hash.Type, download bool, opt *CheckOpt) error {
+	fdst := opt.Fdst
+	sums, err := ParseSumFile(sumFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read sum file")
+	}
+	for remote, sum := range sums {
+		if len(sum) != hash.Width[ht] {
+			return errors.Errorf("failed to read sum file: %v sum for %q has the wrong length", ht, remote)
+		}
+		if !filter.Active.IncludeRemote(remote) {
+			delete(sums, remote)
+		}
+	}
+	if !download && !fdst.Hashes().Contains(ht) {
+		fs.Logf(fdst, "%v not supported - downloading files to check them", ht)
+		download = true
+	}
+
+	type check struct {
+		o   fs.Object
+		sum string
+	}
+	ci := fs.GetConfigFs(fdst)
+	var (
+		mu          sync.Mutex // protect sums
+		wg          sync.WaitGroup
+		checks      = make(chan check, ci.Checkers)
+		differences int32
+		extra       int32
+	)
+	for i := 0; i < ci.Checkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range checks {
+				remote := c.o.Remote()
+				differ, err := checkSumObject(c.o, ht, c.sum, download)
+				switch {
+				case err != nil:
+					fs.Errorf(c.o, "%v", err)
+					fs.CountError(err)
+					atomic.AddInt32(&differences, 1)
+					opt.Report.Add(ReportError, remote)
+				case differ:
+					err = errors.Errorf("%v differ", ht)
+					fs.Errorf(c.o, "%v", err)
+					fs.CountError(err)
+					atomic.AddInt32(&differences, 1)
+					opt.Report.Add(ReportDiffer, remote)
+				default:
+					fs.Debugf(c.o, "OK")
+					opt.Report.Add(ReportMatch, remote)
+				}
+			}
+		}()
+	}
+	listErr := ListFn(fdst, func(o fs.Object) {
+		remote := o.Remote()
+		mu.Lock()
+		sum, found := sums[remote]
+		delete(sums, remote)
+		mu.Unlock()
+		if found {
+			checks <- check{o: o, sum: sum}
+			return
+		}
+		if opt.OneWay {
+			return
+		}
+		err := errors.New("File not in sum file")
+		fs.Errorf(o, "%v", err)
+		fs.CountError(err)
+		atomic.AddInt32(&differences, 1)
+		atomic.AddInt32(&extra, 1)
+		opt.Report.Add(ReportMissingOnSrc, remote)
+	})
+	close(checks)
+	wg.Wait()
+	if listErr != nil {
+		return listErr
+	}
+
+	// Anything left in sums is missing from fdst
+	missing := make([]string, 0, len(sums))
+	for remote := range sums {
+		missing = append(missing, remote)
+	}
+	sort.Strings(missing)
+	for _, remote := range missing {
+		err := errors.Errorf("File not in %v", fdst)
+		fs.Errorf(remote, "%v", err)
+		fs.CountError(err)
+		differences++
+		opt.Report.Add(ReportMissingOnDst, remote)
+	}
+
+	if len(missing) > 0 {
+		fs.Logf(fdst, "%d files missing", len(missing))
+	}
+	if extra > 0 {
+		fs.Logf(fdst, "%d files not in sum file", extra)
+	}
+	fs.Logf(fdst, "%d differences found", differences)
+	if differences > 0 {
+		return errors.Errorf("%d differences found", differences)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package operations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSumFile(t *testing.T) {
+	sums, err := ParseSumFile(strings.NewReader(`# comment
+
+D41D8CD98F00B204E9800998ECF8427E  empty
+5d41402abc4b2a76b9719d911017c592 *./dir/hello world
+7d793037a0760186574b0282f2f435e7  windows line end` + "\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"empty":            "d41d8cd98f00b204e9800998ecf8427e",
+		"dir/hello world":  "5d41402abc4b2a76b9719d911017c592",
+		"windows line end": "7d793037a0760186574b0282f2f435e7",
+	}, sums)
+
+	for _, in := range []string{
+		"no spaces",
+		"d41d8cd98f00b204e9800998ecf8427e empty",
+		"d41d8cd98f00b204e9800998ecf8427e  ",
+		" d41d8cd98f00b204e9800998ecf8427e  empty",
+		"d41d8cd98f00b204e9800998ecf8427e  dup\nd41d8cd98f00b204e9800998ecf8427e  dup",
+	} {
+		_, err = ParseSumFile(strings.NewReader(in))
+		assert.Error(t, err, in)
+	}
+}
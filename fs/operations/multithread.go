@@ -0,0 +1,210 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/pkg/errors"
+)
+
+const (
+	multithreadChunkSize     = 64 << 10
+	multithreadChunkSizeMask = multithreadChunkSize - 1
+	multithreadBufferSize    = 32 * 1024
+)
+
+// state for a multi-thread copy
+type multiThreadCopyState struct {
+	ctx      context.Context
+	partSize int64
+	size     int64
+	wc       fs.WriterAtCloser
+	src      fs.Object
+	acc      *accounting.Account
+	streams  int
+}
+
+// Copy a single stream into place
+func (mc *multiThreadCopyState) copyStream(stream int) (err error) {
+	defer func() {
+		if err != nil {
+			fs.Debugf(mc.src, "multi-thread copy: stream %d/%d failed: %v", stream+1, mc.streams, err)
+		}
+	}()
+	start := int64(stream) * mc.partSize
+	if start >= mc.size {
+		return nil
+	}
+	end := start + mc.partSize
+	if end > mc.size {
+		end = mc.size
+	}
+
+	fs.Debugf(mc.src, "multi-thread copy: stream %d/%d (%d-%d) size %v starting", stream+1, mc.streams, start, end, fs.SizeSuffix(end-start))
+
+	rc, err := mc.src.Open(&fs.RangeOption{Start: start, End: end - 1})
+	if err != nil {
+		return errors.Wrap(err, "multi-thread copy: failed to open source")
+	}
+	defer fs.CheckClose(rc, &err)
+
+	// Copy the data
+	buf := make([]byte, multithreadBufferSize)
+	offset := start
+	for {
+		// Check if context cancelled and exit if so
+		if mc.ctx.Err() != nil {
+			return mc.ctx.Err()
+		}
+		nr, er := rc.Read(buf)
+		if nr > 0 {
+			err = mc.acc.AccountRead(nr)
+			if err != nil {
+				return errors.Wrap(err, "multi-thread copy: accounting failed")
+			}
+			nw, ew := mc.wc.WriteAt(buf[0:nr], offset)
+			if nw > 0 {
+				offset += int64(nw)
+			}
+			if ew != nil {
+				return errors.Wrap(ew, "multi-thread copy: write failed")
+			}
+			if nr != nw {
+				return errors.Wrap(io.ErrShortWrite, "multi-thread copy")
+			}
+		}
+		if er != nil {
+			if er != io.EOF {
+				return errors.Wrap(er, "multi-thread copy: read failed")
+			}
+			break
+		}
+	}
+
+	if offset != end {
+		return errors.Errorf("multi-thread copy: wrote %d bytes but expected to write %d", offset-start, end-start)
+	}
+
+	fs.Debugf(mc.src, "multi-thread copy: stream %d/%d (%d-%d) size %v finished", stream+1, mc.streams, start, end, fs.SizeSuffix(end-start))
+	return nil
+}
+
+// Calculate the chunk sizes and updated number of streams
+func (mc *multiThreadCopyState) calculateChunks() {
+	partSize := mc.size / int64(mc.streams)
+	// Round partition size up so partSize * streams >= size
+	if (mc.size % int64(mc.streams)) != 0 {
+		partSize++
+	}
+	// round partSize up to nearest multithreadChunkSize boundary
+	mc.partSize = (partSize + multithreadChunkSizeMask) &^ multithreadChunkSizeMask
+	// recalculate number of streams
+	mc.streams = int(mc.size / mc.partSize)
+	// round streams up so partSize * streams >= size
+	if (mc.size % mc.partSize) != 0 {
+		mc.streams++
+	}
+}
+
+// removePartial removes the partially written destination of a
+// failed multi-thread copy so it can't be mistaken for a good copy
+func removePartial(f fs.Fs, remote string, src fs.Object) {
+	obj, err := f.NewObject(remote)
+	if err != nil {
+		fs.Debugf(src, "multi-thread copy: no partially written file to remove: %v", err)
+		return
+	}
+	err = obj.Remove()
+	if err != nil {
+		fs.Errorf(src, "multi-thread copy: failed to remove partially written file: %v", err)
+		return
+	}
+	fs.Debugf(src, "multi-thread copy: removed partially written file")
+}
+
+// multiThreadCopy copies src to (f, remote) using streams download
+// threads and the OpenWriterAt feature of f
+//
+// If the copy fails the partially written destination is removed.
+func multiThreadCopy(f fs.Fs, remote string, src fs.Object, streams int) (newDst fs.Object, err error) {
+	openWriterAt := f.Features().OpenWriterAt
+	if openWriterAt == nil {
+		return nil, errors.New("multi-thread copy: OpenWriterAt not supported")
+	}
+	if src.Size() < 0 {
+		return nil, errors.New("multi-thread copy: can't copy unknown sized file")
+	}
+	if src.Size() == 0 {
+		return nil, errors.New("multi-thread copy: can't copy zero sized file")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mc := &multiThreadCopyState{
+		ctx:     ctx,
+		size:    src.Size(),
+		src:     src,
+		streams: streams,
+	}
+	mc.calculateChunks()
+
+	// Make accounting
+	mc.acc = accounting.NewAccount(nil, src).WithRemotes(f, src.Fs())
+	defer fs.CheckClose(mc.acc, &err)
+
+	// create write file handle
+	mc.wc, err = openWriterAt(remote, mc.size)
+	if err != nil {
+		return nil, errors.Wrap(err, "multi-thread copy: failed to open destination")
+	}
+
+	fs.Debugf(src, "Starting multi-thread copy with %d parts of size %v", mc.streams, fs.SizeSuffix(mc.partSize))
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for stream := 0; stream < mc.streams; stream++ {
+		wg.Add(1)
+		go func(stream int) {
+			defer wg.Done()
+			err := mc.copyStream(stream)
+			if err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					// stop the other streams
+					cancel()
+				}
+				errMu.Unlock()
+			}
+		}(stream)
+	}
+	wg.Wait()
+	closeErr := mc.wc.Close()
+	if firstErr == nil && closeErr != nil {
+		firstErr = errors.Wrap(closeErr, "multi-thread copy: failed to close destination")
+	}
+	if firstErr != nil {
+		removePartial(f, remote, src)
+		return nil, firstErr
+	}
+
+	obj, err := f.NewObject(remote)
+	if err != nil {
+		return nil, errors.Wrap(err, "multi-thread copy: failed to find object after copy")
+	}
+
+	err = obj.SetModTime(src.ModTime())
+	switch err {
+	case nil, fs.ErrorCantSetModTime, fs.ErrorCantSetModTimeWithoutDelete:
+	default:
+		return nil, errors.Wrap(err, "multi-thread copy: failed to set modification time")
+	}
+
+	fs.Debugf(src, "Finished multi-thread copy with %d parts of size %v", mc.streams, fs.SizeSuffix(mc.partSize))
+	return obj, nil
+}
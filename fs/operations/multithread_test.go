@@ -0,0 +1,77 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultithreadCalculateChunks(t *testing.T) {
+	for _, test := range []struct {
+		size         int64
+		streams      int
+		wantPartSize int64
+		wantStreams  int
+	}{
+		{size: 1, streams: 10, wantPartSize: multithreadChunkSize, wantStreams: 1},
+		{size: 1 << 20, streams: 1, wantPartSize: 1 << 20, wantStreams: 1},
+		{size: 1 << 20, streams: 2, wantPartSize: 1 << 19, wantStreams: 2},
+		{size: (1 << 20) + 1, streams: 2, wantPartSize: (1 << 19) + multithreadChunkSize, wantStreams: 2},
+		{size: (1 << 20) - 1, streams: 2, wantPartSize: (1 << 19), wantStreams: 2},
+	} {
+		t.Run(fmt.Sprintf("%+v", test), func(t *testing.T) {
+			mc := &multiThreadCopyState{
+				size:    test.size,
+				streams: test.streams,
+			}
+			mc.calculateChunks()
+			assert.Equal(t, test.wantPartSize, mc.partSize)
+			assert.Equal(t, test.wantStreams, mc.streams)
+		})
+	}
+}
+
+// errorOnOpenObject is an fs.Object which fails to open any part
+// except the first
+type errorOnOpenObject struct {
+	fs.Object
+}
+
+// Open the object failing if the range doesn't start at 0
+func (o errorOnOpenObject) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	for _, option := range options {
+		if r, ok := option.(*fs.RangeOption); ok && r.Start > 0 {
+			return nil, errors.New("potato")
+		}
+	}
+	return o.Object.Open(options...)
+}
+
+func TestMultithreadCopyRemovesPartial(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Flocal.Features().OpenWriterAt == nil {
+		t.Skip("OpenWriterAt not supported")
+	}
+
+	contents := strings.Repeat("0123456789abcdef", 100000)
+	file1 := r.WriteObject("file1", contents, time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC))
+	src, err := r.Fremote.NewObject(file1.Path)
+	require.NoError(t, err)
+
+	// An existing file is truncated so must be removed too
+	r.WriteFile("file1", "existing", time.Now())
+
+	_, err = multiThreadCopy(r.Flocal, file1.Path, errorOnOpenObject{src}, 4)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "potato")
+	fstest.CheckItems(t, r.Flocal)
+}
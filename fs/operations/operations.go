@@ -235,6 +235,37 @@ func (o *overrideRemoteObject) MimeType() string {
 // Check interface is satisfied
 var _ fs.MimeTyper = (*overrideRemoteObject)(nil)
 
+// checkCutoff checks whether a new transfer of src may be started
+// according to --max-transfer, --max-duration and --cutoff-mode
+func checkCutoff(src fs.ObjectInfo) error {
+	hard := fs.Config.CutoffMode == fs.CutoffModeHard
+	if fs.Config.MaxTransfer >= 0 {
+		limitReached := false
+		if fs.Config.CutoffMode == fs.CutoffModeCautious {
+			bytesSoFar := accounting.Stats.GetBytesWithPending()
+			if size := src.Size(); size > 0 {
+				bytesSoFar += size
+			}
+			limitReached = bytesSoFar > int64(fs.Config.MaxTransfer)
+		} else {
+			limitReached = accounting.Stats.GetBytes() >= int64(fs.Config.MaxTransfer)
+		}
+		if limitReached {
+			if hard {
+				return accounting.ErrorMaxTransferLimitReached
+			}
+			return accounting.ErrorMaxTransferLimitReachedGraceful
+		}
+	}
+	if accounting.Stats.MaxDurationReached() {
+		if hard {
+			return accounting.ErrorMaxDurationReached
+		}
+		return accounting.ErrorMaxDurationReachedGraceful
+	}
+	return nil
+}
+
 // Copy src object to dst or f if nil.  If dst is nil then it uses
 // remote as the name of the new object.
 //
@@ -246,6 +277,10 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		fs.Logf(src, "Not copying as --dry-run")
 		return newDst, nil
 	}
+	err = checkCutoff(src)
+	if err != nil {
+		return newDst, err
+	}
 	maxTries := fs.Config.LowLevelRetries
 	tries := 0
 	doUpdate := dst != nil
@@ -275,28 +310,40 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		}
 		// If can't server side copy, do it manually
 		if err == fs.ErrorCantCopy {
-			var in0 io.ReadCloser
-			in0, err = src.Open(hashOption)
-			if err != nil {
-				err = errors.Wrap(err, "failed to open source object")
-			} else {
-				in := accounting.NewAccount(in0, src).WithBuffer() // account and buffer the transfer
-				var wrappedSrc fs.ObjectInfo = src
-				// We try to pass the original object if possible
-				if src.Remote() != remote {
-					wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
-				}
+			if f.Features().OpenWriterAt != nil && fs.Config.MultiThreadStreams > 1 && src.Size() > 0 && src.Size() >= int64(fs.Config.MultiThreadCutoff) {
+				newDst, err = multiThreadCopy(f, remote, src, fs.Config.MultiThreadStreams)
 				if doUpdate {
-					actionTaken = "Copied (replaced existing)"
-					err = dst.Update(in, wrappedSrc, hashOption)
+					actionTaken = "Multi-thread Copied (replaced existing)"
 				} else {
-					actionTaken = "Copied (new)"
-					dst, err = f.Put(in, wrappedSrc, hashOption)
+					actionTaken = "Multi-thread Copied (new)"
 				}
-				closeErr := in.Close()
 				if err == nil {
-					newDst = dst
-					err = closeErr
+					dst = newDst
+				}
+			} else {
+				var in0 io.ReadCloser
+				in0, err = src.Open(hashOption)
+				if err != nil {
+					err = errors.Wrap(err, "failed to open source object")
+				} else {
+					in := accounting.NewAccount(in0, src).WithRemotes(f, src.Fs()).WithBuffer() // account and buffer the transfer
+					var wrappedSrc fs.ObjectInfo = src
+					// We try to pass the original object if possible
+					if src.Remote() != remote {
+						wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
+					}
+					if doUpdate {
+						actionTaken = "Copied (replaced existing)"
+						err = dst.Update(in, wrappedSrc, hashOption)
+					} else {
+						actionTaken = "Copied (new)"
+						dst, err = f.Put(in, wrappedSrc, hashOption)
+					}
+					closeErr := in.Close()
+					if err == nil {
+						newDst = dst
+						err = closeErr
+					}
 				}
 			}
 		}
@@ -352,7 +399,7 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 		}
 	}
 
-	fs.Infof(src, actionTaken)
+	fs.Infof(src, "%s%v%v", actionTaken, fs.LogValueHide("event", "copied"), fs.LogValueHide("size", src.Size()))
 	return newDst, err
 }
 
@@ -380,7 +427,7 @@ func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Ob
 		newDst, err = doMove(src, remote)
 		switch err {
 		case nil:
-			fs.Infof(src, "Moved (server side)")
+			fs.Infof(src, "Moved (server side)%v%v", fs.LogValueHide("event", "moved"), fs.LogValueHide("size", src.Size()))
 			return newDst, nil
 		case fs.ErrorCantMove:
 			fs.Debugf(src, "Can't move, switching to copy")
@@ -422,9 +469,9 @@ func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
 	if fs.Config.MaxDelete != -1 && numDeletes > fs.Config.MaxDelete {
 		return fserrors.FatalError(errors.New("--max-delete threshold reached"))
 	}
-	action, actioned, actioning := "delete", "Deleted", "deleting"
+	action, actioned, actioning, event := "delete", "Deleted", "deleting", "deleted"
 	if backupDir != nil {
-		action, actioned, actioning = "move into backup dir", "Moved into backup dir", "moving into backup dir"
+		action, actioned, actioning, event = "move into backup dir", "Moved into backup dir", "moving into backup dir", "backup"
 	}
 	if fs.Config.DryRun {
 		fs.Logf(dst, "Not %s as --dry-run", actioning)
@@ -432,9 +479,7 @@ func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
 		if !SameConfig(dst.Fs(), backupDir) {
 			err = errors.New("parameter to --backup-dir has to be on the same remote as destination")
 		} else {
-			remoteWithSuffix := dst.Remote() + fs.Config.Suffix
-			overwritten, _ := backupDir.NewObject(remoteWithSuffix)
-			_, err = Move(backupDir, overwritten, remoteWithSuffix, dst)
+			err = MoveBackupDir(backupDir, dst)
 		}
 	} else {
 		err = dst.Remove()
@@ -443,12 +488,71 @@ func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
 		fs.CountError(err)
 		fs.Errorf(dst, "Couldn't %s: %v", action, err)
 	} else if !fs.Config.DryRun {
-		fs.Infof(dst, actioned)
+		fs.Infof(dst, "%s%v", actioned, fs.LogValueHide("event", event))
 	}
 	accounting.Stats.DoneChecking(dst.Remote())
 	return err
 }
 
+// MoveBackupDir moves dst into backupDir adding --suffix to its name,
+// overwriting any file already there
+func MoveBackupDir(backupDir fs.Fs, dst fs.Object) (err error) {
+	remoteWithSuffix := SuffixName(dst.Remote())
+	overwritten, _ := backupDir.NewObject(remoteWithSuffix)
+	_, err = Move(backupDir, overwritten, remoteWithSuffix, dst)
+	return err
+}
+
+// CompareOrCopyDest checks --compare-dest and --copy-dest to see if
+// src needs to be transferred to fdst.
+//
+// If --compare-dest is set and an identical file to src is found in
+// compareOrCopyDest then src doesn't need transferring.
+//
+// If --copy-dest is set and an identical file to src is found in
+// compareOrCopyDest then it is copied into fdst with a server side
+// copy, moving dst into backupDir first if set.
+//
+// Returns true if src does not need to be transferred.  backedUp is
+// set if dst was moved into backupDir, even if the copy then failed,
+// so the caller mustn't back it up again.
+func CompareOrCopyDest(fdst fs.Fs, dst, src fs.Object, compareOrCopyDest fs.Fs, backupDir fs.Fs) (noNeedTransfer, backedUp bool, err error) {
+	if fs.Config.CompareDest == "" && fs.Config.CopyDest == "" {
+		return false, false, nil
+	}
+	destFile, err := compareOrCopyDest.NewObject(src.Remote())
+	switch err {
+	case nil:
+	case fs.ErrorObjectNotFound:
+		return false, false, nil
+	default:
+		return false, false, err
+	}
+	if !Equal(src, destFile) {
+		return false, false, nil
+	}
+	if fs.Config.CompareDest != "" {
+		fs.Debugf(src, "Destination found in --compare-dest, skipping%v", fs.LogValueHide("event", "skipped"))
+		return true, false, nil
+	}
+	if dst != nil && backupDir != nil {
+		err = MoveBackupDir(backupDir, dst)
+		if err != nil {
+			return false, false, errors.Wrap(err, "moving to --backup-dir failed")
+		}
+		// dst is no longer there
+		dst = nil
+		backedUp = true
+	}
+	_, err = Copy(fdst, dst, src.Remote(), destFile)
+	if err != nil {
+		fs.Logf(src, "Failed to copy from --copy-dest, transferring instead: %v", err)
+		return false, backedUp, nil
+	}
+	fs.Debugf(src, "Destination found in --copy-dest, using server side copy")
+	return true, backedUp, nil
+}
+
 // DeleteFile deletes a single file respecting --dry-run and accumulating stats and errors.
 //
 // If useBackupDir is set and --backup-dir is in effect then it moves
@@ -535,33 +639,43 @@ func Overlapping(fdst, fsrc fs.Info) bool {
 //
 // it returns true if differences were found
 // it also returns whether it couldn't be hashed
-func checkIdentical(dst, src fs.Object) (differ bool, noHash bool) {
+func checkIdentical(dst, src fs.Object) (differ bool, noHash bool, err error) {
 	same, ht, err := CheckHashes(src, dst)
 	if err != nil {
 		// CheckHashes will log and count errors
-		return true, false
+		return true, false, err
 	}
 	if ht == hash.None {
-		return false, true
+		return false, true, nil
 	}
 	if !same {
 		err = errors.Errorf("%v differ", ht)
 		fs.Errorf(src, "%v", err)
 		fs.CountError(err)
-		return true, false
+		return true, false, nil
 	}
-	return false, false
+	return false, false, nil
 }
 
 // checkFn is the the type of the checking function used in CheckFn()
-type checkFn func(a, b fs.Object) (differ bool, noHash bool)
+//
+// It should return differ = true if differences were found and
+// noHash = true if the files couldn't be hashed.  If the check
+// couldn't be done it should log and count the error and return it.
+type checkFn func(a, b fs.Object) (differ bool, noHash bool, err error)
+
+// CheckOpt contains options for the Check functions
+type CheckOpt struct {
+	Fdst, Fsrc fs.Fs   // fses to check
+	Check      checkFn // function to use for checking
+	OneWay     bool    // one way only?
+	Report     *Report // if set, the paths checked are reported here
+}
 
 // checkMarch is used to march over two Fses in the same way as
 // sync/copy
 type checkMarch struct {
-	fdst, fsrc      fs.Fs
-	check           checkFn
-	oneway          bool
+	opt             CheckOpt
 	differences     int32
 	noHashes        int32
 	srcFilesMissing int32
@@ -572,14 +686,15 @@ type checkMarch struct {
 func (c *checkMarch) DstOnly(dst fs.DirEntry) (recurse bool) {
 	switch dst.(type) {
 	case fs.Object:
-		if c.oneway {
+		if c.opt.OneWay {
 			return false
 		}
-		err := errors.Errorf("File not in %v", c.fsrc)
+		err := errors.Errorf("File not in %v", c.opt.Fsrc)
 		fs.Errorf(dst, "%v", err)
 		fs.CountError(err)
 		atomic.AddInt32(&c.differences, 1)
 		atomic.AddInt32(&c.srcFilesMissing, 1)
+		c.opt.Report.Add(ReportMissingOnSrc, dst.Remote())
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
 		return true
@@ -593,11 +708,12 @@ func (c *checkMarch) DstOnly(dst fs.DirEntry) (recurse bool) {
 func (c *checkMarch) SrcOnly(src fs.DirEntry) (recurse bool) {
 	switch src.(type) {
 	case fs.Object:
-		err := errors.Errorf("File not in %v", c.fdst)
+		err := errors.Errorf("File not in %v", c.opt.Fdst)
 		fs.Errorf(src, "%v", err)
 		fs.CountError(err)
 		atomic.AddInt32(&c.differences, 1)
 		atomic.AddInt32(&c.dstFilesMissing, 1)
+		c.opt.Report.Add(ReportMissingOnDst, src.Remote())
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
 		return true
@@ -608,19 +724,19 @@ func (c *checkMarch) SrcOnly(src fs.DirEntry) (recurse bool) {
 }
 
 // check to see if two objects are identical using the check function
-func (c *checkMarch) checkIdentical(dst, src fs.Object) (differ bool, noHash bool) {
+func (c *checkMarch) checkIdentical(dst, src fs.Object) (differ bool, noHash bool, err error) {
 	accounting.Stats.Checking(src.Remote())
 	defer accounting.Stats.DoneChecking(src.Remote())
 	if sizeDiffers(src, dst) {
 		err := errors.Errorf("Sizes differ")
 		fs.Errorf(src, "%v", err)
 		fs.CountError(err)
-		return true, false
+		return true, false, nil
 	}
 	if fs.Config.SizeOnly {
-		return false, false
+		return false, false, nil
 	}
-	return c.check(dst, src)
+	return c.opt.Check(dst, src)
 }
 
 // Match is called when src and dst are present, so sync src to dst
@@ -629,7 +745,7 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 	case fs.Object:
 		dstX, ok := dst.(fs.Object)
 		if ok {
-			differ, noHash := c.checkIdentical(dstX, srcX)
+			differ, noHash, err := c.checkIdentical(dstX, srcX)
 			if differ {
 				atomic.AddInt32(&c.differences, 1)
 			} else {
@@ -638,12 +754,21 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 			if noHash {
 				atomic.AddInt32(&c.noHashes, 1)
 			}
+			switch {
+			case err != nil:
+				c.opt.Report.Add(ReportError, src.Remote())
+			case differ:
+				c.opt.Report.Add(ReportDiffer, src.Remote())
+			default:
+				c.opt.Report.Add(ReportMatch, src.Remote())
+			}
 		} else {
-			err := errors.Errorf("is file on %v but directory on %v", c.fsrc, c.fdst)
+			err := errors.Errorf("is file on %v but directory on %v", c.opt.Fsrc, c.opt.Fdst)
 			fs.Errorf(src, "%v", err)
 			fs.CountError(err)
 			atomic.AddInt32(&c.differences, 1)
 			atomic.AddInt32(&c.dstFilesMissing, 1)
+			c.opt.Report.Add(ReportMissingOnDst, src.Remote())
 		}
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
@@ -651,11 +776,12 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 		if ok {
 			return true
 		}
-		err := errors.Errorf("is file on %v but directory on %v", c.fdst, c.fsrc)
+		err := errors.Errorf("is file on %v but directory on %v", c.opt.Fdst, c.opt.Fsrc)
 		fs.Errorf(dst, "%v", err)
 		fs.CountError(err)
 		atomic.AddInt32(&c.differences, 1)
 		atomic.AddInt32(&c.srcFilesMissing, 1)
+		c.opt.Report.Add(ReportMissingOnSrc, dst.Remote())
 
 	default:
 		panic("Bad object in DirEntries")
@@ -663,36 +789,36 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 	return false
 }
 
-// CheckFn checks the files in fsrc and fdst according to Size and
-// hash using checkFunction on each file to check the hashes.
+// CheckFn checks the files in opt.Fsrc and opt.Fdst according to
+// Size and hash using opt.Check on each file to check the hashes.
 //
-// checkFunction sees if dst and src are identical
+// opt.Check sees if dst and src are identical
 //
 // it returns true if differences were found
 // it also returns whether it couldn't be hashed
-func CheckFn(fdst, fsrc fs.Fs, check checkFn, oneway bool) error {
+func CheckFn(opt *CheckOpt) error {
+	if opt.Check == nil {
+		return errors.New("internal error: nil check function")
+	}
 	c := &checkMarch{
-		fdst:   fdst,
-		fsrc:   fsrc,
-		check:  check,
-		oneway: oneway,
+		opt: *opt,
 	}
 
 	// set up a march over fdst and fsrc
-	m := march.New(context.Background(), fdst, fsrc, "", c)
-	fs.Infof(fdst, "Waiting for checks to finish")
+	m := march.New(context.Background(), c.opt.Fdst, c.opt.Fsrc, "", c)
+	fs.Infof(c.opt.Fdst, "Waiting for checks to finish")
 	m.Run()
 
 	if c.dstFilesMissing > 0 {
-		fs.Logf(fdst, "%d files missing", c.dstFilesMissing)
+		fs.Logf(c.opt.Fdst, "%d files missing", c.dstFilesMissing)
 	}
 	if c.srcFilesMissing > 0 {
-		fs.Logf(fsrc, "%d files missing", c.srcFilesMissing)
+		fs.Logf(c.opt.Fsrc, "%d files missing", c.srcFilesMissing)
 	}
 
-	fs.Logf(fdst, "%d differences found", accounting.Stats.GetErrors())
+	fs.Logf(c.opt.Fdst, "%d differences found", accounting.Stats.GetErrors())
 	if c.noHashes > 0 {
-		fs.Logf(fdst, "%d hashes could not be checked", c.noHashes)
+		fs.Logf(c.opt.Fdst, "%d hashes could not be checked", c.noHashes)
 	}
 	if c.differences > 0 {
 		return errors.Errorf("%d differences found", c.differences)
@@ -701,21 +827,35 @@ func CheckFn(fdst, fsrc fs.Fs, check checkFn, oneway bool) error {
 }
 
 // Check the files in fsrc and fdst according to Size and hash
-func Check(fdst, fsrc fs.Fs, oneway bool) error {
-	return CheckFn(fdst, fsrc, checkIdentical, oneway)
+func Check(opt *CheckOpt) error {
+	optCopy := *opt
+	optCopy.Check = checkIdentical
+	return CheckFn(&optCopy)
 }
 
 // CheckEqualReaders checks to see if in1 and in2 have the same
 // content when read.
 //
+// in1 and in2 are read concurrently.
+//
 // it returns true if differences were found
 func CheckEqualReaders(in1, in2 io.Reader) (differ bool, err error) {
 	const bufSize = 64 * 1024
 	buf1 := make([]byte, bufSize)
 	buf2 := make([]byte, bufSize)
 	for {
+		var (
+			wg   sync.WaitGroup
+			n2   int
+			err2 error
+		)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n2, err2 = readers.ReadFill(in2, buf2)
+		}()
 		n1, err1 := readers.ReadFill(in1, buf1)
-		n2, err2 := readers.ReadFill(in2, buf2)
+		wg.Wait()
 		// check errors
 		if err1 != nil && err1 != io.EOF {
 			return true, err1
@@ -757,19 +897,51 @@ func CheckIdentical(dst, src fs.Object) (differ bool, err error) {
 	return CheckEqualReaders(in1, in2)
 }
 
-// CheckDownload checks the files in fsrc and fdst according to Size
-// and the actual contents of the files.
-func CheckDownload(fdst, fsrc fs.Fs, oneway bool) error {
-	check := func(a, b fs.Object) (differ bool, noHash bool) {
-		differ, err := CheckIdentical(a, b)
-		if err != nil {
-			fs.CountError(err)
-			fs.Errorf(a, "Failed to download: %v", err)
-			return true, true
+// CheckIdenticalDownload checks to see if dst and src are identical
+// by downloading both of them and comparing the contents.
+//
+// It logs and counts any differences or errors so it is suitable for
+// use as the Check function in CheckOpt.
+func CheckIdenticalDownload(dst, src fs.Object) (differ bool, noHash bool, err error) {
+	differ, err = CheckIdentical(dst, src)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to download: %v", err)
+		return true, true, err
+	}
+	if differ {
+		err = errors.New("contents differ")
+		fs.Errorf(src, "%v", err)
+		fs.CountError(err)
+	}
+	return differ, false, nil
+}
+
+// checkIdenticalOrDownload checks to see if dst and src are
+// identical using a common hash if there is one, otherwise by
+// downloading them.
+//
+// If --ignore-checksum is set it always downloads them.
+func checkIdenticalOrDownload(dst, src fs.Object) (differ bool, noHash bool, err error) {
+	if !fs.Config.IgnoreChecksum {
+		differ, noHash, err = checkIdentical(dst, src)
+		if err != nil || !noHash {
+			return differ, noHash, err
 		}
-		return differ, false
+		fs.Debugf(src, "No common hash - downloading to check")
 	}
-	return CheckFn(fdst, fsrc, check, oneway)
+	return CheckIdenticalDownload(dst, src)
+}
+
+// CheckDownload checks the files in fsrc and fdst according to Size
+// and a common hash if there is one.  Files which can't be checked
+// with a hash are downloaded and their contents compared.
+//
+// If --ignore-checksum is set then all the files are downloaded.
+func CheckDownload(opt *CheckOpt) error {
+	optCopy := *opt
+	optCopy.Check = checkIdenticalOrDownload
+	return CheckFn(&optCopy)
 }
 
 // ListFn lists the Fs to the supplied function
@@ -982,7 +1154,7 @@ func Purge(f fs.Fs, dir string) error {
 // Delete removes all the contents of a container.  Unlike Purge, it
 // obeys includes and excludes.
 func Delete(f fs.Fs) error {
-	delChan := make(fs.ObjectsChan, fs.Config.Transfers)
+	delChan := make(fs.ObjectsChan, fs.GetConfigFs(f).Transfers)
 	delErr := make(chan error, 1)
 	go func() {
 		delErr <- DeleteFiles(delChan)
@@ -1005,7 +1177,7 @@ func Delete(f fs.Fs) error {
 //
 // If the error was ErrorDirNotFound then it will be ignored
 func listToChan(f fs.Fs, dir string) fs.ObjectsChan {
-	o := make(fs.ObjectsChan, fs.Config.Checkers)
+	o := make(fs.ObjectsChan, fs.GetConfigFs(f).Checkers)
 	go func() {
 		defer close(o)
 		_ = walk.Walk(f, dir, true, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
@@ -1040,6 +1212,16 @@ func CleanUp(f fs.Fs) error {
 	return doCleanUp()
 }
 
+// Command runs the backend command name on f with the arguments arg
+// and the options opt, returning the result.
+func Command(f fs.Fs, name string, arg []string, opt map[string]string) (interface{}, error) {
+	doCommand := f.Features().Command
+	if doCommand == nil {
+		return nil, errors.Errorf("%v doesn't support backend commands", f)
+	}
+	return doCommand(name, arg, opt)
+}
+
 // wrap a Reader and a Closer together into a ReadCloser
 type readCloser struct {
 	io.Reader
@@ -1109,7 +1291,7 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 // Rcat reads data from the Reader until EOF and uploads it to a file on remote
 func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (dst fs.Object, err error) {
 	accounting.Stats.Transferring(dstFileName)
-	in = accounting.NewAccountSizeName(in, -1, dstFileName).WithBuffer()
+	in = accounting.NewAccountSizeName(in, -1, dstFileName).WithRemotes(fdst).WithBuffer()
 	defer func() {
 		accounting.Stats.DoneTransferring(dstFileName, err == nil)
 		if otherErr := in.Close(); otherErr != nil {
@@ -1271,7 +1453,7 @@ func NeedTransfer(dst, src fs.Object) bool {
 	}
 	// If we should ignore existing files, don't transfer
 	if fs.Config.IgnoreExisting {
-		fs.Debugf(src, "Destination exists, skipping")
+		fs.Debugf(src, "Destination exists, skipping%v", fs.LogValueHide("event", "skipped"))
 		return false
 	}
 	// If we should upload unconditionally
@@ -1294,13 +1476,13 @@ func NeedTransfer(dst, src fs.Object) bool {
 		}
 		switch {
 		case dt >= modifyWindow:
-			fs.Debugf(src, "Destination is newer than source, skipping")
+			fs.Debugf(src, "Destination is newer than source, skipping%v", fs.LogValueHide("event", "skipped"))
 			return false
 		case dt <= -modifyWindow:
 			fs.Debugf(src, "Destination is older than source, transferring")
 		default:
 			if src.Size() == dst.Size() {
-				fs.Debugf(src, "Destination mod time is within %v of source and sizes identical, skipping", modifyWindow)
+				fs.Debugf(src, "Destination mod time is within %v of source and sizes identical, skipping%v", modifyWindow, fs.LogValueHide("event", "skipped"))
 				return false
 			}
 			fs.Debugf(src, "Destination mod time is within %v of source but sizes differ, transferring", modifyWindow)
@@ -1308,7 +1490,7 @@ func NeedTransfer(dst, src fs.Object) bool {
 	} else {
 		// Check to see if changed or not
 		if Equal(src, dst) {
-			fs.Debugf(src, "Unchanged skipping")
+			fs.Debugf(src, "Unchanged skipping%v", fs.LogValueHide("event", "skipped"))
 			return false
 		}
 	}
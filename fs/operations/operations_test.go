@@ -26,6 +26,7 @@ import (
 	"io"
 	"io/ioutil"
 	"regexp"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -238,14 +239,20 @@ func TestDelete(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file3)
 }
 
-func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs, oneway bool) error) {
+func testCheck(t *testing.T, checkFunction func(opt *operations.CheckOpt) error) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
-	check := func(i int, wantErrors int64, oneway bool) {
+	check := func(i int, wantErrors int64, oneway bool, wantCombined ...string) {
 		fs.Debugf(r.Fremote, "%d: Starting check test", i)
 		oldErrors := accounting.Stats.GetErrors()
-		err := checkFunction(r.Fremote, r.Flocal, oneway)
+		var combined bytes.Buffer
+		err := checkFunction(&operations.CheckOpt{
+			Fdst:   r.Fremote,
+			Fsrc:   r.Flocal,
+			OneWay: oneway,
+			Report: &operations.Report{Combined: &combined},
+		})
 		gotErrors := accounting.Stats.GetErrors() - oldErrors
 		if wantErrors == 0 && err != nil {
 			t.Errorf("%d: Got error when not expecting one: %v", i, err)
@@ -256,21 +263,26 @@ func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs, oneway bool) e
 		if wantErrors != gotErrors {
 			t.Errorf("%d: Expecting %d errors but got %d", i, wantErrors, gotErrors)
 		}
+		// the checks run concurrently so the report isn't ordered
+		gotCombined := strings.Split(strings.TrimSpace(combined.String()), "\n")
+		sort.Strings(gotCombined)
+		sort.Strings(wantCombined)
+		assert.Equal(t, wantCombined, gotCombined, fmt.Sprintf("%d: combined report", i))
 		fs.Debugf(r.Fremote, "%d: Ending check test", i)
 	}
 
 	file1 := r.WriteBoth("rutabaga", "is tasty", t3)
 	fstest.CheckItems(t, r.Fremote, file1)
 	fstest.CheckItems(t, r.Flocal, file1)
-	check(1, 0, false)
+	check(1, 0, false, "= rutabaga")
 
 	file2 := r.WriteFile("potato2", "------------------------------------------------------------", t1)
 	fstest.CheckItems(t, r.Flocal, file1, file2)
-	check(2, 1, false)
+	check(2, 1, false, "= rutabaga", "- potato2")
 
 	file3 := r.WriteObject("empty space", "", t2)
 	fstest.CheckItems(t, r.Fremote, file1, file3)
-	check(3, 2, false)
+	check(3, 2, false, "= rutabaga", "- potato2", "+ empty space")
 
 	file2r := file2
 	if fs.Config.SizeOnly {
@@ -279,16 +291,16 @@ func testCheck(t *testing.T, checkFunction func(fdst, fsrc fs.Fs, oneway bool) e
 		r.WriteObject("potato2", "------------------------------------------------------------", t1)
 	}
 	fstest.CheckItems(t, r.Fremote, file1, file2r, file3)
-	check(4, 1, false)
+	check(4, 1, false, "= rutabaga", "= potato2", "+ empty space")
 
 	r.WriteFile("empty space", "", t2)
 	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
-	check(5, 0, false)
+	check(5, 0, false, "= rutabaga", "= potato2", "= empty space")
 
 	file4 := r.WriteObject("remotepotato", "------------------------------------------------------------", t1)
 	fstest.CheckItems(t, r.Fremote, file1, file2r, file3, file4)
-	check(6, 1, false)
-	check(7, 0, true)
+	check(6, 1, false, "= rutabaga", "= potato2", "= empty space", "+ remotepotato")
+	check(7, 0, true, "= rutabaga", "= potato2", "= empty space")
 }
 
 func TestCheck(t *testing.T) {
@@ -299,12 +311,125 @@ func TestCheckDownload(t *testing.T) {
 	testCheck(t, operations.CheckDownload)
 }
 
+func TestCheckDownloadIgnoreChecksum(t *testing.T) {
+	fs.Config.IgnoreChecksum = true
+	defer func() { fs.Config.IgnoreChecksum = false }()
+	testCheck(t, operations.CheckDownload)
+}
+
+// Check files of the same size with different contents are found
+// whether checked by hash or by downloading
+func TestCheckDownloadDiffer(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.WriteFile("potato", "aaaaaaaa", t1)
+	r.WriteObject("potato", "bbbbbbbb", t1)
+
+	for _, ignoreChecksum := range []bool{false, true} {
+		fs.Config.IgnoreChecksum = ignoreChecksum
+		var differ bytes.Buffer
+		oldErrors := accounting.Stats.GetErrors()
+		err := operations.CheckDownload(&operations.CheckOpt{
+			Fdst:   r.Fremote,
+			Fsrc:   r.Flocal,
+			Report: &operations.Report{Differ: &differ},
+		})
+		assert.Error(t, err, fmt.Sprintf("ignoreChecksum=%v", ignoreChecksum))
+		assert.Equal(t, int64(1), accounting.Stats.GetErrors()-oldErrors)
+		assert.Equal(t, "potato\n", differ.String())
+	}
+	fs.Config.IgnoreChecksum = false
+}
+
 func TestCheckSizeOnly(t *testing.T) {
 	fs.Config.SizeOnly = true
 	defer func() { fs.Config.SizeOnly = false }()
 	TestCheck(t)
 }
 
+func TestCheckSum(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("potato", "hello", t1)
+	file2 := r.WriteObject("sub/carrot", "world", t1)
+	file3 := r.WriteObject("extra", "not in the sum file", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	const sumFile = `# comment
+5d41402abc4b2a76b9719d911017c592  potato
+5d41402abc4b2a76b9719d911017c592 *./sub/carrot
+d41d8cd98f00b204e9800998ecf8427e  missing
+`
+	check := func(name string, wantErrors int64, download, oneway bool, wantCombined ...string) {
+		oldErrors := accounting.Stats.GetErrors()
+		var combined bytes.Buffer
+		err := operations.CheckSum(strings.NewReader(sumFile), hash.MD5, download, &operations.CheckOpt{
+			Fdst:   r.Fremote,
+			OneWay: oneway,
+			Report: &operations.Report{Combined: &combined},
+		})
+		assert.Error(t, err, name)
+		assert.Equal(t, wantErrors, accounting.Stats.GetErrors()-oldErrors, name)
+		// the checks run concurrently so the report isn't ordered
+		gotCombined := strings.Split(strings.TrimSpace(combined.String()), "\n")
+		sort.Strings(gotCombined)
+		sort.Strings(wantCombined)
+		assert.Equal(t, wantCombined, gotCombined, name)
+	}
+	check("hash", 3, false, false, "= potato", "* sub/carrot", "+ extra", "- missing")
+	check("download", 3, true, false, "= potato", "* sub/carrot", "+ extra", "- missing")
+	check("oneway", 2, false, true, "= potato", "* sub/carrot", "- missing")
+
+	require.NoError(t, filter.Active.AddRule("- missing"))
+	defer filter.Active.Clear()
+	check("filter", 2, false, false, "= potato", "* sub/carrot", "+ extra")
+
+	err := operations.CheckSum(strings.NewReader("potato\n"), hash.MD5, false, &operations.CheckOpt{Fdst: r.Fremote})
+	assert.EqualError(t, err, `failed to read sum file: line 1: badly formed sum line "potato"`)
+	err = operations.CheckSum(strings.NewReader(sumFile), hash.SHA1, false, &operations.CheckOpt{Fdst: r.Fremote})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has the wrong length")
+}
+
+func TestPruneBackups(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	fs.Config.Suffix = "-{2006-01-02}"
+	fs.Config.SuffixKeepExtension = true
+	defer func() {
+		fs.Config.Suffix = ""
+		fs.Config.SuffixKeepExtension = false
+	}()
+	file1 := r.WriteObject("dir/file-2019-03-20.txt", "3", t1)
+	file2 := r.WriteObject("dir/file-2019-03-19.txt", "2", t1)
+	file3 := r.WriteObject("dir/file-2019-03-18.txt", "1", t1)
+	file4 := r.WriteObject("dir/other-2019-03-01.txt", "1", t1)
+	file5 := r.WriteObject("dir/not a version.txt", "1", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4, file5)
+
+	// No rules so just list
+	var out bytes.Buffer
+	require.NoError(t, operations.PruneBackups(r.Fremote, "", operations.PruneOpt{}, &out))
+	assert.Equal(t, `keep   2019-03-20 00:00:00 dir/file-2019-03-20.txt
+keep   2019-03-19 00:00:00 dir/file-2019-03-19.txt
+keep   2019-03-18 00:00:00 dir/file-2019-03-18.txt
+keep   2019-03-01 00:00:00 dir/other-2019-03-01.txt
+`, out.String())
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4, file5)
+
+	out.Reset()
+	require.NoError(t, operations.PruneBackups(r.Fremote, "dir", operations.PruneOpt{KeepLast: 1}, &out))
+	assert.Equal(t, `keep   2019-03-20 00:00:00 dir/file-2019-03-20.txt
+delete 2019-03-19 00:00:00 dir/file-2019-03-19.txt
+delete 2019-03-18 00:00:00 dir/file-2019-03-18.txt
+keep   2019-03-01 00:00:00 dir/other-2019-03-01.txt
+`, out.String())
+	fstest.CheckItems(t, r.Fremote, file1, file4, file5)
+
+	fs.Config.Suffix = ".bak"
+	assert.Error(t, operations.PruneBackups(r.Fremote, "", operations.PruneOpt{KeepLast: 1}, &out))
+}
+
 func TestCat(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -523,6 +648,39 @@ func TestCopyFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+func TestCopyFileMultiThread(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Flocal.Features().OpenWriterAt == nil {
+		t.Skip("OpenWriterAt not supported")
+	}
+
+	oldCutoff, oldStreams := fs.Config.MultiThreadCutoff, fs.Config.MultiThreadStreams
+	defer func() {
+		fs.Config.MultiThreadCutoff, fs.Config.MultiThreadStreams = oldCutoff, oldStreams
+	}()
+	fs.Config.MultiThreadCutoff = 1024
+	fs.Config.MultiThreadStreams = 4
+
+	contents := strings.Repeat("0123456789abcdef", 100000)
+	file1 := r.WriteObject("file1", contents, t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+	fstest.CheckItems(t, r.Flocal)
+
+	// Copy from the remote to the local backend which supports
+	// OpenWriterAt
+	err := operations.CopyFile(r.Flocal, r.Fremote, file1.Path, file1.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// Check that an existing file is replaced
+	file2 := r.WriteObject("file1", contents[:len(contents)/2]+"changed", t2)
+	err = operations.CopyFile(r.Flocal, r.Fremote, file2.Path, file2.Path)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, file2)
+}
+
 // testFsInfo is for unit testing fs.Info
 type testFsInfo struct {
 	name      string
@@ -0,0 +1,96 @@
+// Remote control for the operations
+
+package operations
+
+import (
+	"fmt"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "backend/command",
+		Fn:    rcBackend,
+		Title: "Runs a backend command.",
+		Help: `
+This takes the following parameters
+
+- command - a string with the command name
+- fs - a remote name string eg "drive:"
+- arg - a list of arguments for the backend command
+- opt - a map of string to string of options
+
+Returns
+
+- result - result from the backend command
+
+For example
+
+    rclone rc backend/command command=restore fs=s3:bucket/path/to/file -o priority=Bulk
+
+Use -o/--opt and -a/--arg to set the opt and arg parameters with
+"rclone rc".  In a JSON request they are passed as a map and a list, eg
+
+    {
+        "command": "restore",
+        "fs": "s3:bucket/path/to/file",
+        "opt": {"priority": "Bulk"}
+    }
+
+See [rclone backend](/commands/rclone_backend/) for more information
+and the documentation of each backend for the commands it supports.
+`,
+	})
+}
+
+// Run a backend command
+func rcBackend(in rc.Params) (out rc.Params, err error) {
+	command, ok := in["command"].(string)
+	if !ok || command == "" {
+		return nil, errors.New("need command parameter")
+	}
+	fsName, ok := in["fs"].(string)
+	if !ok || fsName == "" {
+		return nil, errors.New("need fs parameter")
+	}
+	var arg []string
+	switch value := in["arg"].(type) {
+	case nil:
+	case string:
+		arg = []string{value}
+	case []string:
+		arg = value
+	case []interface{}:
+		for _, item := range value {
+			arg = append(arg, fmt.Sprint(item))
+		}
+	default:
+		return nil, errors.Errorf("expecting list of strings for arg but got %T", value)
+	}
+	opt := map[string]string{}
+	switch value := in["opt"].(type) {
+	case nil:
+	case map[string]string:
+		opt = value
+	case map[string]interface{}:
+		for k, v := range value {
+			opt[k] = fmt.Sprint(v)
+		}
+	default:
+		return nil, errors.Errorf("expecting map of strings for opt but got %T", value)
+	}
+	f, err := fs.NewFs(fsName)
+	if err != nil {
+		return nil, err
+	}
+	result, err := Command(f, command, arg, opt)
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{
+		"result": result,
+	}, nil
+}
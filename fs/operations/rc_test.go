@@ -0,0 +1,50 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRcBackend(t *testing.T) {
+	// Missing parameters
+	_, err := rcBackend(rc.Params{"fs": "."})
+	assert.Error(t, err)
+	_, err = rcBackend(rc.Params{"command": "noop"})
+	assert.Error(t, err)
+
+	// Run the local backend's noop command
+	out, err := rcBackend(rc.Params{
+		"command": "noop",
+		"fs":      ".",
+		"arg":     []interface{}{"path1", "path2"},
+		"opt":     map[string]interface{}{"echo": "yes", "blue": ""},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"result": map[string]interface{}{
+			"name": "noop",
+			"arg":  []string{"path1", "path2"},
+			"opt":  map[string]string{"echo": "yes", "blue": ""},
+		},
+	}, out)
+
+	// Errors from the command are returned
+	_, err = rcBackend(rc.Params{
+		"command": "noop",
+		"fs":      ".",
+		"opt":     map[string]string{"error": "potato"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, "potato", err.Error())
+
+	// Unknown commands
+	_, err = rcBackend(rc.Params{
+		"command": "not-a-command",
+		"fs":      ".",
+	})
+	assert.Equal(t, fs.ErrorCommandNotFound, err)
+}
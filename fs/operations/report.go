@@ -0,0 +1,63 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Status characters used in the Combined output of a Report
+const (
+	ReportMatch        = '=' // path is identical in source and destination
+	ReportMissingOnSrc = '+' // path only exists in the destination
+	ReportMissingOnDst = '-' // path only exists in the source
+	ReportDiffer       = '*' // path exists in both but differs
+	ReportError        = '!' // there was an error processing the path
+)
+
+// Report contains the writers for the lists of paths processed by
+// check, sync, copy and move.  Each list has one path per line.
+//
+// Any of the writers may be nil in which case that list isn't
+// written.
+type Report struct {
+	mu           sync.Mutex
+	Combined     io.Writer // every path with a status character and a space prefixed
+	MissingOnSrc io.Writer // paths only in the destination
+	MissingOnDst io.Writer // paths only in the source
+	Match        io.Writer // paths which were identical
+	Differ       io.Writer // paths which differed
+	Error        io.Writer // paths which had an error
+}
+
+// Add writes remote to the list for status and to the combined list.
+//
+// It is safe to call on a nil *Report which does nothing.
+func (r *Report) Add(status byte, remote string) {
+	if r == nil {
+		return
+	}
+	var w io.Writer
+	switch status {
+	case ReportMatch:
+		w = r.Match
+	case ReportMissingOnSrc:
+		w = r.MissingOnSrc
+	case ReportMissingOnDst:
+		w = r.MissingOnDst
+	case ReportDiffer:
+		w = r.Differ
+	case ReportError:
+		w = r.Error
+	default:
+		panic(fmt.Sprintf("unknown report status %q", status))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w != nil {
+		_, _ = fmt.Fprintln(w, remote)
+	}
+	if r.Combined != nil {
+		_, _ = fmt.Fprintf(r.Combined, "%c %s\n", status, remote)
+	}
+}
@@ -0,0 +1,38 @@
+package operations
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportAdd(t *testing.T) {
+	var combined, missingOnSrc, missingOnDst, match, differ bytes.Buffer
+	r := &Report{
+		Combined:     &combined,
+		MissingOnSrc: &missingOnSrc,
+		MissingOnDst: &missingOnDst,
+		Match:        &match,
+		Differ:       &differ,
+		// Error left nil
+	}
+	r.Add(ReportMatch, "a")
+	r.Add(ReportMissingOnSrc, "b")
+	r.Add(ReportMissingOnDst, "c")
+	r.Add(ReportDiffer, "d")
+	r.Add(ReportError, "e")
+	r.Add(ReportMatch, "dir/f")
+
+	assert.Equal(t, "= a\n+ b\n- c\n* d\n! e\n= dir/f\n", combined.String())
+	assert.Equal(t, "b\n", missingOnSrc.String())
+	assert.Equal(t, "c\n", missingOnDst.String())
+	assert.Equal(t, "a\ndir/f\n", match.String())
+	assert.Equal(t, "d\n", differ.String())
+
+	assert.Panics(t, func() { r.Add('?', "g") })
+
+	// check a nil report does nothing
+	var nilReport *Report
+	nilReport.Add(ReportMatch, "a")
+}
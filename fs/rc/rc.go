@@ -40,10 +40,27 @@ func init() {
 func Start(opt *Options) {
 	if opt.Enabled {
 		s := newServer(opt)
-		go s.serve()
+		go func() {
+			err := s.serve()
+			if err != nil {
+				fs.Errorf(nil, "Opening listener: %v", err)
+			}
+		}()
 	}
 }
 
+// Serve runs the remote control server until it is stopped whether
+// or not opt.Enabled is set.  It returns an error if the server
+// couldn't be started.
+func Serve(opt *Options) error {
+	s := newServer(opt)
+	err := s.serve()
+	if err != nil {
+		return errors.Wrap(err, "opening listener")
+	}
+	return nil
+}
+
 // server contains everything to run the server
 type server struct {
 	srv *httplib.Server
@@ -59,14 +76,15 @@ func newServer(opt *Options) *server {
 	return s
 }
 
-// serve runs the http server - doesn't return
-func (s *server) serve() {
+// serve runs the http server until it is stopped
+func (s *server) serve() error {
 	err := s.srv.Serve()
 	if err != nil {
-		fs.Errorf(nil, "Opening listener: %v", err)
+		return err
 	}
 	fs.Logf(nil, "Serving remote control on %s", s.srv.URL())
 	s.srv.Wait()
+	return nil
 }
 
 // WriteJSON writes JSON in out to w
@@ -76,6 +94,23 @@ func WriteJSON(w io.Writer, out Params) error {
 	return enc.Encode(out)
 }
 
+// ParseOptions parses a slice of options in the form key=value or
+// key into a map.  An option without a value is set to "true".
+func ParseOptions(options []string) (opt map[string]string) {
+	opt = make(map[string]string, len(options))
+	for _, option := range options {
+		equals := strings.IndexRune(option, '=')
+		key := option
+		value := "true"
+		if equals >= 0 {
+			key = option[:equals]
+			value = option[equals+1:]
+		}
+		opt[key] = value
+	}
+	return opt
+}
+
 // handler reads incoming requests and dispatches them
 func (s *server) handler(w http.ResponseWriter, r *http.Request) {
 	path := strings.Trim(r.URL.Path, "/")
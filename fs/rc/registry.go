@@ -3,6 +3,7 @@
 package rc
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
@@ -13,6 +14,16 @@ import (
 // Params is the input and output type for the Func
 type Params map[string]interface{}
 
+// GetString reads key from p as a string, returning "" if it isn't
+// set
+func (p Params) GetString(key string) string {
+	value, ok := p[key]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
 // Func defines a type for a remote control function
 type Func func(in Params) (out Params, err error)
 
@@ -77,3 +88,8 @@ var registry = NewRegistry()
 func Add(call Call) {
 	registry.add(call)
 }
+
+// Find a Call in the global registry from a path or nil if not found
+func Find(path string) *Call {
+	return registry.get(strings.Trim(path, "/"))
+}
@@ -0,0 +1,210 @@
+// Parse cron expressions
+
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// cronField describes one of the fields of a cron expression
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// cronDescriptors are the shorthands for common cron expressions
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronSchedule is a parsed cron expression
+type cronSchedule struct {
+	every   time.Duration // if set run at this interval instead
+	minute  uint64        // bit set for each minute to run in
+	hour    uint64        // bit set for each hour to run in
+	dom     uint64        // bit set for each day of the month to run on
+	month   uint64        // bit set for each month to run in
+	dow     uint64        // bit set for each day of the week to run on
+	domStar bool          // set if the day of the month was *
+	dowStar bool          // set if the day of the week was *
+}
+
+// value parses a single number or name for the field
+func (f *cronField) value(s string) (int, error) {
+	if n, ok := f.names[strings.ToLower(s)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("bad %s %q", f.name, s)
+	}
+	return n, nil
+}
+
+// parse a field of a cron expression into a bit set with a bit for
+// each value it matches
+//
+// The field is a comma separated list of *, a value or a range of
+// values like 1-5, each optionally followed by /step.
+func (f *cronField) parse(field string) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		slash := strings.IndexRune(part, '/')
+		if slash >= 0 {
+			rangePart = part[:slash]
+			step, err = strconv.Atoi(part[slash+1:])
+			if err != nil || step <= 0 {
+				return 0, errors.Errorf("bad step in %s %q", f.name, part)
+			}
+		}
+		var lo, hi int
+		if rangePart == "*" {
+			lo, hi = f.min, f.max
+		} else if dash := strings.IndexRune(rangePart, '-'); dash >= 0 {
+			lo, err = f.value(rangePart[:dash])
+			if err != nil {
+				return 0, err
+			}
+			hi, err = f.value(rangePart[dash+1:])
+			if err != nil {
+				return 0, err
+			}
+		} else {
+			lo, err = f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			hi = lo
+			// 5/15 means every 15 starting at 5
+			if slash >= 0 {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, errors.Errorf("%s %q out of range %d-%d", f.name, part, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseCron parses a cron expression
+//
+// This is 5 fields separated by spaces for the minute, hour, day of
+// the month, month and day of the week, eg "30 2 * * 1-5" for 02:30
+// every week day, or one of the descriptors like @daily, or "@every
+// duration" to run at a fixed interval, eg "@every 90m".
+func parseCron(spec string) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := fs.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad schedule %q", spec)
+		}
+		if every < time.Second {
+			return nil, errors.Errorf("bad schedule %q: interval must be at least 1s", spec)
+		}
+		return &cronSchedule{every: every}, nil
+	}
+	expanded := spec
+	if descriptor, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		expanded = descriptor
+	}
+	fields := strings.Fields(expanded)
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("bad schedule %q: expecting %d fields for minute, hour, day of month, month and day of week", spec, len(cronFields))
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		bits[i], err = cronFields[i].parse(field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad schedule %q", spec)
+		}
+	}
+	// 7 is Sunday as well as 0
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	c := &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+	if c.Next(time.Now()).IsZero() {
+		return nil, errors.Errorf("bad schedule %q: never runs", spec)
+	}
+	return c, nil
+}
+
+// dayMatches returns true if the schedule runs on the day of t
+//
+// As in cron, if both the day of the month and the day of the week
+// are restricted then the schedule runs if either matches.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first time the schedule should run after t or the
+// zero time if it never does.
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	if c.every > 0 {
+		return t.Add(c.every)
+	}
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// every schedule which runs at all runs within 8 years
+	limit := t.AddDate(8, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/potato * * * *",
+		"potato * * * *",
+		"* * 30 feb *",
+		"@potato",
+		"@every potato",
+		"@every 1ms",
+	} {
+		_, err := parseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2019, 1, 16, 10, 30, 20, 0, time.Local)
+	for _, test := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2019, 1, 16, 10, 31, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2019, 1, 16, 10, 45, 0, 0, time.Local)},
+		{"30 * * * *", time.Date(2019, 1, 16, 11, 30, 0, 0, time.Local)},
+		{"0,45 9-11 * * *", time.Date(2019, 1, 16, 10, 45, 0, 0, time.Local)},
+		{"5/20 * * * *", time.Date(2019, 1, 16, 10, 45, 0, 0, time.Local)},
+		{"30 2 * * *", time.Date(2019, 1, 17, 2, 30, 0, 0, time.Local)},
+		{"30 2 * * 1-5", time.Date(2019, 1, 17, 2, 30, 0, 0, time.Local)},
+		{"0 0 * * sat", time.Date(2019, 1, 19, 0, 0, 0, 0, time.Local)},
+		{"0 0 * * 7", time.Date(2019, 1, 20, 0, 0, 0, 0, time.Local)},
+		{"0 0 1 * *", time.Date(2019, 2, 1, 0, 0, 0, 0, time.Local)},
+		{"0 0 31 * *", time.Date(2019, 1, 31, 0, 0, 0, 0, time.Local)},
+		{"0 0 31 feb-dec *", time.Date(2019, 3, 31, 0, 0, 0, 0, time.Local)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.Local)},
+		// either the day of the month or the day of the week
+		{"0 0 20 * fri", time.Date(2019, 1, 18, 0, 0, 0, 0, time.Local)},
+		{"@hourly", time.Date(2019, 1, 16, 11, 0, 0, 0, time.Local)},
+		{"@daily", time.Date(2019, 1, 17, 0, 0, 0, 0, time.Local)},
+		{"@weekly", time.Date(2019, 1, 20, 0, 0, 0, 0, time.Local)},
+		{"@monthly", time.Date(2019, 2, 1, 0, 0, 0, 0, time.Local)},
+		{"@yearly", time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)},
+		{"@every 90m", time.Date(2019, 1, 16, 12, 0, 20, 0, time.Local)},
+	} {
+		c, err := parseCron(test.spec)
+		require.NoError(t, err, test.spec)
+		assert.Equal(t, test.want, c.Next(from), test.spec)
+	}
+}
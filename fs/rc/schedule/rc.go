@@ -0,0 +1,187 @@
+// Remote control for the scheduler
+
+package schedule
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "schedule/add",
+		Fn:    rcAdd,
+		Title: "Add a job to run a remote control call on a schedule.",
+		Help: `
+This takes the following parameters
+
+- schedule - a cron expression saying when to run, see below
+- command - the remote control call to run, eg "backend/command"
+- params - optional parameters for the call as a JSON object
+- name - optional name to describe the job
+
+Returns
+
+- id - the ID of the new job
+- next_run - when it will next run
+
+The schedule is a cron expression with 5 fields separated by spaces
+for the minute, hour, day of the month, month and day of the week,
+eg "30 2 * * 1-5" to run at 02:30 every week day.  Each field can be
+"*", a number, a range like "1-5", a list like "1,3,5" or any of
+these followed by "/step", eg "*/15" in the minute field for every 15
+minutes.  Months and days of the week can be given as names like
+"jan" or "mon".  The times are in the local time zone of rclone.
+
+@yearly, @monthly, @weekly, @daily and @hourly can be used instead,
+or "@every duration" to run at a fixed interval from when the job was
+added or rclone started, eg "@every 90m".
+
+If a job is due to run while its last run hasn't finished then that
+run is skipped.  Runs missed while rclone isn't running aren't made
+up when it starts.
+
+For example
+
+    rclone rc schedule/add schedule="0 3 * * *" command=backend/command params='{"command":"cleanup","fs":"remote:"}'
+
+The schedule/* calls can't themselves be scheduled.
+
+The scheduler only runs in rclone rcd.  The jobs are saved to the
+schedule file and run again each time rclone rcd starts, with all the
+rights rclone has, so the schedule file must only be writable by the
+user running rclone.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:  "schedule/list",
+		Fn:    rcList,
+		Title: "List the scheduled jobs.",
+		Help: `
+This takes no parameters and returns
+
+- jobs - a list of the scheduled jobs
+
+Each job has
+
+- id - its ID
+- name - its name if set
+- schedule - the cron expression saying when to run
+- command - the remote control call it runs
+- params - the parameters for the call
+- created - when it was added
+- running - true if it is running now
+- next_run - when it will next run
+- last_run - when it was last run
+- last_error - the error from the last run or "" if it succeeded
+- runs - the number of times it has been run
+- skipped - the number of runs skipped as the last one hadn't finished
+- history - the last 10 runs, most recent last, each with
+  - start - when the run started
+  - duration - how long it took in seconds
+  - error - the error if it failed
+  - skipped - true if it was skipped
+`,
+	})
+	rc.Add(rc.Call{
+		Path:  "schedule/delete",
+		Fn:    rcDelete,
+		Title: "Delete a scheduled job.",
+		Help: `
+This takes the following parameters
+
+- id - the ID of the job to delete
+
+If the job is running it will be allowed to finish.
+`,
+	})
+}
+
+// getParams reads the params parameter which may be a JSON object
+// or a string containing one
+func getParams(in rc.Params) (params rc.Params, err error) {
+	switch value := in["params"].(type) {
+	case nil:
+		return rc.Params{}, nil
+	case rc.Params:
+		return value, nil
+	case map[string]interface{}:
+		return rc.Params(value), nil
+	case string:
+		if value == "" {
+			return rc.Params{}, nil
+		}
+		err = json.Unmarshal([]byte(value), &params)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse params as a JSON object")
+		}
+		return params, nil
+	default:
+		return nil, errors.Errorf("expecting JSON object for params but got %T", value)
+	}
+}
+
+// Add a scheduled job
+func rcAdd(in rc.Params) (out rc.Params, err error) {
+	s, err := getActive()
+	if err != nil {
+		return nil, err
+	}
+	schedule := in.GetString("schedule")
+	if schedule == "" {
+		return nil, errors.New("need schedule parameter")
+	}
+	command := in.GetString("command")
+	if command == "" {
+		return nil, errors.New("need command parameter")
+	}
+	params, err := getParams(in)
+	if err != nil {
+		return nil, err
+	}
+	job, err := s.Add(in.GetString("name"), schedule, command, params)
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{
+		"id":       job.ID,
+		"next_run": job.NextRun,
+	}, nil
+}
+
+// List the scheduled jobs
+func rcList(in rc.Params) (out rc.Params, err error) {
+	s, err := getActive()
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{
+		"jobs": s.List(),
+	}, nil
+}
+
+// Delete a scheduled job
+func rcDelete(in rc.Params) (out rc.Params, err error) {
+	s, err := getActive()
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	switch value := in["id"].(type) {
+	case float64:
+		id = int64(value)
+	default:
+		id, err = strconv.ParseInt(in.GetString("id"), 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "need integer id parameter")
+		}
+	}
+	err = s.Delete(id)
+	if err != nil {
+		return nil, err
+	}
+	return rc.Params{}, nil
+}
@@ -0,0 +1,429 @@
+// Package schedule implements a scheduler which runs remote control
+// calls at times given by cron expressions.
+package schedule
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+// maxHistory is the number of runs kept in the history of each job
+const maxHistory = 10
+
+// Run is the record of a single run of a Job
+type Run struct {
+	Start    time.Time `json:"start"`
+	Duration float64   `json:"duration"`          // in seconds
+	Error    string    `json:"error,omitempty"`   // error if it failed
+	Skipped  bool      `json:"skipped,omitempty"` // set if skipped as the previous run was still going
+}
+
+// Job is a remote control call to run on a schedule
+type Job struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Schedule  string    `json:"schedule"` // cron expression
+	Command   string    `json:"command"`  // path of the rc call, eg "vfs/refresh"
+	Params    rc.Params `json:"params"`   // parameters for the rc call
+	Created   time.Time `json:"created"`
+	Running   bool      `json:"running"`
+	NextRun   time.Time `json:"next_run"`
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error"`
+	Runs      int64     `json:"runs"`    // number of times run
+	Skipped   int64     `json:"skipped"` // number of runs skipped
+	History   []Run     `json:"history"` // most recent last
+
+	cron *cronSchedule
+}
+
+// addHistory records run in the history of the job
+func (job *Job) addHistory(run Run) {
+	job.History = append(job.History, run)
+	if len(job.History) > maxHistory {
+		job.History = append([]Run(nil), job.History[len(job.History)-maxHistory:]...)
+	}
+}
+
+// Scheduler runs Jobs at the times they are scheduled for, saving
+// them to a file
+type Scheduler struct {
+	mu      sync.Mutex
+	path    string         // file to save the jobs in
+	nextID  int64          // ID for the next job added
+	jobs    map[int64]*Job // jobs by ID
+	wake    chan struct{}  // signal to recalculate when to wake up
+	stop    chan struct{}  // close to stop the scheduler
+	running sync.WaitGroup // jobs running
+	done    sync.WaitGroup // the scheduler loop
+}
+
+// scheduleFile is the format of the file the jobs are saved in
+type scheduleFile struct {
+	Jobs []*Job `json:"jobs"`
+}
+
+// checkCommand checks command is an rc call which can be scheduled
+//
+// The schedule/* calls can't be scheduled so a job can't add more
+// jobs or change the schedule.
+func checkCommand(command string) error {
+	if strings.HasPrefix(command, "schedule/") {
+		return errors.Errorf("can't schedule %q", command)
+	}
+	if rc.Find(command) == nil {
+		return errors.Errorf("couldn't find method %q", command)
+	}
+	return nil
+}
+
+// New makes a Scheduler which saves its jobs in the file at path,
+// loading any jobs already saved there.
+//
+// The jobs in the file are run with all the rights rclone has so it
+// is trusted and should only be writable by the user running rclone.
+// The jobs loaded are checked the same way as those added with Add.
+//
+// Call Start to start running the jobs.
+func New(path string) (*Scheduler, error) {
+	s := &Scheduler{
+		path:   path,
+		nextID: 1,
+		jobs:   map[int64]*Job{},
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read schedule")
+	}
+	var file scheduleFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse schedule %q", path)
+	}
+	now := time.Now()
+	for _, job := range file.Jobs {
+		if job == nil || job.ID <= 0 {
+			return nil, errors.Errorf("job with bad ID in %q", path)
+		}
+		if _, found := s.jobs[job.ID]; found {
+			return nil, errors.Errorf("duplicate job %d in %q", job.ID, path)
+		}
+		job.cron, err = parseCron(job.Schedule)
+		if err == nil {
+			err = checkCommand(job.Command)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "job %d in %q", job.ID, path)
+		}
+		if job.Params == nil {
+			job.Params = rc.Params{}
+		}
+		// any runs missed while not running aren't caught up
+		job.Running = false
+		job.NextRun = job.cron.Next(now)
+		s.jobs[job.ID] = job
+		if job.ID >= s.nextID {
+			s.nextID = job.ID + 1
+		}
+	}
+	fs.Infof(nil, "Loaded %d scheduled jobs from %q", len(s.jobs), path)
+	return s, nil
+}
+
+// sortedJobs returns the jobs sorted by ID
+//
+// Call with the lock held
+func (s *Scheduler) sortedJobs() (jobs []*Job) {
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// save the jobs to the file, writing to a temporary file first so
+// the file is never left half written
+//
+// Call with the lock held
+func (s *Scheduler) save() error {
+	data, err := json.MarshalIndent(scheduleFile{Jobs: s.sortedJobs()}, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal schedule")
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "failed to make schedule directory")
+	}
+	tmpPath := s.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write schedule")
+	}
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return errors.Wrap(err, "failed to save schedule")
+	}
+	return nil
+}
+
+// wakeUp tells the scheduler loop the jobs have changed
+func (s *Scheduler) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Add a job to run the rc call command with params at the times
+// given by the cron expression schedule
+func (s *Scheduler) Add(name, schedule, command string, params rc.Params) (Job, error) {
+	cron, err := parseCron(schedule)
+	if err != nil {
+		return Job{}, err
+	}
+	err = checkCommand(command)
+	if err != nil {
+		return Job{}, err
+	}
+	if params == nil {
+		params = rc.Params{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	job := &Job{
+		ID:       s.nextID,
+		Name:     name,
+		Schedule: schedule,
+		Command:  command,
+		Params:   params,
+		Created:  now,
+		NextRun:  cron.Next(now),
+		cron:     cron,
+	}
+	s.jobs[job.ID] = job
+	err = s.save()
+	if err != nil {
+		delete(s.jobs, job.ID)
+		return Job{}, err
+	}
+	s.nextID++
+	s.wakeUp()
+	fs.Infof(nil, "schedule: added job %d to run %q %q", job.ID, job.Command, job.Schedule)
+	return job.copy(), nil
+}
+
+// Delete the job with the given ID
+//
+// If it is running it is allowed to finish.
+func (s *Scheduler) Delete(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return errors.Errorf("job %d not found", id)
+	}
+	delete(s.jobs, id)
+	err := s.save()
+	if err != nil {
+		s.jobs[id] = job
+		return err
+	}
+	s.wakeUp()
+	fs.Infof(nil, "schedule: deleted job %d", id)
+	return nil
+}
+
+// copy returns a copy of the job which can be read without the lock
+func (job *Job) copy() Job {
+	jobCopy := *job
+	jobCopy.History = append([]Run{}, job.History...)
+	return jobCopy
+}
+
+// List returns copies of all the jobs sorted by ID
+func (s *Scheduler) List() (jobs []Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs = []Job{}
+	for _, job := range s.sortedJobs() {
+		jobs = append(jobs, job.copy())
+	}
+	return jobs
+}
+
+// run the rc call for the job, recording the result
+func (s *Scheduler) run(job *Job, start time.Time) {
+	defer s.running.Done()
+	fs.Infof(nil, "schedule: job %d: running %q", job.ID, job.Command)
+	var err error
+	call := rc.Find(job.Command)
+	if call == nil {
+		err = errors.Errorf("couldn't find method %q", job.Command)
+	} else {
+		// copy the params so the call can't modify them
+		in := make(rc.Params, len(job.Params))
+		for k, v := range job.Params {
+			in[k] = v
+		}
+		_, err = call.Fn(in)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.Running = false
+	run := Run{
+		Start:    start,
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		fs.Errorf(nil, "schedule: job %d: %q failed: %v", job.ID, job.Command, err)
+		run.Error = err.Error()
+	} else {
+		fs.Infof(nil, "schedule: job %d: %q finished", job.ID, job.Command)
+	}
+	job.LastError = run.Error
+	job.addHistory(run)
+	if s.jobs[job.ID] == job {
+		err = s.save()
+		if err != nil {
+			fs.Errorf(nil, "schedule: %v", err)
+		}
+	}
+}
+
+// fire starts the jobs which are due to run at now
+//
+// Jobs whose previous run hasn't finished are skipped.
+func (s *Scheduler) fire(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fired := false
+	for _, job := range s.sortedJobs() {
+		if job.NextRun.IsZero() || job.NextRun.After(now) {
+			continue
+		}
+		fired = true
+		job.NextRun = job.cron.Next(now)
+		job.LastRun = now
+		if job.Running {
+			fs.Logf(nil, "schedule: job %d: skipping run as the previous one hasn't finished", job.ID)
+			job.Skipped++
+			job.addHistory(Run{
+				Start:   now,
+				Skipped: true,
+			})
+			continue
+		}
+		job.Running = true
+		job.Runs++
+		s.running.Add(1)
+		go s.run(job, now)
+	}
+	if fired {
+		err := s.save()
+		if err != nil {
+			fs.Errorf(nil, "schedule: %v", err)
+		}
+	}
+}
+
+// nextRun returns the time the next job is due to run or the zero
+// time if there isn't one
+func (s *Scheduler) nextRun() (next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if !job.NextRun.IsZero() && (next.IsZero() || job.NextRun.Before(next)) {
+			next = job.NextRun
+		}
+	}
+	return next
+}
+
+// loop runs the jobs when they are due until stopped
+func (s *Scheduler) loop() {
+	defer s.done.Done()
+	for {
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if next := s.nextRun(); !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			timerC = timer.C
+		}
+		select {
+		case <-timerC:
+			s.fire(time.Now())
+		case <-s.wake:
+		case <-s.stop:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+}
+
+// Start running the jobs in the background
+func (s *Scheduler) Start() {
+	s.done.Add(1)
+	go s.loop()
+}
+
+// Stop the scheduler and wait for any running jobs to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.done.Wait()
+	s.running.Wait()
+}
+
+var (
+	activeMu sync.Mutex
+	active   *Scheduler
+)
+
+// Start loads the jobs saved in the file at path and starts running
+// them.  The schedule/* remote control calls then control this
+// scheduler.
+func Start(path string) (*Scheduler, error) {
+	s, err := New(path)
+	if err != nil {
+		return nil, err
+	}
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	if active != nil {
+		return nil, errors.New("scheduler already started")
+	}
+	active = s
+	s.Start()
+	return s, nil
+}
+
+// getActive returns the scheduler the remote control calls use
+func getActive() (*Scheduler, error) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	if active == nil {
+		return nil, errors.New("scheduler not running - use rclone rcd to run scheduled jobs")
+	}
+	return active, nil
+}
@@ -0,0 +1,255 @@
+package schedule
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// calls made to schedule_test/call
+var testCalls = make(chan rc.Params, 10)
+
+// close this to let schedule_test/call return
+var testRelease chan struct{}
+
+func init() {
+	rc.Add(rc.Call{
+		Path: "schedule_test/call",
+		Fn: func(in rc.Params) (out rc.Params, err error) {
+			testCalls <- in
+			<-testRelease
+			if in["fail"] == "true" {
+				return nil, errors.New("potato")
+			}
+			return rc.Params{}, nil
+		},
+	})
+}
+
+// newTestScheduler makes a Scheduler with its file in a temporary
+// directory
+func newTestScheduler(t *testing.T) (s *Scheduler, path string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "rclone-schedule")
+	require.NoError(t, err)
+	path = filepath.Join(dir, "schedule.json")
+	s, err = New(path)
+	require.NoError(t, err)
+	return s, path, func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestSchedulerAddDelete(t *testing.T) {
+	s, path, cleanup := newTestScheduler(t)
+	defer cleanup()
+
+	_, err := s.Add("", "potato", "schedule_test/call", nil)
+	assert.Error(t, err)
+	_, err = s.Add("", "@daily", "schedule_test/potato", nil)
+	assert.Error(t, err)
+	_, err = s.Add("", "@daily", "schedule/list", nil)
+	assert.EqualError(t, err, `can't schedule "schedule/list"`)
+
+	job, err := s.Add("one", "@daily", "schedule_test/call", rc.Params{"a": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), job.ID)
+	assert.True(t, job.NextRun.After(time.Now()))
+	job, err = s.Add("two", "*/5 * * * *", "schedule_test/call", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), job.ID)
+
+	jobs := s.List()
+	require.Equal(t, 2, len(jobs))
+	assert.Equal(t, "one", jobs[0].Name)
+	assert.Equal(t, rc.Params{"a": "b"}, jobs[0].Params)
+	assert.Equal(t, "*/5 * * * *", jobs[1].Schedule)
+
+	// Check it was saved and reload it
+	s2, err := New(path)
+	require.NoError(t, err)
+	jobs2 := s2.List()
+	require.Equal(t, 2, len(jobs2))
+	assert.Equal(t, "one", jobs2[0].Name)
+	assert.Equal(t, "b", jobs2[0].Params["a"])
+	assert.Equal(t, "schedule_test/call", jobs2[1].Command)
+	assert.Equal(t, jobs[1].NextRun.Unix(), jobs2[1].NextRun.Unix())
+
+	require.NoError(t, s.Delete(1))
+	assert.Error(t, s.Delete(1))
+	job, err = s.Add("three", "@hourly", "schedule_test/call", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), job.ID)
+
+	s2, err = New(path)
+	require.NoError(t, err)
+	jobs2 = s2.List()
+	require.Equal(t, 2, len(jobs2))
+	assert.Equal(t, int64(2), jobs2[0].ID)
+	assert.Equal(t, int64(3), jobs2[1].ID)
+	assert.Equal(t, int64(4), s2.nextID)
+
+	// A corrupted file is an error
+	require.NoError(t, ioutil.WriteFile(path, []byte("potato"), 0600))
+	_, err = New(path)
+	assert.Error(t, err)
+
+	// Jobs in the file are checked like those added
+	for _, test := range []struct {
+		jobs    string
+		wantErr string
+	}{
+		{`[{"id":1,"schedule":"@daily","command":"schedule_test/call"}]`, ""},
+		{`[{"id":1,"schedule":"@daily","command":"schedule/add"}]`, `can't schedule "schedule/add"`},
+		{`[{"id":1,"schedule":"@daily","command":"schedule_test/potato"}]`, `couldn't find method "schedule_test/potato"`},
+		{`[{"id":1,"schedule":"potato","command":"schedule_test/call"}]`, "job 1"},
+		{`[{"id":0,"schedule":"@daily","command":"schedule_test/call"}]`, "bad ID"},
+		{`[{"id":1,"schedule":"@daily","command":"schedule_test/call"},{"id":1,"schedule":"@daily","command":"schedule_test/call"}]`, "duplicate job 1"},
+		{`[null]`, "bad ID"},
+	} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(`{"jobs":`+test.jobs+`}`), 0600))
+		s2, err = New(path)
+		if test.wantErr == "" {
+			require.NoError(t, err, test.jobs)
+			assert.Equal(t, rc.Params{}, s2.List()[0].Params)
+		} else {
+			require.Error(t, err, test.jobs)
+			assert.Contains(t, err.Error(), test.wantErr, test.jobs)
+		}
+	}
+}
+
+func TestSchedulerFire(t *testing.T) {
+	s, path, cleanup := newTestScheduler(t)
+	defer cleanup()
+	testRelease = make(chan struct{})
+
+	job, err := s.Add("", "* * * * *", "schedule_test/call", rc.Params{"fail": "true"})
+	require.NoError(t, err)
+	due := job.NextRun
+
+	// Not due yet
+	s.fire(due.Add(-time.Second))
+	assert.Equal(t, int64(0), s.List()[0].Runs)
+
+	s.fire(due)
+	in := <-testCalls
+	assert.Equal(t, "true", in["fail"])
+	jobs := s.List()
+	assert.True(t, jobs[0].Running)
+	assert.Equal(t, int64(1), jobs[0].Runs)
+	assert.Equal(t, due.Add(time.Minute), jobs[0].NextRun)
+
+	// The next run overlaps so is skipped
+	s.fire(due.Add(time.Minute))
+	jobs = s.List()
+	assert.Equal(t, int64(1), jobs[0].Runs)
+	assert.Equal(t, int64(1), jobs[0].Skipped)
+	require.Equal(t, 1, len(jobs[0].History))
+	assert.True(t, jobs[0].History[0].Skipped)
+
+	close(testRelease)
+	s.running.Wait()
+	jobs = s.List()
+	assert.False(t, jobs[0].Running)
+	assert.Equal(t, "potato", jobs[0].LastError)
+	require.Equal(t, 2, len(jobs[0].History))
+	assert.Equal(t, due, jobs[0].History[1].Start)
+	assert.Equal(t, "potato", jobs[0].History[1].Error)
+
+	// The history and errors are saved
+	s2, err := New(path)
+	require.NoError(t, err)
+	jobs = s2.List()
+	assert.Equal(t, "potato", jobs[0].LastError)
+	assert.Equal(t, 2, len(jobs[0].History))
+	assert.Equal(t, int64(1), jobs[0].Runs)
+
+	// The history is limited
+	for i := 0; i < maxHistory+5; i++ {
+		s.fire(s.List()[0].NextRun)
+		<-testCalls
+		s.running.Wait()
+	}
+	jobs = s.List()
+	assert.Equal(t, maxHistory, len(jobs[0].History))
+	assert.Equal(t, int64(maxHistory+6), jobs[0].Runs)
+}
+
+func TestSchedulerLoop(t *testing.T) {
+	s, _, cleanup := newTestScheduler(t)
+	defer cleanup()
+	testRelease = make(chan struct{})
+	close(testRelease)
+
+	s.Start()
+	_, err := s.Add("", "@every 1s", "schedule_test/call", rc.Params{"loop": "true"})
+	require.NoError(t, err)
+	select {
+	case in := <-testCalls:
+		assert.Equal(t, "true", in["loop"])
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for job to run")
+	}
+	s.Stop()
+}
+
+func TestRc(t *testing.T) {
+	_, err := rcList(rc.Params{})
+	assert.Error(t, err, "should fail if not running")
+
+	s, _, cleanup := newTestScheduler(t)
+	defer cleanup()
+	activeMu.Lock()
+	active = s
+	activeMu.Unlock()
+	defer func() {
+		activeMu.Lock()
+		active = nil
+		activeMu.Unlock()
+	}()
+
+	_, err = rcAdd(rc.Params{"command": "schedule_test/call"})
+	assert.Error(t, err)
+	_, err = rcAdd(rc.Params{"schedule": "@daily"})
+	assert.Error(t, err)
+	_, err = rcAdd(rc.Params{"schedule": "@daily", "command": "schedule_test/call", "params": "potato"})
+	assert.Error(t, err)
+
+	out, err := rcAdd(rc.Params{
+		"name":     "test",
+		"schedule": "@daily",
+		"command":  "schedule_test/call",
+		"params":   `{"a":"b"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), out["id"])
+	_, err = rcAdd(rc.Params{
+		"schedule": "@hourly",
+		"command":  "schedule_test/call",
+		"params":   map[string]interface{}{"c": "d"},
+	})
+	require.NoError(t, err)
+
+	out, err = rcList(rc.Params{})
+	require.NoError(t, err)
+	jobs := out["jobs"].([]Job)
+	require.Equal(t, 2, len(jobs))
+	assert.Equal(t, "test", jobs[0].Name)
+	assert.Equal(t, rc.Params{"a": "b"}, jobs[0].Params)
+	assert.Equal(t, rc.Params{"c": "d"}, jobs[1].Params)
+
+	_, err = rcDelete(rc.Params{"id": "potato"})
+	assert.Error(t, err)
+	_, err = rcDelete(rc.Params{"id": "1"})
+	require.NoError(t, err)
+	_, err = rcDelete(rc.Params{"id": float64(2)})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(s.List()))
+}
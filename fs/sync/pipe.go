@@ -1,12 +1,19 @@
 package sync
 
 import (
+	"container/heap"
 	"context"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
 )
 
+// compare two items for order by
+type lessFn func(a, b fs.ObjectPair) bool
+
 // pipe provides an unbounded channel like experience
 //
 // Note unlike channels these aren't strictly ordered.
@@ -17,13 +24,55 @@ type pipe struct {
 	closed    bool
 	totalSize int64
 	stats     func(items int, totalSize int64)
+	less      lessFn
+	fraction  int
 }
 
-func newPipe(stats func(items int, totalSize int64), maxBacklog int) *pipe {
-	return &pipe{
-		c:     make(chan struct{}, maxBacklog),
-		stats: stats,
+func newPipe(orderBy string, stats func(items int, totalSize int64), maxBacklog int) (*pipe, error) {
+	less, fraction, err := newLess(orderBy)
+	if err != nil {
+		return nil, err
+	}
+	p := &pipe{
+		c:        make(chan struct{}, maxBacklog),
+		stats:    stats,
+		less:     less,
+		fraction: fraction,
+	}
+	if p.less != nil {
+		heap.Init(p)
 	}
+	return p, nil
+}
+
+// Len satisfy heap.Interface - must be called with lock held
+func (p *pipe) Len() int {
+	return len(p.queue)
+}
+
+// Less satisfy heap.Interface - must be called with lock held
+func (p *pipe) Less(i, j int) bool {
+	return p.less(p.queue[i], p.queue[j])
+}
+
+// Swap satisfy heap.Interface - must be called with lock held
+func (p *pipe) Swap(i, j int) {
+	p.queue[i], p.queue[j] = p.queue[j], p.queue[i]
+}
+
+// Push satisfy heap.Interface - must be called with lock held
+func (p *pipe) Push(item interface{}) {
+	p.queue = append(p.queue, item.(fs.ObjectPair))
+}
+
+// Pop satisfy heap.Interface - must be called with lock held
+func (p *pipe) Pop() interface{} {
+	old := p.queue
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = fs.ObjectPair{} // avoid memory leak
+	p.queue = old[0 : n-1]
+	return item
 }
 
 // Put an pair into the pipe
@@ -36,7 +85,12 @@ func (p *pipe) Put(ctx context.Context, pair fs.ObjectPair) (ok bool) {
 		return false
 	}
 	p.mu.Lock()
-	p.queue = append(p.queue, pair)
+	if p.less == nil {
+		// no order-by
+		p.queue = append(p.queue, pair)
+	} else {
+		heap.Push(p, pair)
+	}
 	size := pair.Src.Size()
 	if size > 0 {
 		p.totalSize += size
@@ -51,11 +105,16 @@ func (p *pipe) Put(ctx context.Context, pair fs.ObjectPair) (ok bool) {
 	return true
 }
 
-// Get a pair from the pipe
+// GetMax gets a pair from the pipe
+//
+// If the pipe was created with a mixed order-by and fraction is
+// below the mixed fraction then the item which sorts last is returned
+// rather than the one which sorts first.  Pass fraction < 0 to always
+// get the item which sorts first.
 //
 // It returns ok = false if the context was cancelled or Close() has
 // been called.
-func (p *pipe) Get(ctx context.Context) (pair fs.ObjectPair, ok bool) {
+func (p *pipe) GetMax(ctx context.Context, fraction int) (pair fs.ObjectPair, ok bool) {
 	if ctx.Err() != nil {
 		return
 	}
@@ -68,7 +127,16 @@ func (p *pipe) Get(ctx context.Context) (pair fs.ObjectPair, ok bool) {
 		}
 	}
 	p.mu.Lock()
-	pair, p.queue = p.queue[0], p.queue[1:]
+	if p.less == nil {
+		// no order-by
+		pair = p.queue[0]
+		p.queue[0] = fs.ObjectPair{} // avoid memory leak
+		p.queue = p.queue[1:]
+	} else if p.fraction < 0 || fraction < 0 || fraction >= p.fraction {
+		pair = heap.Pop(p).(fs.ObjectPair)
+	} else {
+		pair = heap.Remove(p, p.maxIndex()).(fs.ObjectPair)
+	}
 	size := pair.Src.Size()
 	if size > 0 {
 		p.totalSize -= size
@@ -81,6 +149,30 @@ func (p *pipe) Get(ctx context.Context) (pair fs.ObjectPair, ok bool) {
 	return pair, true
 }
 
+// Get a pair from the pipe
+//
+// It returns ok = false if the context was cancelled or Close() has
+// been called.
+func (p *pipe) Get(ctx context.Context) (pair fs.ObjectPair, ok bool) {
+	return p.GetMax(ctx, -1)
+}
+
+// maxIndex finds the index of the item which sorts last in the heap -
+// must be called with lock held and a non empty queue
+//
+// The largest item must be one of the leaves of the heap so only the
+// second half of the queue needs to be searched.
+func (p *pipe) maxIndex() int {
+	n := len(p.queue)
+	maxI := n / 2
+	for i := maxI + 1; i < n; i++ {
+		if p.less(p.queue[maxI], p.queue[i]) {
+			maxI = i
+		}
+	}
+	return maxI
+}
+
 // Stats reads the number of items in the queue and the totalSize
 func (p *pipe) Stats() (items int, totalSize int64) {
 	p.mu.Lock()
@@ -98,3 +190,62 @@ func (p *pipe) Close() {
 	p.closed = true
 	p.mu.Unlock()
 }
+
+// newLess returns a less function for the heap comparison or nil if
+// one is not required
+//
+// orderBy is of the form "key[,direction[,fraction]]" where key is
+// one of size, name or modtime and direction is one of ascending,
+// descending or mixed.  For mixed the fraction is the percentage of
+// transfers which take the largest items from the queue.
+func newLess(orderBy string) (less lessFn, fraction int, err error) {
+	fraction = -1
+	if orderBy == "" {
+		return nil, fraction, nil
+	}
+	parts := strings.Split(strings.ToLower(orderBy), ",")
+	switch parts[0] {
+	case "name":
+		less = func(a, b fs.ObjectPair) bool {
+			return a.Src.Remote() < b.Src.Remote()
+		}
+	case "size":
+		less = func(a, b fs.ObjectPair) bool {
+			return a.Src.Size() < b.Src.Size()
+		}
+	case "modtime":
+		less = func(a, b fs.ObjectPair) bool {
+			return a.Src.ModTime().Before(b.Src.ModTime())
+		}
+	default:
+		return nil, fraction, errors.Errorf("unknown --order-by comparison %q", parts[0])
+	}
+	descending := false
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "ascending", "asc":
+		case "descending", "desc":
+			descending = true
+		case "mixed":
+			fraction = 50
+			if len(parts) > 2 {
+				fraction, err = strconv.Atoi(parts[2])
+				if err != nil || fraction < 0 || fraction > 100 {
+					return nil, fraction, errors.Errorf("bad mixed fraction --order-by %q", parts[2])
+				}
+			}
+		default:
+			return nil, fraction, errors.Errorf("unknown --order-by sort direction %q", parts[1])
+		}
+	}
+	if (fraction >= 0 && len(parts) > 3) || (fraction < 0 && len(parts) > 2) {
+		return nil, fraction, errors.Errorf("bad --order-by string %q", orderBy)
+	}
+	if descending {
+		oldLess := less
+		less = func(a, b fs.ObjectPair) bool {
+			return oldLess(b, a)
+		}
+	}
+	return less, fraction, nil
+}
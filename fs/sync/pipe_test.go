@@ -2,13 +2,16 @@ package sync
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPipe(t *testing.T) {
@@ -19,7 +22,8 @@ func TestPipe(t *testing.T) {
 	}
 
 	// Make a new pipe
-	p := newPipe(stats, 10)
+	p, err := newPipe("", stats, 10)
+	require.NoError(t, err)
 
 	checkStats := func(expectedN int, expectedSize int64) {
 		n, size := p.Stats()
@@ -60,7 +64,8 @@ func TestPipe(t *testing.T) {
 	assert.Panics(t, func() { p.Put(ctx, pair1) })
 
 	// Make a new pipe
-	p = newPipe(stats, 10)
+	p, err = newPipe("", stats, 10)
+	require.NoError(t, err)
 	ctx2, cancel := context.WithCancel(ctx)
 
 	// cancel it in the background - check read ceases
@@ -86,7 +91,8 @@ func TestPipeConcurrent(t *testing.T) {
 	stats := func(n int, size int64) {}
 
 	// Make a new pipe
-	p := newPipe(stats, 10)
+	p, err := newPipe("", stats, 10)
+	require.NoError(t, err)
 
 	var wg sync.WaitGroup
 	obj1 := mockobject.New("potato").WithContent([]byte("hello"), mockobject.SeekModeNone)
@@ -120,3 +126,89 @@ func TestPipeConcurrent(t *testing.T) {
 
 	assert.Equal(t, int64(0), count)
 }
+
+func TestPipeOrderBy(t *testing.T) {
+	var (
+		stats = func(n int, size int64) {}
+		ctx   = context.Background()
+		obj1  = mockobject.New("b").WithContent([]byte("1"), mockobject.SeekModeNone)
+		obj2  = mockobject.New("a").WithContent([]byte("22"), mockobject.SeekModeNone)
+		obj3  = mockobject.New("c").WithContent([]byte("333"), mockobject.SeekModeNone)
+	)
+	for _, test := range []struct {
+		orderBy  string
+		fraction int
+		want     []string
+	}{
+		{"", -1, []string{"b", "a", "c"}},
+		{"name", -1, []string{"a", "b", "c"}},
+		{"name,ascending", -1, []string{"a", "b", "c"}},
+		{"name,descending", -1, []string{"c", "b", "a"}},
+		{"size", -1, []string{"b", "a", "c"}},
+		{"size,asc", -1, []string{"b", "a", "c"}},
+		{"size,desc", -1, []string{"c", "a", "b"}},
+		{"size,mixed,50", -1, []string{"b", "a", "c"}},
+		{"size,mixed,50", 0, []string{"c", "a", "b"}},
+		{"size,mixed,50", 50, []string{"b", "a", "c"}},
+		{"SIZE,MIXED", 25, []string{"c", "a", "b"}},
+	} {
+		t.Run(fmt.Sprintf("%s/%d", test.orderBy, test.fraction), func(t *testing.T) {
+			p, err := newPipe(test.orderBy, stats, 10)
+			require.NoError(t, err)
+			for _, obj := range []fs.Object{obj1, obj2, obj3} {
+				ok := p.Put(ctx, fs.ObjectPair{Src: obj, Dst: nil})
+				assert.True(t, ok)
+			}
+			var got []string
+			for range test.want {
+				pair, ok := p.GetMax(ctx, test.fraction)
+				require.True(t, ok)
+				got = append(got, pair.Src.Remote())
+			}
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+// modTimeObject is a mock object with a modification time
+type modTimeObject struct {
+	mockobject.Object
+	modTime time.Time
+}
+
+// ModTime returns the modification time of the object
+func (o modTimeObject) ModTime() time.Time { return o.modTime }
+
+func TestPipeOrderByModTime(t *testing.T) {
+	stats := func(n int, size int64) {}
+	ctx := context.Background()
+	p, err := newPipe("modtime,descending", stats, 10)
+	require.NoError(t, err)
+	t0 := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	for i, name := range []string{"old", "new", "middle"} {
+		obj := modTimeObject{Object: mockobject.New(name), modTime: t0.Add(time.Duration([]int{0, 2, 1}[i]) * time.Hour)}
+		assert.True(t, p.Put(ctx, fs.ObjectPair{Src: obj}))
+	}
+	var got []string
+	for i := 0; i < 3; i++ {
+		pair, ok := p.Get(ctx)
+		require.True(t, ok)
+		got = append(got, pair.Src.Remote())
+	}
+	assert.Equal(t, []string{"new", "middle", "old"}, got)
+}
+
+func TestNewLessErrors(t *testing.T) {
+	for _, orderBy := range []string{
+		"potato",
+		"size,potato",
+		"size,mixed,potato",
+		"size,mixed,101",
+		"size,mixed,-1",
+		"size,ascending,extra",
+		"size,mixed,50,extra",
+	} {
+		_, _, err := newLess(orderBy)
+		assert.Error(t, err, orderBy)
+	}
+}
@@ -4,9 +4,12 @@ package sync
 import (
 	"context"
 	"fmt"
+	"math"
 	"path"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
@@ -27,73 +30,110 @@ type syncCopyMove struct {
 	deleteEmptySrcDirs bool
 	dir                string
 	// internal state
-	ctx            context.Context        // internal context for controlling go-routines
-	cancel         func()                 // cancel the context
-	deletersWg     sync.WaitGroup         // for delete before go routine
-	deleteFilesCh  chan fs.Object         // channel to receive deletes if delete before
-	trackRenames   bool                   // set if we should do server side renames
-	dstFilesMu     sync.Mutex             // protect dstFiles
-	dstFiles       map[string]fs.Object   // dst files, always filled
-	srcFiles       map[string]fs.Object   // src files, only used if deleteBefore
-	srcFilesChan   chan fs.Object         // passes src objects
-	srcFilesResult chan error             // error result of src listing
-	dstFilesResult chan error             // error result of dst listing
-	dstEmptyDirsMu sync.Mutex             // protect dstEmptyDirs
-	dstEmptyDirs   map[string]fs.DirEntry // potentially empty directories
-	srcEmptyDirsMu sync.Mutex             // protect srcEmptyDirs
-	srcEmptyDirs   map[string]fs.DirEntry // potentially empty directories
-	checkerWg      sync.WaitGroup         // wait for checkers
-	toBeChecked    *pipe                  // checkers channel
-	transfersWg    sync.WaitGroup         // wait for transfers
-	toBeUploaded   *pipe                  // copiers channel
-	errorMu        sync.Mutex             // Mutex covering the errors variables
-	err            error                  // normal error from copy process
-	noRetryErr     error                  // error with NoRetry set
-	fatalErr       error                  // fatal error
-	commonHash     hash.Type              // common hash type between src and dst
-	renameMapMu    sync.Mutex             // mutex to protect the below
-	renameMap      map[string][]fs.Object // dst files by hash - only used by trackRenames
-	renamerWg      sync.WaitGroup         // wait for renamers
-	toBeRenamed    *pipe                  // renamers channel
-	trackRenamesWg sync.WaitGroup         // wg for background track renames
-	trackRenamesCh chan fs.Object         // objects are pumped in here
-	renameCheck    []fs.Object            // accumulate files to check for rename here
-	backupDir      fs.Fs                  // place to store overwrites/deletes
-	suffix         string                 // suffix to add to files placed in backupDir
-}
-
-func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (*syncCopyMove, error) {
+	ctx                  context.Context        // internal context for controlling go-routines
+	cancel               func()                 // cancel the context
+	deletersWg           sync.WaitGroup         // for delete before go routine
+	deleteFilesCh        chan fs.Object         // channel to receive deletes if delete before
+	trackRenames         bool                   // set if we should do server side renames
+	trackRenamesStrategy trackRenamesStrategy   // strategies used for tracking renames
+	modifyWindow         time.Duration          // modify window for the modtime strategy
+	dstFilesMu           sync.Mutex             // protect dstFiles
+	dstFiles             map[string]fs.Object   // dst files, always filled
+	srcFiles             map[string]fs.Object   // src files, only used if deleteBefore
+	srcFilesChan         chan fs.Object         // passes src objects
+	srcFilesResult       chan error             // error result of src listing
+	dstFilesResult       chan error             // error result of dst listing
+	dstEmptyDirsMu       sync.Mutex             // protect dstEmptyDirs
+	dstEmptyDirs         map[string]fs.DirEntry // potentially empty directories
+	srcEmptyDirsMu       sync.Mutex             // protect srcEmptyDirs
+	srcEmptyDirs         map[string]fs.DirEntry // potentially empty directories
+	checkerWg            sync.WaitGroup         // wait for checkers
+	toBeChecked          *pipe                  // checkers channel
+	transfersWg          sync.WaitGroup         // wait for transfers
+	toBeUploaded         *pipe                  // copiers channel
+	errorMu              sync.Mutex             // Mutex covering the errors variables
+	err                  error                  // normal error from copy process
+	noRetryErr           error                  // error with NoRetry set
+	fatalErr             error                  // fatal error
+	commonHash           hash.Type              // common hash type between src and dst
+	renameMapMu          sync.Mutex             // mutex to protect the below
+	renameMap            map[string][]fs.Object // dst files by hash - only used by trackRenames
+	renamerWg            sync.WaitGroup         // wait for renamers
+	toBeRenamed          *pipe                  // renamers channel
+	trackRenamesWg       sync.WaitGroup         // wg for background track renames
+	trackRenamesCh       chan fs.Object         // objects are pumped in here
+	renameCheck          []fs.Object            // accumulate files to check for rename here
+	backupDir            fs.Fs                  // place to store overwrites/deletes
+	compareCopyDest      fs.Fs                  // place to check for files to server side copy or skip
+	checkFirst           bool                   // if set run all the checkers before starting transfers
+	report               *operations.Report     // if set, the paths processed are reported here
+	ci                   *fs.ConfigInfo         // config taking into account overrides of fdst and fsrc
+}
+
+func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, report *operations.Report) (*syncCopyMove, error) {
+	ci := fs.GetConfigFs(fdst, fsrc)
 	s := &syncCopyMove{
+		ci:                 ci,
 		fdst:               fdst,
 		fsrc:               fsrc,
 		deleteMode:         deleteMode,
 		DoMove:             DoMove,
 		deleteEmptySrcDirs: deleteEmptySrcDirs,
 		dir:                "",
-		srcFilesChan:       make(chan fs.Object, fs.Config.Checkers+fs.Config.Transfers),
+		srcFilesChan:       make(chan fs.Object, ci.Checkers+ci.Transfers),
 		srcFilesResult:     make(chan error, 1),
 		dstFilesResult:     make(chan error, 1),
 		dstEmptyDirs:       make(map[string]fs.DirEntry),
 		srcEmptyDirs:       make(map[string]fs.DirEntry),
-		toBeChecked:        newPipe(accounting.Stats.SetCheckQueue, fs.Config.MaxBacklog),
-		toBeUploaded:       newPipe(accounting.Stats.SetTransferQueue, fs.Config.MaxBacklog),
-		deleteFilesCh:      make(chan fs.Object, fs.Config.Checkers),
+		deleteFilesCh:      make(chan fs.Object, ci.Checkers),
 		trackRenames:       fs.Config.TrackRenames,
 		commonHash:         fsrc.Hashes().Overlap(fdst.Hashes()).GetOne(),
-		toBeRenamed:        newPipe(accounting.Stats.SetRenameQueue, fs.Config.MaxBacklog),
-		trackRenamesCh:     make(chan fs.Object, fs.Config.Checkers),
+		trackRenamesCh:     make(chan fs.Object, ci.Checkers),
+		checkFirst:         fs.Config.CheckFirst,
+		report:             report,
+	}
+	uploadBacklog := fs.Config.MaxBacklog
+	if s.checkFirst {
+		fs.Infof(s.fdst, "Running all checks before starting transfers")
+		// the transfer queue must be able to hold every file
+		// as nothing takes from it until the checks are done
+		uploadBacklog = math.MaxInt32
+	}
+	var err error
+	s.toBeChecked, err = newPipe(fs.Config.OrderBy, accounting.Stats.SetCheckQueue, fs.Config.MaxBacklog)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	s.toBeUploaded, err = newPipe(fs.Config.OrderBy, accounting.Stats.SetTransferQueue, uploadBacklog)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	s.toBeRenamed, err = newPipe(fs.Config.OrderBy, accounting.Stats.SetRenameQueue, fs.Config.MaxBacklog)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
 	}
 	s.ctx, s.cancel = context.WithCancel(context.Background())
 	if s.trackRenames {
+		s.trackRenamesStrategy, err = parseTrackRenamesStrategy(fs.Config.TrackRenamesStrategy)
+		if err != nil {
+			return nil, fserrors.FatalError(err)
+		}
 		// Don't track renames for remotes without server-side move support.
 		if !operations.CanServerSideMove(fdst) {
 			fs.Errorf(fdst, "Ignoring --track-renames as the destination does not support server-side move or copy")
 			s.trackRenames = false
 		}
-		if s.commonHash == hash.None {
+		if s.trackRenamesStrategy.hash() && s.commonHash == hash.None {
 			fs.Errorf(fdst, "Ignoring --track-renames as the source and destination do not have a common hash")
 			s.trackRenames = false
 		}
+		if s.trackRenamesStrategy.modTime() {
+			s.modifyWindow = fs.GetModifyWindow(fsrc, fdst)
+			if s.modifyWindow == fs.ModTimeNotSupported {
+				fs.Errorf(fdst, "Ignoring --track-renames as either the source or destination do not support modtime")
+				s.trackRenames = false
+			}
+		}
 		if s.deleteMode == fs.DeleteModeOff {
 			fs.Errorf(fdst, "Ignoring --track-renames as it doesn't work with copy or move, only sync")
 			s.trackRenames = false
@@ -107,7 +147,6 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 	}
 	// Make Fs for --backup-dir if required
 	if fs.Config.BackupDir != "" {
-		var err error
 		s.backupDir, err = fs.NewFs(fs.Config.BackupDir)
 		if err != nil {
 			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --backup-dir %q: %v", fs.Config.BackupDir, err))
@@ -124,7 +163,28 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		if operations.Overlapping(fsrc, s.backupDir) {
 			return nil, fserrors.FatalError(errors.New("source and parameter to --backup-dir mustn't overlap"))
 		}
-		s.suffix = fs.Config.Suffix
+	}
+	// Make Fs for --compare-dest or --copy-dest if required
+	if fs.Config.CompareDest != "" || fs.Config.CopyDest != "" {
+		flag, dest := "--compare-dest", fs.Config.CompareDest
+		if fs.Config.CopyDest != "" {
+			flag, dest = "--copy-dest", fs.Config.CopyDest
+		}
+		s.compareCopyDest, err = fs.NewFs(dest)
+		if err != nil {
+			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for %s %q: %v", flag, dest, err))
+		}
+		if fs.Config.CopyDest != "" {
+			if !operations.SameConfig(fdst, s.compareCopyDest) {
+				return nil, fserrors.FatalError(errors.New("parameter to --copy-dest has to be on the same remote as destination"))
+			}
+			if fdst.Features().Copy == nil {
+				return nil, fserrors.FatalError(errors.New("can't use --copy-dest on a remote which doesn't support server side copy"))
+			}
+		}
+		if operations.Overlapping(fdst, s.compareCopyDest) {
+			return nil, fserrors.FatalError(errors.Errorf("destination and parameter to %s mustn't overlap", flag))
+		}
 	}
 	return s, nil
 }
@@ -166,6 +226,12 @@ func (s *syncCopyMove) processError(err error) {
 			s.cancel()
 		}
 		s.fatalErr = err
+	case err == accounting.ErrorMaxTransferLimitReachedGraceful || err == accounting.ErrorMaxDurationReachedGraceful:
+		if !s.aborting() {
+			fs.Logf(nil, "%v - stopping transfers", err)
+			s.cancel()
+		}
+		s.noRetryErr = err
 	case fserrors.IsNoRetryError(err):
 		s.noRetryErr = err
 	default:
@@ -203,19 +269,42 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, wg *sync.WaitGroup) {
 		accounting.Stats.Checking(src.Remote())
 		// Check to see if can store this
 		if src.Storable() {
-			if operations.NeedTransfer(pair.Dst, pair.Src) {
+			// the status to report if src is copied
+			copyStatus := byte(operations.ReportMissingOnDst)
+			if pair.Dst != nil {
+				copyStatus = operations.ReportDiffer
+			}
+			needTransfer := operations.NeedTransfer(pair.Dst, pair.Src)
+			copiedFromCopyDest := false
+			if needTransfer && s.compareCopyDest != nil {
+				noNeedTransfer, backedUp, err := operations.CompareOrCopyDest(s.fdst, pair.Dst, pair.Src, s.compareCopyDest, s.backupDir)
+				if err != nil {
+					s.processError(err)
+					s.report.Add(operations.ReportError, src.Remote())
+					accounting.Stats.DoneChecking(src.Remote())
+					continue
+				}
+				if backedUp {
+					// dst is in --backup-dir now so don't move it there again
+					pair.Dst = nil
+				}
+				needTransfer = !noNeedTransfer
+				copiedFromCopyDest = noNeedTransfer && fs.Config.CopyDest != ""
+			}
+			if needTransfer {
+				s.report.Add(copyStatus, src.Remote())
 				// If files are treated as immutable, fail if destination exists and does not match
 				if fs.Config.Immutable && pair.Dst != nil {
 					fs.Errorf(pair.Dst, "Source and destination exist but do not match: immutable file modified")
 					s.processError(fs.ErrorImmutableModified)
+					s.report.Add(operations.ReportError, src.Remote())
 				} else {
 					// If destination already exists, then we must move it into --backup-dir if required
 					if pair.Dst != nil && s.backupDir != nil {
-						remoteWithSuffix := pair.Dst.Remote() + s.suffix
-						overwritten, _ := s.backupDir.NewObject(remoteWithSuffix)
-						_, err := operations.Move(s.backupDir, overwritten, remoteWithSuffix, pair.Dst)
+						err := operations.MoveBackupDir(s.backupDir, pair.Dst)
 						if err != nil {
 							s.processError(err)
+							s.report.Add(operations.ReportError, src.Remote())
 						} else {
 							// If successful zero out the dst as it is no longer there and copy the file
 							pair.Dst = nil
@@ -232,10 +321,19 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, wg *sync.WaitGroup) {
 					}
 				}
 			} else {
+				if copiedFromCopyDest {
+					s.report.Add(copyStatus, src.Remote())
+				} else {
+					s.report.Add(operations.ReportMatch, src.Remote())
+				}
 				// If moving need to delete the files we don't need to copy
 				if s.DoMove {
 					// Delete src if no error on copy
-					s.processError(operations.DeleteFile(src))
+					err := operations.DeleteFile(src)
+					s.processError(err)
+					if err != nil {
+						s.report.Add(operations.ReportError, src.Remote())
+					}
 				}
 			}
 		}
@@ -264,11 +362,14 @@ func (s *syncCopyMove) pairRenamer(in *pipe, out *pipe, wg *sync.WaitGroup) {
 }
 
 // pairCopyOrMove reads Objects on in and moves or copies them.
-func (s *syncCopyMove) pairCopyOrMove(in *pipe, fdst fs.Fs, wg *sync.WaitGroup) {
+//
+// fraction is used to pick the item from the pipe when using a mixed
+// --order-by
+func (s *syncCopyMove) pairCopyOrMove(in *pipe, fdst fs.Fs, fraction int, wg *sync.WaitGroup) {
 	defer wg.Done()
 	var err error
 	for {
-		pair, ok := in.Get(s.ctx)
+		pair, ok := in.GetMax(s.ctx, fraction)
 		if !ok {
 			return
 		}
@@ -280,14 +381,17 @@ func (s *syncCopyMove) pairCopyOrMove(in *pipe, fdst fs.Fs, wg *sync.WaitGroup)
 			_, err = operations.Copy(fdst, pair.Dst, src.Remote(), src)
 		}
 		s.processError(err)
+		if err != nil {
+			s.report.Add(operations.ReportError, src.Remote())
+		}
 		accounting.Stats.DoneTransferring(src.Remote(), err == nil)
 	}
 }
 
 // This starts the background checkers.
 func (s *syncCopyMove) startCheckers() {
-	s.checkerWg.Add(fs.Config.Checkers)
-	for i := 0; i < fs.Config.Checkers; i++ {
+	s.checkerWg.Add(s.ci.Checkers)
+	for i := 0; i < s.ci.Checkers; i++ {
 		go s.pairChecker(s.toBeChecked, s.toBeUploaded, &s.checkerWg)
 	}
 }
@@ -301,9 +405,10 @@ func (s *syncCopyMove) stopCheckers() {
 
 // This starts the background transfers
 func (s *syncCopyMove) startTransfers() {
-	s.transfersWg.Add(fs.Config.Transfers)
-	for i := 0; i < fs.Config.Transfers; i++ {
-		go s.pairCopyOrMove(s.toBeUploaded, s.fdst, &s.transfersWg)
+	s.transfersWg.Add(s.ci.Transfers)
+	for i := 0; i < s.ci.Transfers; i++ {
+		fraction := (100 * i) / s.ci.Transfers
+		go s.pairCopyOrMove(s.toBeUploaded, s.fdst, fraction, &s.transfersWg)
 	}
 }
 
@@ -319,8 +424,8 @@ func (s *syncCopyMove) startRenamers() {
 	if !s.trackRenames {
 		return
 	}
-	s.renamerWg.Add(fs.Config.Checkers)
-	for i := 0; i < fs.Config.Checkers; i++ {
+	s.renamerWg.Add(s.ci.Checkers)
+	for i := 0; i < s.ci.Checkers; i++ {
 		go s.pairRenamer(s.toBeRenamed, s.toBeUploaded, &s.renamerWg)
 	}
 }
@@ -392,7 +497,7 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 	}
 
 	// Delete the spare files
-	toDelete := make(fs.ObjectsChan, fs.Config.Transfers)
+	toDelete := make(fs.ObjectsChan, s.ci.Transfers)
 	go func() {
 	outer:
 		for remote, o := range s.dstFiles {
@@ -508,48 +613,120 @@ func (s *syncCopyMove) srcParentDirCheck(entry fs.DirEntry) {
 	}
 }
 
-// renameHash makes a string with the size and the hash for rename detection
+// trackRenamesStrategy is a bitmask of the ways files can be matched
+// for --track-renames
+type trackRenamesStrategy byte
+
+const (
+	trackRenamesStrategyHash trackRenamesStrategy = 1 << iota
+	trackRenamesStrategyModtime
+	trackRenamesStrategyLeaf
+)
+
+// parseTrackRenamesStrategy parses a comma separated list of
+// strategies, eg "modtime,leaf"
+//
+// The size is always used so on its own it isn't enough to match
+// files - at least one of hash, modtime or leaf must be given.
+func parseTrackRenamesStrategy(strategies string) (strategy trackRenamesStrategy, err error) {
+	for _, s := range strings.Split(strategies, ",") {
+		switch s {
+		case "hash":
+			strategy |= trackRenamesStrategyHash
+		case "modtime":
+			strategy |= trackRenamesStrategyModtime
+		case "leaf":
+			strategy |= trackRenamesStrategyLeaf
+		case "size":
+			// ignore - the size is always used
+		default:
+			return strategy, errors.Errorf("unknown track renames strategy %q", s)
+		}
+	}
+	if strategy == 0 {
+		return strategy, errors.Errorf("track renames strategy %q must include hash, modtime or leaf", strategies)
+	}
+	return strategy, nil
+}
+
+// hash returns true if the strategy includes the hash
+func (strategy trackRenamesStrategy) hash() bool {
+	return (strategy & trackRenamesStrategyHash) != 0
+}
+
+// modTime returns true if the strategy includes the modification time
+func (strategy trackRenamesStrategy) modTime() bool {
+	return (strategy & trackRenamesStrategyModtime) != 0
+}
+
+// leaf returns true if the strategy includes the leaf name
+func (strategy trackRenamesStrategy) leaf() bool {
+	return (strategy & trackRenamesStrategyLeaf) != 0
+}
+
+// renameID makes a string with the size and the other parts chosen
+// by the --track-renames-strategy for rename detection
 //
-// it may return an empty string in which case no hash could be made
-func (s *syncCopyMove) renameHash(obj fs.Object) (hash string) {
-	var err error
-	hash, err = obj.Hash(s.commonHash)
-	if err != nil {
-		fs.Debugf(obj, "Hash failed: %v", err)
-		return ""
+// The modification time isn't part of the id as it has to be
+// compared within the modify window - popRenameMap does that.
+//
+// it may return an empty string in which case no id could be made
+func (s *syncCopyMove) renameID(obj fs.Object) string {
+	id := fmt.Sprintf("%d", obj.Size())
+	if s.trackRenamesStrategy.hash() {
+		hash, err := obj.Hash(s.commonHash)
+		if err != nil {
+			fs.Debugf(obj, "Hash failed: %v", err)
+			return ""
+		}
+		if hash == "" {
+			return ""
+		}
+		id += "," + hash
 	}
-	if hash == "" {
-		return ""
+	if s.trackRenamesStrategy.leaf() {
+		id += "," + path.Base(obj.Remote())
 	}
-	return fmt.Sprintf("%d,%s", obj.Size(), hash)
+	return id
 }
 
-// pushRenameMap adds the object with hash to the rename map
-func (s *syncCopyMove) pushRenameMap(hash string, obj fs.Object) {
+// pushRenameMap adds the object with id to the rename map
+func (s *syncCopyMove) pushRenameMap(id string, obj fs.Object) {
 	s.renameMapMu.Lock()
-	s.renameMap[hash] = append(s.renameMap[hash], obj)
+	s.renameMap[id] = append(s.renameMap[id], obj)
 	s.renameMapMu.Unlock()
 }
 
-// popRenameMap finds the object with hash and pop the first match from
-// renameMap or returns nil if not found.
-func (s *syncCopyMove) popRenameMap(hash string) (dst fs.Object) {
+// popRenameMap finds the object with id which matches src and pops
+// the first match from renameMap or returns nil if not found.
+//
+// If the strategy includes the modification time then only objects
+// whose modification time is within the modify window of src match.
+func (s *syncCopyMove) popRenameMap(id string, src fs.Object) (dst fs.Object) {
 	s.renameMapMu.Lock()
-	dsts, ok := s.renameMap[hash]
-	if ok && len(dsts) > 0 {
-		dst, dsts = dsts[0], dsts[1:]
-		if len(dsts) > 0 {
-			s.renameMap[hash] = dsts
-		} else {
-			delete(s.renameMap, hash)
+	defer s.renameMapMu.Unlock()
+	dsts := s.renameMap[id]
+	for i, candidate := range dsts {
+		if s.trackRenamesStrategy.modTime() {
+			dt := candidate.ModTime().Sub(src.ModTime())
+			if dt < -s.modifyWindow || dt > s.modifyWindow {
+				continue
+			}
 		}
+		dst = candidate
+		dsts = append(dsts[:i], dsts[i+1:]...)
+		break
+	}
+	if len(dsts) > 0 {
+		s.renameMap[id] = dsts
+	} else {
+		delete(s.renameMap, id)
 	}
-	s.renameMapMu.Unlock()
 	return dst
 }
 
-// makeRenameMap builds a map of the destination files by hash that
-// match sizes in the slice of objects in s.renameCheck
+// makeRenameMap builds a map of the destination files by rename id
+// that match sizes in the slice of objects in s.renameCheck
 func (s *syncCopyMove) makeRenameMap() {
 	fs.Infof(s.fdst, "Making map for --track-renames")
 
@@ -560,23 +737,23 @@ func (s *syncCopyMove) makeRenameMap() {
 	}
 
 	// pump all the dstFiles into in
-	in := make(chan fs.Object, fs.Config.Checkers)
+	in := make(chan fs.Object, s.ci.Checkers)
 	go s.pumpMapToChan(s.dstFiles, in)
 
-	// now make a map of size,hash for all dstFiles
+	// now make a map of rename id for all dstFiles
 	s.renameMap = make(map[string][]fs.Object)
 	var wg sync.WaitGroup
-	wg.Add(fs.Config.Transfers)
-	for i := 0; i < fs.Config.Transfers; i++ {
+	wg.Add(s.ci.Transfers)
+	for i := 0; i < s.ci.Transfers; i++ {
 		go func() {
 			defer wg.Done()
 			for obj := range in {
 				// only create hash for dst fs.Object if its size could match
 				if _, found := possibleSizes[obj.Size()]; found {
 					accounting.Stats.Checking(obj.Remote())
-					hash := s.renameHash(obj)
-					if hash != "" {
-						s.pushRenameMap(hash, obj)
+					id := s.renameID(obj)
+					if id != "" {
+						s.pushRenameMap(id, obj)
 					}
 					accounting.Stats.DoneChecking(obj.Remote())
 				}
@@ -593,14 +770,14 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	accounting.Stats.Checking(src.Remote())
 	defer accounting.Stats.DoneChecking(src.Remote())
 
-	// Calculate the hash of the src object
-	hash := s.renameHash(src)
-	if hash == "" {
+	// Calculate the rename id of the src object
+	id := s.renameID(src)
+	if id == "" {
 		return false
 	}
 
 	// Get a match on fdst
-	dst := s.popRenameMap(hash)
+	dst := s.popRenameMap(id, src)
 	if dst == nil {
 		return false
 	}
@@ -620,7 +797,7 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	delete(s.dstFiles, dst.Remote())
 	s.dstFilesMu.Unlock()
 
-	fs.Infof(src, "Renamed from %q", dst.Remote())
+	fs.Infof(src, "Renamed from %q%v%v", dst.Remote(), fs.LogValueHide("event", "renamed"), fs.LogValueHide("from", dst.Remote()))
 	return true
 }
 
@@ -640,7 +817,9 @@ func (s *syncCopyMove) run() error {
 	// Start background checking and transferring pipeline
 	s.startCheckers()
 	s.startRenamers()
-	s.startTransfers()
+	if !s.checkFirst {
+		s.startTransfers()
+	}
 	s.startDeleters()
 	s.dstFiles = make(map[string]fs.Object)
 
@@ -666,6 +845,10 @@ func (s *syncCopyMove) run() error {
 	// Stop background checking and transferring pipeline
 	s.stopCheckers()
 	s.stopRenamers()
+	if s.checkFirst {
+		fs.Infof(s.fdst, "Checks finished, now starting transfers")
+		s.startTransfers()
+	}
 	s.stopTransfers()
 	s.stopDeleters()
 
@@ -703,6 +886,9 @@ func (s *syncCopyMove) run() error {
 
 // DstOnly have an object which is in the destination only
 func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
+	if _, isObject := dst.(fs.Object); isObject {
+		s.report.Add(operations.ReportMissingOnSrc, dst.Remote())
+	}
 	if s.deleteMode == fs.DeleteModeOff {
 		return false
 	}
@@ -753,6 +939,11 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 		s.srcParentDirCheck(src)
 		s.srcEmptyDirsMu.Unlock()
 
+		if s.trackRenames || s.compareCopyDest == nil {
+			// otherwise pairChecker reports it after checking
+			// --compare-dest or --copy-dest
+			s.report.Add(operations.ReportMissingOnDst, x.Remote())
+		}
 		if s.trackRenames {
 			// Save object to check for a rename later
 			select {
@@ -760,6 +951,12 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 				return
 			case s.trackRenamesCh <- x:
 			}
+		} else if s.compareCopyDest != nil {
+			// Check --compare-dest or --copy-dest for the file
+			ok := s.toBeChecked.Put(s.ctx, fs.ObjectPair{Src: x, Dst: nil})
+			if !ok {
+				return
+			}
 		} else {
 			// No need to check since doesn't exist
 			ok := s.toBeUploaded.Put(s.ctx, fs.ObjectPair{Src: x, Dst: nil})
@@ -832,7 +1029,9 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) error {
+//
+// If report is set then the paths processed are written to it
+func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, report *operations.Report) error {
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
@@ -841,8 +1040,9 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		if fs.Config.TrackRenames {
 			return fserrors.FatalError(errors.New("can't use --delete-before with --track-renames"))
 		}
-		// only delete stuff during in this pass
-		do, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs)
+		// only delete stuff during in this pass - it reports the
+		// deletions and the next pass reports everything else
+		do, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs, report)
 		if err != nil {
 			return err
 		}
@@ -853,7 +1053,7 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		// Next pass does a copy only
 		deleteMode = fs.DeleteModeOff
 	}
-	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs)
+	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, report)
 	if err != nil {
 		return err
 	}
@@ -862,21 +1062,42 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 
 // Sync fsrc into fdst
 func Sync(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.Config.DeleteMode, false, false)
+	return SyncWithReport(fdst, fsrc, nil)
+}
+
+// SyncWithReport syncs fsrc into fdst writing the paths processed
+// to report if it is not nil
+func SyncWithReport(fdst, fsrc fs.Fs, report *operations.Report) error {
+	return runSyncCopyMove(fdst, fsrc, fs.Config.DeleteMode, false, false, report)
 }
 
 // CopyDir copies fsrc into fdst
 func CopyDir(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, false, false)
+	return CopyDirWithReport(fdst, fsrc, nil)
+}
+
+// CopyDirWithReport copies fsrc into fdst writing the paths
+// processed to report if it is not nil
+func CopyDirWithReport(fdst, fsrc fs.Fs, report *operations.Report) error {
+	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, false, false, report)
 }
 
 // moveDir moves fsrc into fdst
-func moveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs)
+func moveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, report *operations.Report) error {
+	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs, report)
 }
 
 // MoveDir moves fsrc into fdst
 func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
+	return MoveDirWithReport(fdst, fsrc, deleteEmptySrcDirs, nil)
+}
+
+// MoveDirWithReport moves fsrc into fdst writing the paths processed
+// to report if it is not nil.
+//
+// If a server side directory move is used then no paths are
+// reported.
+func MoveDirWithReport(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, report *operations.Report) error {
 	if operations.Same(fdst, fsrc) {
 		fs.Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
@@ -911,5 +1132,5 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 	}
 
 	// Otherwise move the files one by one
-	return moveDir(fdst, fsrc, deleteEmptySrcDirs)
+	return moveDir(fdst, fsrc, deleteEmptySrcDirs, report)
 }
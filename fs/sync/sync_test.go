@@ -3,14 +3,23 @@
 package sync
 
 import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path"
 	"runtime"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	_ "github.com/ncw/rclone/backend/all" // import all backends
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fstest"
@@ -79,6 +88,61 @@ func TestCopyWithDepth(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+// Test copy with --check-first and more files than the backlog
+func TestCopyCheckFirst(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	var items []fstest.Item
+	for i := 0; i < 5; i++ {
+		items = append(items, r.WriteFile(fmt.Sprintf("file%d", i), fmt.Sprintf("contents %d", i), t1))
+	}
+
+	oldBacklog := fs.Config.MaxBacklog
+	fs.Config.CheckFirst = true
+	fs.Config.MaxBacklog = 2
+	defer func() {
+		fs.Config.CheckFirst = false
+		fs.Config.MaxBacklog = oldBacklog
+	}()
+
+	err := CopyDir(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Flocal, items...)
+	fstest.CheckItems(t, r.Fremote, items...)
+
+	// only the transfer queue is unbounded
+	s, err := newSyncCopyMove(r.Fremote, r.Flocal, fs.DeleteModeOff, false, false, nil)
+	require.NoError(t, err)
+	defer s.cancel()
+	assert.Equal(t, 2, cap(s.toBeChecked.c))
+	assert.Equal(t, math.MaxInt32, cap(s.toBeUploaded.c))
+	assert.Equal(t, 2, cap(s.toBeRenamed.c))
+}
+
+// Test copy with --order-by
+func TestCopyOrderBy(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
+	file2 := r.WriteFile("hello world2", "hello world2", t2)
+
+	fs.Config.OrderBy = "size,mixed,25"
+	defer func() { fs.Config.OrderBy = "" }()
+
+	err := CopyDir(r.Fremote, r.Flocal)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// Check a bad --order-by is a fatal error
+	fs.Config.OrderBy = "potato"
+	err = CopyDir(r.Fremote, r.Flocal)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsFatalError(err))
+}
+
 // Test copy empty directories
 func TestCopyEmptyDirectories(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -479,6 +543,53 @@ func TestSyncAfterAddingAFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1, file2)
 }
 
+// Test the report of paths processed by sync
+func TestSyncWithReport(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("same", "same", t2)
+	file2 := r.WriteFile("new", "new file", t2)
+	r.WriteObject("changed", "old contents", t1)
+	file3 := r.WriteFile("changed", "new contents, longer", t2)
+	r.WriteObject("extra", "only on the remote", t2)
+
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+
+	var combined, missingOnSrc, missingOnDst, match, differ, errs bytes.Buffer
+	report := &operations.Report{
+		Combined:     &combined,
+		MissingOnSrc: &missingOnSrc,
+		MissingOnDst: &missingOnDst,
+		Match:        &match,
+		Differ:       &differ,
+		Error:        &errs,
+	}
+	accounting.Stats.ResetCounters()
+	err := SyncWithReport(r.Fremote, r.Flocal, report)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	// the checks and transfers run concurrently so sort the lines
+	gotCombined := strings.Split(strings.TrimSpace(combined.String()), "\n")
+	sort.Strings(gotCombined)
+	assert.Equal(t, []string{"* changed", "+ extra", "- new", "= same"}, gotCombined)
+	assert.Equal(t, "extra\n", missingOnSrc.String())
+	assert.Equal(t, "new\n", missingOnDst.String())
+	assert.Equal(t, "same\n", match.String())
+	assert.Equal(t, "changed\n", differ.String())
+	assert.Equal(t, "", errs.String())
+}
+
+// Test the report includes the files deleted by --delete-before
+func TestSyncWithReportDeleteBefore(t *testing.T) {
+	fs.Config.DeleteMode = fs.DeleteModeBefore
+	defer func() {
+		fs.Config.DeleteMode = fs.DeleteModeDefault
+	}()
+
+	TestSyncWithReport(t)
+}
+
 func TestSyncAfterChangingFilesSizeOnly(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -746,6 +857,33 @@ func TestCopyDeleteBefore(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 }
 
+// Test sync between remotes with the same name and root but different
+// connection string parameters isn't treated as syncing a remote to
+// itself
+func TestSyncConnectionStringParams(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	password := obscure.MustObscure("potato")
+	newCrypt := func(dir string) fs.Fs {
+		f, err := fs.NewFs(`:crypt,password=` + password + `,remote="` + path.Join(r.LocalName, dir) + `":`)
+		require.NoError(t, err)
+		return f
+	}
+	fsrc, fdst := newCrypt("src"), newCrypt("dst")
+	assert.Equal(t, fsrc.Root(), fdst.Root())
+	assert.NotEqual(t, fsrc.Name(), fdst.Name())
+	assert.False(t, operations.Overlapping(fdst, fsrc))
+	require.NoError(t, operations.Mkdir(fsrc, ""))
+	file1 := r.WriteObjectTo(fsrc, "file1", "hello world", t1, false)
+	fstest.CheckItems(t, fsrc, file1)
+
+	accounting.Stats.ResetCounters()
+	err := Sync(fdst, fsrc)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), accounting.Stats.GetTransfers())
+	fstest.CheckItems(t, fdst, file1)
+}
+
 // Test with exclude
 func TestSyncWithExclude(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -839,19 +977,30 @@ func TestSyncWithUpdateOlder(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, oneO, twoF, threeO, fourF, fiveF)
 }
 
-// Test with TrackRenames set
-func TestSyncWithTrackRenames(t *testing.T) {
+// Test with TrackRenames set using the strategy passed in
+//
+// The file "yam" is renamed to newPath and wantRename says whether
+// the strategy should be able to detect that.
+func testSyncWithTrackRenames(t *testing.T, strategy string, newPath string, wantRename bool) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
 
 	fs.Config.TrackRenames = true
+	fs.Config.TrackRenamesStrategy = strategy
 	defer func() {
 		fs.Config.TrackRenames = false
-
+		fs.Config.TrackRenamesStrategy = "hash"
 	}()
 
 	haveHash := r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).GetOne() != hash.None
-	canTrackRenames := haveHash && operations.CanServerSideMove(r.Fremote)
+	haveModTime := fs.GetModifyWindow(r.Fremote, r.Flocal) != fs.ModTimeNotSupported
+	canTrackRenames := operations.CanServerSideMove(r.Fremote)
+	if strings.Contains(strategy, "hash") {
+		canTrackRenames = canTrackRenames && haveHash
+	}
+	if strings.Contains(strategy, "modtime") {
+		canTrackRenames = canTrackRenames && haveModTime
+	}
 	t.Logf("Can track renames: %v", canTrackRenames)
 
 	f1 := r.WriteFile("potato", "Potato Content", t1)
@@ -864,20 +1013,95 @@ func TestSyncWithTrackRenames(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, f1, f2)
 
 	// Now rename locally.
-	f2 = r.RenameFile(f2, "yaml")
+	require.NoError(t, os.MkdirAll(path.Join(r.LocalName, path.Dir(newPath)), 0777))
+	f2 = r.RenameFile(f2, newPath)
 
 	accounting.Stats.ResetCounters()
 	require.NoError(t, Sync(r.Fremote, r.Flocal))
 
 	fstest.CheckItems(t, r.Fremote, f1, f2)
 
-	if canTrackRenames {
+	if canTrackRenames && wantRename {
 		assert.Equal(t, accounting.Stats.GetTransfers(), int64(0))
 	} else {
 		assert.Equal(t, accounting.Stats.GetTransfers(), int64(1))
 	}
 }
 
+func TestSyncWithTrackRenames(t *testing.T) {
+	testSyncWithTrackRenames(t, "hash", "yaml", true)
+}
+
+func TestSyncWithTrackRenamesStrategyModtime(t *testing.T) {
+	testSyncWithTrackRenames(t, "modtime", "yaml", true)
+}
+
+func TestSyncWithTrackRenamesStrategyLeaf(t *testing.T) {
+	testSyncWithTrackRenames(t, "leaf", "sub/yam", true)
+}
+
+func TestSyncWithTrackRenamesStrategyLeafRenamed(t *testing.T) {
+	testSyncWithTrackRenames(t, "leaf", "yaml", false)
+}
+
+func TestSyncWithTrackRenamesStrategyHashModtime(t *testing.T) {
+	testSyncWithTrackRenames(t, "hash,modtime", "sub/yaml", true)
+}
+
+// Test --track-renames-strategy modtime matches times within the
+// modify window even if they are either side of a whole window
+func TestSyncWithTrackRenamesModtimeWindow(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	oldModifyWindow := fs.Config.ModifyWindow
+	fs.Config.TrackRenames = true
+	fs.Config.TrackRenamesStrategy = "modtime"
+	fs.Config.ModifyWindow = time.Second
+	defer func() {
+		fs.Config.TrackRenames = false
+		fs.Config.TrackRenamesStrategy = "hash"
+		fs.Config.ModifyWindow = oldModifyWindow
+	}()
+	if !operations.CanServerSideMove(r.Fremote) || fs.GetModifyWindow(r.Fremote, r.Flocal) != time.Second {
+		t.Skip("Can't test modify window with this remote")
+	}
+
+	modTime := fstest.Time("2001-02-03T04:05:06.999000000Z")
+	r.WriteObject("yam", "Yam Content", modTime)
+	f2 := r.WriteFile("yaml", "Yam Content", modTime.Add(2*time.Millisecond))
+
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(r.Fremote, r.Flocal))
+
+	fstest.CheckItems(t, r.Fremote, f2)
+	assert.Equal(t, int64(0), accounting.Stats.GetTransfers())
+}
+
+func TestParseTrackRenamesStrategy(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    trackRenamesStrategy
+		wantErr bool
+	}{
+		{"", 0, true},
+		{"hash", trackRenamesStrategyHash, false},
+		{"size", 0, true},
+		{"size,leaf", trackRenamesStrategyLeaf, false},
+		{"modtime,leaf", trackRenamesStrategyModtime | trackRenamesStrategyLeaf, false},
+		{"hash,modtime,leaf", trackRenamesStrategyHash | trackRenamesStrategyModtime | trackRenamesStrategyLeaf, false},
+		{"hash,potato", 0, true},
+	} {
+		got, err := parseTrackRenamesStrategy(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
 // Test a server side move if possible, or the backup path if not
 func testServerSideMove(t *testing.T, r *fstest.Run, withFilter, testDeleteEmptyDirs bool) {
 	FremoteMove, _, finaliseMove, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
@@ -1117,6 +1341,249 @@ func testSyncBackupDir(t *testing.T, suffix string) {
 func TestSyncBackupDir(t *testing.T)           { testSyncBackupDir(t, "") }
 func TestSyncBackupDirWithSuffix(t *testing.T) { testSyncBackupDir(t, ".bak") }
 
+// Test --backup-dir with a time format in --suffix and --suffix-keep-extension
+func TestSyncBackupDirSuffixKeepExtension(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	if !operations.CanServerSideMove(r.Fremote) {
+		t.Skip("Skipping test as remote does not support server side move")
+	}
+	r.Mkdir(r.Fremote)
+
+	fs.Config.BackupDir = r.FremoteName + "/backup"
+	fs.Config.Suffix = "-{2006}"
+	fs.Config.SuffixKeepExtension = true
+	defer func() {
+		fs.Config.BackupDir = ""
+		fs.Config.Suffix = ""
+		fs.Config.SuffixKeepExtension = false
+	}()
+
+	file1 := r.WriteObject("dst/one.txt", "one", t1)
+	file1a := r.WriteFile("one.txt", "oneA", t2)
+
+	fstest.CheckItems(t, r.Fremote, file1)
+	fstest.CheckItems(t, r.Flocal, file1a)
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+
+	accounting.Stats.ResetCounters()
+	err = Sync(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	// one should be moved to the backup dir with the year before
+	// the extension and the new one installed
+	file1.Path = "backup/one-" + time.Now().Format("2006") + ".txt"
+	file1a.Path = "dst/one.txt"
+
+	fstest.CheckItems(t, r.Fremote, file1, file1a)
+}
+
+// Test with --compare-dest
+func TestSyncCompareDest(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	fs.Config.CompareDest = r.FremoteName + "/CompareDest"
+	defer func() {
+		fs.Config.CompareDest = ""
+	}()
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+
+	// check empty dest, empty compare
+	file1 := r.WriteFile("one", "one", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	file1dst := file1
+	file1dst.Path = "dst/one"
+
+	fstest.CheckItems(t, r.Fremote, file1dst)
+
+	// check old dest, empty compare
+	file1b := r.WriteFile("one", "onet2", t2)
+	fstest.CheckItems(t, r.Fremote, file1dst)
+	fstest.CheckItems(t, r.Flocal, file1b)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	file1bdst := file1b
+	file1bdst.Path = "dst/one"
+
+	fstest.CheckItems(t, r.Fremote, file1bdst)
+
+	// check old dest, new compare
+	file3 := r.WriteObject("dst/one", "one", t1)
+	file2 := r.WriteObject("CompareDest/one", "onet2", t2)
+	file1c := r.WriteFile("one", "onet2", t2)
+	fstest.CheckItems(t, r.Fremote, file2, file3)
+	fstest.CheckItems(t, r.Flocal, file1c)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	// one should not have been copied as it is in CompareDest
+	fstest.CheckItems(t, r.Fremote, file2, file3)
+
+	// check empty dest, new compare
+	file4 := r.WriteObject("CompareDest/two", "two", t2)
+	file5 := r.WriteFile("two", "two", t2)
+	fstest.CheckItems(t, r.Fremote, file2, file3, file4)
+	fstest.CheckItems(t, r.Flocal, file1c, file5)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Fremote, file2, file3, file4)
+
+	// check new dest, new compare
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Fremote, file2, file3, file4)
+}
+
+// Test with --copy-dest
+func TestSyncCopyDest(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	if r.Fremote.Features().Copy == nil {
+		t.Skip("Skipping test as remote does not support server side copy")
+	}
+
+	fs.Config.CopyDest = r.FremoteName + "/CopyDest"
+	defer func() {
+		fs.Config.CopyDest = ""
+	}()
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+
+	// check empty dest, empty copy
+	file1 := r.WriteFile("one", "one", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	file1dst := file1
+	file1dst.Path = "dst/one"
+
+	fstest.CheckItems(t, r.Fremote, file1dst)
+
+	// check old dest, new copy
+	file3 := r.WriteObject("dst/one", "one", t1)
+	file2 := r.WriteObject("CopyDest/one", "onet2", t2)
+	file1b := r.WriteFile("one", "onet2", t2)
+	fstest.CheckItems(t, r.Fremote, file2, file3)
+	fstest.CheckItems(t, r.Flocal, file1b)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	// one should have been server side copied from CopyDest
+	file2dst := file2
+	file2dst.Path = "dst/one"
+
+	fstest.CheckItems(t, r.Fremote, file2, file2dst)
+
+	// check empty dest, new copy
+	file4 := r.WriteObject("CopyDest/two", "two", t2)
+	file5 := r.WriteFile("two", "two", t2)
+	fstest.CheckItems(t, r.Fremote, file2, file2dst, file4)
+	fstest.CheckItems(t, r.Flocal, file1b, file5)
+
+	accounting.Stats.ResetCounters()
+	err = CopyDir(fdst, r.Flocal)
+	require.NoError(t, err)
+
+	file4dst := file4
+	file4dst.Path = "dst/two"
+
+	fstest.CheckItems(t, r.Fremote, file2, file2dst, file4, file4dst)
+
+	// check that moving deletes the source of files found in CopyDest
+	file6 := r.WriteObject("CopyDest/three", "three", t2)
+	file7 := r.WriteFile("three", "three", t2)
+	fstest.CheckItems(t, r.Flocal, file1b, file5, file7)
+
+	accounting.Stats.ResetCounters()
+	err = MoveDir(fdst, r.Flocal, false)
+	require.NoError(t, err)
+
+	file6dst := file6
+	file6dst.Path = "dst/three"
+
+	fstest.CheckItems(t, r.Fremote, file2, file2dst, file4, file4dst, file6, file6dst)
+	fstest.CheckItems(t, r.Flocal)
+}
+
+// Test with --copy-dest and --backup-dir
+func TestSyncCopyDestBackupDir(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	if r.Fremote.Features().Copy == nil {
+		t.Skip("Skipping test as remote does not support server side copy")
+	}
+	if !operations.CanServerSideMove(r.Fremote) {
+		t.Skip("Skipping test as remote does not support server side move")
+	}
+
+	fs.Config.CopyDest = r.FremoteName + "/CopyDest"
+	fs.Config.BackupDir = r.FremoteName + "/backup"
+	defer func() {
+		fs.Config.CopyDest = ""
+		fs.Config.BackupDir = ""
+	}()
+
+	fdst, err := fs.NewFs(r.FremoteName + "/dst")
+	require.NoError(t, err)
+
+	file1 := r.WriteObject("dst/one", "one", t1)
+	file2 := r.WriteObject("CopyDest/one", "onet2", t2)
+	file3 := r.WriteFile("one", "onet2", t2)
+	file4 := r.WriteObject("CopyDest/two", "two", t2)
+	file5 := r.WriteFile("two", "two", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file4)
+	fstest.CheckItems(t, r.Flocal, file3, file5)
+
+	var combined bytes.Buffer
+	accounting.Stats.ResetCounters()
+	err = CopyDirWithReport(fdst, r.Flocal, &operations.Report{Combined: &combined})
+	require.NoError(t, err)
+
+	// one should have been moved to the backup dir once then
+	// server side copied from CopyDest as should two
+	file1backup := file1
+	file1backup.Path = "backup/one"
+	file2dst := file2
+	file2dst.Path = "dst/one"
+	file4dst := file4
+	file4dst.Path = "dst/two"
+	fstest.CheckItems(t, r.Fremote, file1backup, file2, file2dst, file4, file4dst)
+
+	// and reported as copied
+	gotCombined := strings.Split(strings.TrimSpace(combined.String()), "\n")
+	sort.Strings(gotCombined)
+	assert.Equal(t, []string{"* one", "- two"}, gotCombined)
+}
+
 // Check we can sync two files with differing UTF-8 representations
 func TestSyncUTFNorm(t *testing.T) {
 	if runtime.GOOS == "darwin" {
@@ -1216,3 +1683,107 @@ func TestAbort(t *testing.T) {
 	err := Sync(r.Fremote, r.Flocal)
 	assert.Equal(t, accounting.ErrorMaxTransferLimitReached, err)
 }
+
+// Test that --cutoff-mode soft lets the current transfers finish
+// then stops
+func TestAbortCutoffModeSoft(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	if r.Fremote.Name() != "local" {
+		t.Skip("This test only runs on local")
+	}
+
+	oldMaxTransfer := fs.Config.MaxTransfer
+	oldTransfers := fs.Config.Transfers
+	oldCheckers := fs.Config.Checkers
+	oldCutoffMode := fs.Config.CutoffMode
+	fs.Config.MaxTransfer = 3 * 1024
+	fs.Config.Transfers = 1
+	fs.Config.Checkers = 1
+	fs.Config.CutoffMode = fs.CutoffModeSoft
+	defer func() {
+		fs.Config.MaxTransfer = oldMaxTransfer
+		fs.Config.Transfers = oldTransfers
+		fs.Config.Checkers = oldCheckers
+		fs.Config.CutoffMode = oldCutoffMode
+	}()
+
+	// Create file on source
+	file1 := r.WriteFile("file1", string(make([]byte, 5*1024)), t1)
+	file2 := r.WriteFile("file2", string(make([]byte, 2*1024)), t1)
+	file3 := r.WriteFile("file3", string(make([]byte, 3*1024)), t1)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+	fstest.CheckItems(t, r.Fremote)
+
+	accounting.Stats.ResetCounters()
+
+	err := Sync(r.Fremote, r.Flocal)
+	assert.Equal(t, accounting.ErrorMaxTransferLimitReachedGraceful, err)
+	assert.False(t, fserrors.IsFatalError(err))
+	assert.True(t, fserrors.IsNoRetryError(err))
+
+	// The first file should be transferred completely
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+// Test that --cutoff-mode cautious doesn't start a transfer which
+// would go over the limit
+func TestAbortCutoffModeCautious(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	oldMaxTransfer := fs.Config.MaxTransfer
+	oldTransfers := fs.Config.Transfers
+	oldCheckers := fs.Config.Checkers
+	oldCutoffMode := fs.Config.CutoffMode
+	fs.Config.MaxTransfer = 3 * 1024
+	fs.Config.Transfers = 1
+	fs.Config.Checkers = 1
+	fs.Config.CutoffMode = fs.CutoffModeCautious
+	defer func() {
+		fs.Config.MaxTransfer = oldMaxTransfer
+		fs.Config.Transfers = oldTransfers
+		fs.Config.Checkers = oldCheckers
+		fs.Config.CutoffMode = oldCutoffMode
+	}()
+
+	file1 := r.WriteFile("file1", string(make([]byte, 5*1024)), t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	accounting.Stats.ResetCounters()
+
+	err := Sync(r.Fremote, r.Flocal)
+	assert.Equal(t, accounting.ErrorMaxTransferLimitReachedGraceful, err)
+	fstest.CheckItems(t, r.Fremote)
+}
+
+// Test that --max-duration stops the transfers
+func TestAbortMaxDuration(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	oldMaxDuration := fs.Config.MaxDuration
+	oldCutoffMode := fs.Config.CutoffMode
+	defer func() {
+		fs.Config.MaxDuration = oldMaxDuration
+		fs.Config.CutoffMode = oldCutoffMode
+	}()
+	fs.Config.MaxDuration = time.Nanosecond
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	for _, test := range []struct {
+		mode fs.CutoffMode
+		want error
+	}{
+		{fs.CutoffModeHard, accounting.ErrorMaxDurationReached},
+		{fs.CutoffModeSoft, accounting.ErrorMaxDurationReachedGraceful},
+	} {
+		fs.Config.CutoffMode = test.mode
+		err := Sync(r.Fremote, r.Flocal)
+		assert.Equal(t, test.want, err, test.mode.String())
+		fstest.CheckItems(t, r.Fremote)
+	}
+}
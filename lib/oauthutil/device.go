@@ -0,0 +1,155 @@
+// OAuth device authorization flow (RFC 8628)
+
+package oauthutil
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// deviceGrantType is the grant_type used to poll for the token
+	deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// defaultDeviceInterval is the polling interval to use if the
+	// provider doesn't return one
+	defaultDeviceInterval = 5 * time.Second
+
+	// defaultDeviceExpiresIn is how long the device code lasts if
+	// the provider doesn't say
+	defaultDeviceExpiresIn = 900
+)
+
+// deviceAuth is the response from the device authorization endpoint
+type deviceAuth struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	VerificationURL string `json:"verification_url"` // used by some providers instead of verification_uri
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// deviceToken is the response from the token endpoint when polling
+// for a device token
+type deviceToken struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// postForm posts values to endpoint decoding the JSON response into
+// result.  Responses with an error status are decoded too as the
+// token endpoint returns its errors in the body.
+func postForm(endpoint string, values url.Values, result interface{}) (err error) {
+	client := fshttp.NewClient(fs.Config)
+	resp, err := client.PostForm(endpoint, values)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode response (HTTP status %s)", resp.Status)
+	}
+	return nil
+}
+
+// getDeviceCode asks the device authorization endpoint for a code for
+// the user to enter.
+func getDeviceCode(oauthConfig *oauth2.Config, deviceAuthURL string) (*deviceAuth, error) {
+	values := url.Values{
+		"client_id": {oauthConfig.ClientID},
+		"scope":     {strings.Join(oauthConfig.Scopes, " ")},
+	}
+	var da deviceAuth
+	err := postForm(deviceAuthURL, values, &da)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get device code")
+	}
+	if da.DeviceCode == "" || da.UserCode == "" {
+		return nil, errors.New("failed to get device code: no code returned")
+	}
+	if da.VerificationURI == "" {
+		da.VerificationURI = da.VerificationURL
+	}
+	if da.ExpiresIn <= 0 {
+		da.ExpiresIn = defaultDeviceExpiresIn
+	}
+	return &da, nil
+}
+
+// errDevicePending is returned by requestDeviceToken if the user
+// hasn't authorized the device code yet
+var errDevicePending = errors.New("device code not authorized yet")
+
+// requestDeviceToken asks the token endpoint once for the token for
+// the device code.  It returns errDevicePending if the user hasn't
+// authorized the device code yet, with slowDown set if the server
+// asked for it to be polled less often.
+func requestDeviceToken(oauthConfig *oauth2.Config, deviceCode string) (token *oauth2.Token, slowDown bool, err error) {
+	values := url.Values{
+		"grant_type":  {deviceGrantType},
+		"device_code": {deviceCode},
+		"client_id":   {oauthConfig.ClientID},
+	}
+	if oauthConfig.ClientSecret != "" {
+		values.Set("client_secret", oauthConfig.ClientSecret)
+	}
+	var dt deviceToken
+	err = postForm(oauthConfig.Endpoint.TokenURL, values, &dt)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get token")
+	}
+	switch dt.Error {
+	case "":
+		if dt.AccessToken == "" {
+			return nil, false, errors.New("failed to get token: no access token returned")
+		}
+		token = &oauth2.Token{
+			AccessToken:  dt.AccessToken,
+			TokenType:    dt.TokenType,
+			RefreshToken: dt.RefreshToken,
+		}
+		if dt.ExpiresIn > 0 {
+			token.Expiry = time.Now().Add(time.Duration(dt.ExpiresIn) * time.Second)
+		}
+		return token, false, nil
+	case "authorization_pending":
+		return nil, false, errDevicePending
+	case "slow_down":
+		return nil, true, errDevicePending
+	default:
+		return nil, false, errors.Errorf("failed to get token: %s: %s", dt.Error, dt.ErrorDescription)
+	}
+}
+
+// pollDeviceToken polls the token endpoint every interval until the
+// user has authorized the device code, returning the token.  It gives
+// up at expiry.
+func pollDeviceToken(oauthConfig *oauth2.Config, deviceCode string, interval time.Duration, expiry time.Time) (*oauth2.Token, error) {
+	for {
+		token, slowDown, err := requestDeviceToken(oauthConfig, deviceCode)
+		if err != errDevicePending {
+			return token, err
+		}
+		if slowDown {
+			interval += 5 * time.Second
+		}
+		if time.Now().Add(interval).After(expiry) {
+			return nil, errors.New("device code expired before it was authorized")
+		}
+		fs.Debugf(nil, "Waiting for device code to be authorized")
+		time.Sleep(interval)
+	}
+}
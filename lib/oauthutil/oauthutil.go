@@ -6,9 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -288,159 +288,424 @@ func NewClientWithBaseClient(name string, m configmap.Mapper, config *oauth2.Con
 // NewClient gets a token from the config file and configures a Client
 // with it.  It returns the client and a TokenSource which Invalidate may need to be called on
 func NewClient(name string, m configmap.Mapper, oauthConfig *oauth2.Config) (*http.Client, *TokenSource, error) {
-	return NewClientWithBaseClient(name, m, oauthConfig, fshttp.NewClient(fs.Config))
+	return NewClientWithBaseClient(name, m, oauthConfig, fshttp.NewClient(fs.GetConfigMap(name, m)))
 }
 
-// Config does the initial creation of the token
-//
-// It may run an internal webserver to receive the results
-func Config(id, name string, m configmap.Mapper, config *oauth2.Config, opts ...oauth2.AuthCodeOption) error {
-	return doConfig(id, name, m, nil, config, true, opts)
+// Options for the OAuth config of a backend
+type Options struct {
+	OAuth2Config  *oauth2.Config                // the basic config for oauth2
+	NoOffline     bool                          // if set then "access_type=offline" isn't passed
+	CheckAuth     func(*http.Request) AuthError // optional function called with the request made to rclone if no code was found
+	OAuth2Opts    []oauth2.AuthCodeOption       // extra oauth2 options
+	DeviceAuthURL string                        // optional device authorization endpoint for headless config
 }
 
-// ConfigNoOffline does the same as Config but does not pass the
-// "access_type=offline" parameter.
-func ConfigNoOffline(id, name string, m configmap.Mapper, config *oauth2.Config, opts ...oauth2.AuthCodeOption) error {
-	return doConfig(id, name, m, nil, config, false, opts)
+func init() {
+	fs.ConfigOAuth = ConfigOAuth
 }
 
-// ConfigErrorCheck does the same as Config, but allows the backend to pass a error handling function
-// This function gets called with the request made to rclone as a parameter if no code was found
-func ConfigErrorCheck(id, name string, m configmap.Mapper, errorHandler func(*http.Request) AuthError, config *oauth2.Config, opts ...oauth2.AuthCodeOption) error {
-	return doConfig(id, name, m, errorHandler, config, true, opts)
+// ConfigOut returns the config step to do the OAuth with opt, returning
+// to state when it is done.
+//
+// The backend config should return this from its initial "" state
+// as the OAuth states read opt from there.
+func ConfigOut(state string, opt *Options) (*fs.ConfigOut, error) {
+	return &fs.ConfigOut{
+		State: fs.StatePush(state, "*oauth"),
+		OAuth: opt,
+	}, nil
 }
 
-func doConfig(id, name string, m configmap.Mapper, errorHandler func(*http.Request) AuthError, oauthConfig *oauth2.Config, offline bool, opts []oauth2.AuthCodeOption) error {
-	oauthConfig, changed := overrideCredentials(name, m, oauthConfig)
+// getOAuth reads the OAuth options from the initial state of the
+// backend config
+func getOAuth(name string, m configmap.Mapper, ri *fs.RegInfo) (*Options, error) {
+	out, err := ri.Config(name, m, fs.ConfigIn{})
+	if err != nil {
+		return nil, err
+	}
+	if out != nil {
+		if opt, ok := out.OAuth.(*Options); ok && opt != nil {
+			return opt, nil
+		}
+	}
+	return nil, errors.Errorf("%s backend didn't return OAuth options", ri.Name)
+}
+
+// ConfigOAuth runs the "*oauth" states of the backend config
+//
+// It is called by the fs config machinery - use ConfigOut to start it.
+func ConfigOAuth(name string, m configmap.Mapper, ri *fs.RegInfo, in fs.ConfigIn) (*fs.ConfigOut, error) {
+	next, stateName := fs.StatePop(in.State)
+	opt, err := getOAuth(name, m, ri)
+	if err != nil {
+		return nil, err
+	}
+	oauthConfig, changed := overrideCredentials(name, m, opt.OAuth2Config)
 	auto, ok := m.Get(config.ConfigAutomatic)
 	automatic := ok && auto != ""
 
-	// See if already have a token
-	tokenString, ok := m.Get("token")
-	if ok && tokenString != "" {
-		fmt.Printf("Already have a token - refresh?\n")
-		if !config.Confirm() {
-			return nil
+	switch stateName {
+	case "*oauth":
+		// See if already have a token
+		tokenString, ok := m.Get("token")
+		if ok && tokenString != "" {
+			return fs.ConfigConfirm(fs.StatePush(next, "*oauth-confirm"), true, "config_refresh_token", "Already have a token - refresh?")
 		}
-	}
-
-	// Detect whether we should use internal web server
-	useWebServer := false
-	switch oauthConfig.RedirectURL {
-	case RedirectURL, RedirectPublicURL, RedirectLocalhostURL:
-		if changed {
-			fmt.Printf("Make sure your Redirect URL is set to %q in your custom config.\n", oauthConfig.RedirectURL)
+		return fs.ConfigGoto(fs.StatePush(next, "*oauth-islocal"))
+	case "*oauth-confirm":
+		if in.Result == "false" {
+			return fs.ConfigGoto(next)
 		}
-		useWebServer = true
-		if automatic {
-			break
-		}
-		fmt.Printf("Use auto config?\n")
-		fmt.Printf(" * Say Y if not sure\n")
-		fmt.Printf(" * Say N if you are working on a remote or headless machine\n")
-		auto := config.Confirm()
-		if !auto {
-			fmt.Printf("For this to work, you will need rclone available on a machine that has a web browser available.\n")
-			fmt.Printf("Execute the following on your machine:\n")
+		return fs.ConfigGoto(fs.StatePush(next, "*oauth-islocal"))
+	case "*oauth-islocal":
+		// Detect whether we can use internal web server
+		help := "Use auto config?\n * Say Y if not sure\n * Say N if you are working on a remote or headless machine"
+		switch oauthConfig.RedirectURL {
+		case RedirectURL, RedirectPublicURL, RedirectLocalhostURL:
 			if changed {
-				fmt.Printf("\trclone authorize %q %q %q\n", id, oauthConfig.ClientID, oauthConfig.ClientSecret)
-			} else {
-				fmt.Printf("\trclone authorize %q\n", id)
+				help = fmt.Sprintf("Make sure your Redirect URL is set to %q in your custom config.\n", oauthConfig.RedirectURL) + help
 			}
-			fmt.Println("Then paste the result below:")
-			code := ""
-			for code == "" {
-				fmt.Printf("result> ")
-				code = strings.TrimSpace(config.ReadLine())
+		case TitleBarRedirectURL:
+			help += " or Y didn't work"
+		default:
+			// No web server so ask for the code
+			return fs.ConfigResult(fs.StatePush(next, "*oauth-do"), "false")
+		}
+		if automatic {
+			return fs.ConfigResult(fs.StatePush(next, "*oauth-isauto"), "true")
+		}
+		return fs.ConfigConfirm(fs.StatePush(next, "*oauth-isauto"), true, "config_is_local", help)
+	case "*oauth-isauto":
+		if in.Result == "true" || oauthConfig.RedirectURL == TitleBarRedirectURL {
+			return fs.ConfigResult(fs.StatePush(next, "*oauth-do"), in.Result)
+		}
+		if opt.DeviceAuthURL != "" {
+			return fs.ConfigConfirm(fs.StatePush(next, "*oauth-device"), true, "config_device_code", "Use a device code to authorize?\n * Say Y to get a code to enter in a web browser on any device\n * Say N to run \"rclone authorize\" on another machine")
+		}
+		return authorizeInput(ri, oauthConfig, changed, next)
+	case "*oauth-device":
+		if in.Result == "false" {
+			return authorizeInput(ri, oauthConfig, changed, next)
+		}
+		da, err := getDeviceCode(oauthConfig, opt.DeviceAuthURL)
+		if err != nil {
+			return nil, err
+		}
+		interval := time.Duration(da.Interval) * time.Second
+		if interval <= 0 {
+			interval = defaultDeviceInterval
+		}
+		expiry := time.Now().Add(time.Duration(da.ExpiresIn) * time.Second)
+		state := fs.StatePush(next, "*oauth-device-poll", da.DeviceCode, interval.String(), strconv.FormatInt(expiry.Unix(), 10))
+		return fs.ConfigConfirm(state, true, "config_device_done", fmt.Sprintf(`Go to the following link on any device with a web browser
+
+	%s
+
+and enter the code
+
+	%s
+
+Log in and authorize rclone for access, then say Y`, da.VerificationURI, da.UserCode))
+	case "*oauth-device-poll":
+		next, deviceCode := fs.StatePop(next)
+		next, intervalString := fs.StatePop(next)
+		next, expiryString := fs.StatePop(next)
+		if in.Result == "false" {
+			return fs.ConfigGoto(fs.StatePush(next, "*oauth-islocal"))
+		}
+		interval, err := time.ParseDuration(intervalString)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad device code interval")
+		}
+		expiryUnix, err := strconv.ParseInt(expiryString, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad device code expiry")
+		}
+		expiry := time.Unix(expiryUnix, 0)
+		var token *oauth2.Token
+		if in.NonInteractive {
+			// Don't block the caller - poll once and ask again
+			// if the user hasn't authorized the code yet
+			var slowDown bool
+			token, slowDown, err = requestDeviceToken(oauthConfig, deviceCode)
+			if err == errDevicePending && time.Now().Before(expiry) {
+				if slowDown {
+					interval += 5 * time.Second
+				}
+				state := fs.StatePush(next, "*oauth-device-poll", deviceCode, interval.String(), expiryString)
+				out, err := fs.ConfigConfirm(state, true, "config_device_done", "Log in and authorize rclone for access, then say Y")
+				if out != nil {
+					out.Error = "Haven't received the authorization for the device code yet"
+				}
+				return out, err
 			}
-			token := &oauth2.Token{}
-			err := json.Unmarshal([]byte(code), token)
-			if err != nil {
-				return err
+			if err == errDevicePending {
+				err = errors.New("device code expired before it was authorized")
 			}
-			return PutToken(name, m, token, false)
+		} else {
+			token, err = pollDeviceToken(oauthConfig, deviceCode, interval, expiry)
 		}
-	case TitleBarRedirectURL:
-		useWebServer = automatic
-		if !automatic {
-			fmt.Printf("Use auto config?\n")
-			fmt.Printf(" * Say Y if not sure\n")
-			fmt.Printf(" * Say N if you are working on a remote or headless machine or Y didn't work\n")
-			useWebServer = config.Confirm()
+		if err != nil {
+			return fs.ConfigError(fs.StatePush(next, "*oauth-islocal"), fmt.Sprintf("Device code authorization failed - try again: %v", err))
 		}
-		if useWebServer {
+		err = PutToken(name, m, token, false)
+		if err != nil {
+			return nil, err
+		}
+		return fs.ConfigGoto(next)
+	case "*oauth-authorize":
+		token := &oauth2.Token{}
+		err := json.Unmarshal([]byte(strings.TrimSpace(in.Result)), token)
+		if err != nil {
+			return fs.ConfigError(fs.StatePush(next, "*oauth-isauto"), fmt.Sprintf("Couldn't decode the result - try again: %v", err))
+		}
+		err = PutToken(name, m, token, false)
+		if err != nil {
+			return nil, err
+		}
+		return fs.ConfigGoto(next)
+	case "*oauth-do":
+		useWebServer := in.Result == "true"
+		if useWebServer && oauthConfig.RedirectURL == TitleBarRedirectURL {
 			// copy the config and set to use the internal webserver
 			configCopy := *oauthConfig
 			oauthConfig = &configCopy
 			oauthConfig.RedirectURL = RedirectURL
 		}
+		if useWebServer && in.NonInteractive {
+			return startWaiting(oauthConfig, opt, next)
+		}
+		authURL, authCode, err := getAuthCode(oauthConfig, opt, useWebServer)
+		if err != nil {
+			return nil, err
+		}
+		if authCode == "" {
+			// Ask for the code the user was given
+			return fs.ConfigInput(fs.StatePush(next, "*oauth-code"), "config_verification_code", fmt.Sprintf("Go to the following link, log in and authorize rclone for access\n\n\t%s\n\nThen enter the verification code", authURL))
+		}
+		return configToken(name, m, oauthConfig, authCode, automatic, next)
+	case "*oauth-code":
+		return configToken(name, m, oauthConfig, strings.TrimSpace(in.Result), automatic, next)
+	case "*oauth-wait":
+		next, authState := fs.StatePop(next)
+		if in.Result == "false" {
+			stopWaiting(authState)
+			return fs.ConfigGoto(fs.StatePush(next, "*oauth-islocal"))
+		}
+		return checkWaiting(name, m, authState, automatic, next)
+	}
+	return nil, errors.Errorf("unknown internal oauth state %q", stateName)
+}
+
+// authorizeInput asks the user to run "rclone authorize" on another
+// machine and paste the result
+func authorizeInput(ri *fs.RegInfo, oauthConfig *oauth2.Config, changed bool, next string) (*fs.ConfigOut, error) {
+	command := fmt.Sprintf("rclone authorize %q", ri.Name)
+	if changed {
+		command = fmt.Sprintf("rclone authorize %q %q %q", ri.Name, oauthConfig.ClientID, oauthConfig.ClientSecret)
 	}
+	return fs.ConfigInput(fs.StatePush(next, "*oauth-authorize"), "config_token", fmt.Sprintf(`For this to work, you will need rclone available on a machine that has
+a web browser available.
 
-	// Make random state
+Execute the following on that machine:
+
+	%s
+
+Then paste the result.`, command))
+}
+
+// waitTimeout is how long the local web server started by
+// startWaiting waits for the browser
+const waitTimeout = 10 * time.Minute
+
+// waitingAuth is an authorization whose local web server is waiting
+// for the browser in the background
+type waitingAuth struct {
+	server      *authServer
+	oauthConfig *oauth2.Config // config to exchange the code with
+}
+
+var (
+	waitingMu sync.Mutex
+	waiting   = map[string]*waitingAuth{} // by the state of the authorization
+)
+
+// startWaiting starts the local web server in the background and
+// asks the user to visit it and log in.
+//
+// This is used when the config can't wait for the user, eg over the
+// rc, so the code is collected by checkWaiting when the user answers.
+func startWaiting(oauthConfig *oauth2.Config, opt *Options, next string) (*fs.ConfigOut, error) {
+	authState, authURL, err := newAuthURL(oauthConfig, opt)
+	if err != nil {
+		return nil, err
+	}
+	server := &authServer{
+		state:        authState,
+		bindAddress:  bindAddress,
+		authURL:      authURL,
+		errorHandler: opt.CheckAuth,
+		code:         make(chan string, 1),
+		err:          make(chan error, 1),
+	}
+	err = server.Start()
+	if err != nil {
+		return nil, err
+	}
+	waitingMu.Lock()
+	waiting[authState] = &waitingAuth{
+		server:      server,
+		oauthConfig: oauthConfig,
+	}
+	waitingMu.Unlock()
+	time.AfterFunc(waitTimeout, func() {
+		stopWaiting(authState)
+	})
+	return waitingOut(authState, next)
+}
+
+// waitingOut asks the user to say when they have logged in
+func waitingOut(authState, next string) (*fs.ConfigOut, error) {
+	return fs.ConfigConfirm(fs.StatePush(next, "*oauth-wait", authState), true, "config_oauth_done", fmt.Sprintf(`Go to the following link in a web browser on the machine running rclone
+
+	http://%s/auth
+
+Log in and authorize rclone for access, then say Y`, bindAddress))
+}
+
+// stopWaiting stops the local web server for authState if it is
+// still running
+func stopWaiting(authState string) {
+	waitingMu.Lock()
+	defer waitingMu.Unlock()
+	if w, ok := waiting[authState]; ok {
+		delete(waiting, authState)
+		w.server.Stop()
+	}
+}
+
+// checkWaiting reads the code from the local web server for
+// authState without waiting and saves the token if it has arrived.
+//
+// If it hasn't arrived yet the user is asked again.
+func checkWaiting(name string, m configmap.Mapper, authState string, automatic bool, next string) (*fs.ConfigOut, error) {
+	waitingMu.Lock()
+	w, ok := waiting[authState]
+	if !ok {
+		waitingMu.Unlock()
+		return fs.ConfigError(fs.StatePush(next, "*oauth-islocal"), "Authorization timed out - try again")
+	}
+	var authCode string
+	var authError error
+	select {
+	case authCode = <-w.server.code:
+		authError = <-w.server.err
+	default:
+		waitingMu.Unlock()
+		out, err := waitingOut(authState, next)
+		if out != nil {
+			out.Error = "Haven't received the authorization from the web browser yet"
+		}
+		return out, err
+	}
+	delete(waiting, authState)
+	w.server.Stop()
+	waitingMu.Unlock()
+	if authCode == "" {
+		if authError == nil {
+			authError = errors.New("failed to get code")
+		}
+		return fs.ConfigError(fs.StatePush(next, "*oauth-islocal"), fmt.Sprintf("Authorization failed - try again: %v", authError))
+	}
+	return configToken(name, m, w.oauthConfig, authCode, automatic, next)
+}
+
+// newAuthURL makes a random state and returns it along with the URL
+// the user should visit to authorize rclone
+func newAuthURL(oauthConfig *oauth2.Config, opt *Options) (state, authURL string, err error) {
 	stateBytes := make([]byte, 16)
-	_, err := rand.Read(stateBytes)
+	_, err = rand.Read(stateBytes)
 	if err != nil {
-		return err
+		return "", "", err
 	}
-	state := fmt.Sprintf("%x", stateBytes)
-	if offline {
-		opts = append(opts, oauth2.AccessTypeOffline)
+	state = fmt.Sprintf("%x", stateBytes)
+	opts := opt.OAuth2Opts
+	if !opt.NoOffline {
+		opts = append(opts[:len(opts):len(opts)], oauth2.AccessTypeOffline)
+	}
+	return state, oauthConfig.AuthCodeURL(state, opts...), nil
+}
+
+// getAuthCode sends the user to the authorization URL and returns the
+// code from the internal webserver if useWebServer is set, otherwise
+// it returns authCode as "" and the user should be asked for the code.
+func getAuthCode(oauthConfig *oauth2.Config, opt *Options, useWebServer bool) (authURL, authCode string, err error) {
+	state, authURL, err := newAuthURL(oauthConfig, opt)
+	if err != nil {
+		return "", "", err
 	}
-	authURL := oauthConfig.AuthCodeURL(state, opts...)
 
 	// Prepare webserver
 	server := authServer{
 		state:        state,
 		bindAddress:  bindAddress,
 		authURL:      authURL,
-		errorHandler: errorHandler,
+		errorHandler: opt.CheckAuth,
 	}
 	if useWebServer {
 		server.code = make(chan string, 1)
 		server.err = make(chan error, 1)
-		go server.Start()
+		err = server.Start()
+		if err != nil {
+			return "", "", err
+		}
 		defer server.Stop()
 		authURL = "http://" + bindAddress + "/auth"
 	}
 
 	// Generate a URL for the user to visit for authorization.
 	_ = open.Start(authURL)
+	if !useWebServer {
+		return authURL, "", nil
+	}
 	fmt.Printf("If your browser doesn't open automatically go to the following link: %s\n", authURL)
 	fmt.Printf("Log in and authorize rclone for access\n")
 
-	var authCode string
-	if useWebServer {
-		// Read the code, and exchange it for a token.
-		fmt.Printf("Waiting for code...\n")
-		authCode = <-server.code
-		authError := <-server.err
-		if authCode != "" {
-			fmt.Printf("Got code\n")
-		} else {
-			if authError != nil {
-				return authError
-			}
-			return errors.New("failed to get code")
+	// Read the code from the webserver
+	fmt.Printf("Waiting for code...\n")
+	authCode = <-server.code
+	authError := <-server.err
+	if authCode == "" {
+		if authError != nil {
+			return authURL, "", authError
 		}
-	} else {
-		// Read the code, and exchange it for a token.
-		fmt.Printf("Enter verification code> ")
-		authCode = config.ReadLine()
+		return authURL, "", errors.New("failed to get code")
 	}
+	fmt.Printf("Got code\n")
+	return authURL, authCode, nil
+}
+
+// configToken exchanges authCode for a token and saves it, then goes
+// to the next state
+func configToken(name string, m configmap.Mapper, oauthConfig *oauth2.Config, authCode string, automatic bool, next string) (*fs.ConfigOut, error) {
 	token, err := oauthConfig.Exchange(oauth2.NoContext, authCode)
 	if err != nil {
-		return errors.Wrap(err, "failed to get token")
+		return nil, errors.Wrap(err, "failed to get token")
 	}
 
 	// Print code if we do automatic retrieval
 	if automatic {
 		result, err := json.Marshal(token)
 		if err != nil {
-			return errors.Wrap(err, "failed to marshal token")
+			return nil, errors.Wrap(err, "failed to marshal token")
 		}
-		fmt.Printf("Paste the following into your remote machine --->\n%s\n<---End paste", result)
+		fmt.Printf("Paste the following into your remote machine --->\n%s\n<---End paste\n", result)
 	}
-	return PutToken(name, m, token, true)
+	err = PutToken(name, m, token, true)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ConfigGoto(next)
 }
 
 // Local web server for collecting auth
@@ -470,8 +735,9 @@ type AuthResponseData struct {
 	AuthError
 }
 
-// startWebServer runs an internal web server to receive config details
-func (s *authServer) Start() {
+// Start runs an internal web server in the background to receive
+// config details, returning an error if it couldn't listen
+func (s *authServer) Start() error {
 	fs.Debugf(nil, "Starting auth server on %s", s.bindAddress)
 	mux := http.NewServeMux()
 	s.server = &http.Server{
@@ -538,8 +804,11 @@ func (s *authServer) Start() {
 	var err error
 	s.listener, err = net.Listen("tcp", s.bindAddress)
 	if err != nil {
-		log.Fatalf("Failed to start auth webserver: %v", err)
+		return errors.Wrap(err, "failed to start auth webserver")
 	}
-	err = s.server.Serve(s.listener)
-	fs.Debugf(nil, "Closed auth server with error: %v", err)
+	go func() {
+		err := s.server.Serve(s.listener)
+		fs.Debugf(nil, "Closed auth server with error: %v", err)
+	}()
+	return nil
 }
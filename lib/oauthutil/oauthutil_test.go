@@ -0,0 +1,323 @@
+package oauthutil
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// testOAuthOptions are returned by the test backend's config
+var testOAuthOptions *Options
+
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name: "oauthutil_test",
+		Config: func(name string, m configmap.Mapper, in fs.ConfigIn) (*fs.ConfigOut, error) {
+			if in.State == "" {
+				return ConfigOut("", testOAuthOptions)
+			}
+			return nil, nil
+		},
+	})
+}
+
+// testServer is a fake OAuth provider
+type testServer struct {
+	*httptest.Server
+	pending int // number of times to say the device authorization is pending
+	values  []url.Values
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		s.values = append(s.values, r.PostForm)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"device_code":"DEVICE","user_code":"USER","verification_url":"https://example.com/device","expires_in":60}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		s.values = append(s.values, r.PostForm)
+		w.Header().Set("Content-Type", "application/json")
+		if s.pending > 0 {
+			s.pending--
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+			return
+		}
+		if r.PostForm.Get("code") == "bad" || r.PostForm.Get("device_code") == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"access_denied","error_description":"user said no"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"ACCESS","token_type":"Bearer","refresh_token":"REFRESH","expires_in":3600}`))
+	})
+	s.Server = httptest.NewServer(mux)
+	testOAuthOptions = &Options{
+		OAuth2Config: &oauth2.Config{
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  s.URL + "/auth",
+				TokenURL: s.URL + "/token",
+			},
+			Scopes:       []string{"read", "write"},
+			ClientID:     "id",
+			ClientSecret: "secret",
+			RedirectURL:  RedirectURL,
+		},
+		DeviceAuthURL: s.URL + "/device",
+	}
+	return s
+}
+
+// setupTestConfig points the config at an empty temporary config file
+func setupTestConfig(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "oauthutil")
+	require.NoError(t, err)
+	oldConfigPath := config.ConfigPath
+	config.ConfigPath = filepath.Join(dir, "rclone.conf")
+	config.LoadConfig()
+	return func() {
+		config.ConfigPath = oldConfigPath
+		config.LoadConfig()
+		require.NoError(t, os.RemoveAll(dir))
+	}
+}
+
+func TestPollDeviceToken(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	oauthConfig := testOAuthOptions.OAuth2Config
+
+	da, err := getDeviceCode(oauthConfig, testOAuthOptions.DeviceAuthURL)
+	require.NoError(t, err)
+	assert.Equal(t, "DEVICE", da.DeviceCode)
+	assert.Equal(t, "USER", da.UserCode)
+	assert.Equal(t, "https://example.com/device", da.VerificationURI)
+	assert.Equal(t, "id", s.values[0].Get("client_id"))
+	assert.Equal(t, "read write", s.values[0].Get("scope"))
+
+	s.pending = 2
+	token, err := pollDeviceToken(oauthConfig, da.DeviceCode, time.Millisecond, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "ACCESS", token.AccessToken)
+	assert.Equal(t, "REFRESH", token.RefreshToken)
+	assert.True(t, token.Expiry.After(time.Now()))
+	require.Len(t, s.values, 4)
+	assert.Equal(t, deviceGrantType, s.values[3].Get("grant_type"))
+	assert.Equal(t, "DEVICE", s.values[3].Get("device_code"))
+
+	_, err = pollDeviceToken(oauthConfig, "bad", time.Millisecond, time.Now().Add(time.Minute))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user said no")
+
+	s.pending = 100
+	_, err = pollDeviceToken(oauthConfig, da.DeviceCode, time.Millisecond, time.Now().Add(10*time.Millisecond))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expired")
+}
+
+func TestConfigOAuthDevice(t *testing.T) {
+	defer setupTestConfig(t)()
+	s := newTestServer(t)
+	defer s.Close()
+	ri, err := fs.Find("oauthutil_test")
+	require.NoError(t, err)
+	config.FileSet("test", "type", "oauthutil_test")
+	config.SaveConfig()
+	m := configmap.Simple{}
+
+	// Say we are headless
+	out, err := ConfigOAuth("test", m, ri, fs.ConfigIn{State: "*oauth-isauto,next", Result: "false"})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, "config_device_code", out.Option.Name)
+
+	// Use a device code
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: out.State, Result: "true"})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, "config_device_done", out.Option.Name)
+	assert.Contains(t, out.Option.Help, "https://example.com/device")
+	assert.Contains(t, out.Option.Help, "USER")
+
+	// Done
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: out.State, Result: "true"})
+	require.NoError(t, err)
+	assert.Equal(t, "next", out.State)
+	assert.Contains(t, config.FileGet("test", config.ConfigToken), "ACCESS")
+
+	// Not using a device code asks for rclone authorize
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: "*oauth-device,next", Result: "false"})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, "config_token", out.Option.Name)
+}
+
+func TestConfigOAuthDeviceNonInteractive(t *testing.T) {
+	defer setupTestConfig(t)()
+	s := newTestServer(t)
+	defer s.Close()
+	ri, err := fs.Find("oauthutil_test")
+	require.NoError(t, err)
+	config.FileSet("test", "type", "oauthutil_test")
+	config.SaveConfig()
+	m := configmap.Simple{}
+
+	out, err := ConfigOAuth("test", m, ri, fs.ConfigIn{State: "*oauth-device,next", Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, "config_device_done", out.Option.Name)
+	pollState := out.State
+
+	// Answering before the device code is authorized polls once
+	// and asks again
+	s.pending = 100
+	nValues := len(s.values)
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: pollState, Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, "config_device_done", out.Option.Name)
+	assert.Equal(t, pollState, out.State)
+	assert.NotEqual(t, "", out.Error)
+	assert.Equal(t, nValues+1, len(s.values))
+
+	// Once authorized the token is saved
+	s.pending = 0
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: pollState, Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	assert.Equal(t, "next", out.State)
+	assert.Contains(t, config.FileGet("test", config.ConfigToken), "ACCESS")
+}
+
+func TestConfigOAuthNonInteractive(t *testing.T) {
+	defer setupTestConfig(t)()
+	s := newTestServer(t)
+	defer s.Close()
+	ri, err := fs.Find("oauthutil_test")
+	require.NoError(t, err)
+	config.FileSet("test", "type", "oauthutil_test")
+	config.SaveConfig()
+	m := configmap.Simple{}
+
+	// The web server is started in the background
+	out, err := ConfigOAuth("test", m, ri, fs.ConfigIn{State: "*oauth-do,next", Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, "config_oauth_done", out.Option.Name)
+	assert.Contains(t, out.Option.Help, "http://"+bindAddress+"/auth")
+	waitState := out.State
+	rest, _ := fs.StatePop(waitState)
+	_, authState := fs.StatePop(rest)
+	require.NotEqual(t, "", authState)
+
+	// Answering before the browser has been redirected asks again
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: waitState, Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, waitState, out.State)
+	assert.NotEqual(t, "", out.Error)
+
+	// The browser is redirected to the web server with the code
+	resp, err := http.Get(RedirectURL + "?code=CODE&state=" + authState)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: waitState, Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	assert.Equal(t, "next", out.State)
+	assert.Equal(t, "CODE", s.values[len(s.values)-1].Get("code"))
+	assert.Contains(t, config.FileGet("test", config.ConfigToken), "ACCESS")
+
+	// The authorization can only be used once
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: waitState, Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	assert.Equal(t, "*oauth-islocal,next", out.State)
+	assert.NotEqual(t, "", out.Error)
+
+	// Saying no stops the web server
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: "*oauth-do,next", Result: "true", NonInteractive: true})
+	require.NoError(t, err)
+	out, err = ConfigOAuth("test", m, ri, fs.ConfigIn{State: out.State, Result: "false", NonInteractive: true})
+	require.NoError(t, err)
+	assert.Equal(t, "*oauth-islocal,next", out.State)
+	waitingMu.Lock()
+	assert.Equal(t, 0, len(waiting))
+	waitingMu.Unlock()
+}
+
+func TestRcAuthorize(t *testing.T) {
+	defer setupTestConfig(t)()
+	s := newTestServer(t)
+	defer s.Close()
+
+	_, err := rcAuthorize(rc.Params{})
+	assert.Error(t, err)
+
+	out, err := rcAuthorize(rc.Params{"type": "oauthutil_test", "client_id": "myid"})
+	require.NoError(t, err)
+	state, _ := out["state"].(string)
+	require.NotEqual(t, "", state)
+	assert.Equal(t, RedirectURL, out["redirect_url"])
+	authURL, err := url.Parse(out["url"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, s.URL+"/auth", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, "myid", authURL.Query().Get("client_id"))
+	assert.Equal(t, state, authURL.Query().Get("state"))
+
+	// Wrong state
+	_, err = rcAuthorize(rc.Params{"state": "potato", "code": "CODE"})
+	assert.Error(t, err)
+	_, err = rcAuthorize(rc.Params{"state": state, "url": RedirectURL + "?code=CODE&state=potato"})
+	assert.Error(t, err)
+	_, err = rcAuthorize(rc.Params{"state": state, "url": RedirectURL + "?error=access_denied&state=" + state})
+	assert.Error(t, err)
+
+	// Finish with the redirected URL
+	out, err = rcAuthorize(rc.Params{"state": state, "url": RedirectURL + "?code=CODE&state=" + state})
+	require.NoError(t, err)
+	token := new(oauth2.Token)
+	require.NoError(t, json.Unmarshal([]byte(out["token"].(string)), token))
+	assert.Equal(t, "ACCESS", token.AccessToken)
+	assert.Equal(t, "CODE", s.values[len(s.values)-1].Get("code"))
+
+	// The state can only be used once
+	_, err = rcAuthorize(rc.Params{"state": state, "code": "CODE"})
+	assert.Error(t, err)
+
+	// Authorize an existing remote saving the token
+	config.FileSet("myremote", "type", "oauthutil_test")
+	config.SaveConfig()
+	out, err = rcAuthorize(rc.Params{"name": "myremote"})
+	require.NoError(t, err)
+	out, err = rcAuthorize(rc.Params{"state": out["state"], "code": " CODE\n"})
+	require.NoError(t, err)
+	assert.Contains(t, config.FileGet("myremote", config.ConfigToken), "ACCESS")
+
+	// A failed exchange uses up the state too
+	out, err = rcAuthorize(rc.Params{"type": "oauthutil_test"})
+	require.NoError(t, err)
+	state, _ = out["state"].(string)
+	_, err = rcAuthorize(rc.Params{"state": state, "code": "bad"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "user said no")
+	_, err = rcAuthorize(rc.Params{"state": state, "code": "CODE"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
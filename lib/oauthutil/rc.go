@@ -0,0 +1,199 @@
+// Remote control for OAuth authorization
+
+package oauthutil
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "config/authorize",
+		Fn:    rcAuthorize,
+		Title: "Authorize a remote using OAuth without a local web server.",
+		Help: `
+This lets a web GUI or other rc client finish the OAuth authorization
+of a remote for backends such as drive, onedrive, box, dropbox and
+pcloud when rclone is running on a server without a web browser.
+
+It is called twice.  The first call takes these parameters
+
+- type - the type of the remote, eg "drive"
+- name - optional name of an existing remote to authorize
+- client_id - optional client ID to use
+- client_secret - optional client secret to use
+- redirect_url - optional redirect URL to use instead of the backend's
+
+If name is set then the type and any client ID, client secret and
+other options needed to authorize are read from that remote, and the
+token is saved to it when the authorization is finished.
+
+It returns
+
+- url - the URL the user should open in a web browser
+- state - pass this to the second call
+- redirect_url - the URL the browser will be redirected to with the code
+
+After the user has logged in and authorized rclone the browser is
+redirected to redirect_url with the code in the query, or for some
+backends, eg drive, the code is shown to the user.  Call
+config/authorize again with
+
+- state - the state returned by the first call
+- code - the code, or
+- url - the whole URL the browser was redirected to
+
+It returns
+
+- token - the token as a JSON string
+
+The token can be passed as the "token" parameter to config/create.
+The second call must be made within 10 minutes of the first.
+`,
+	})
+}
+
+// rcAuthorizeTimeout is how long an authorization started with
+// config/authorize is kept waiting for its code
+const rcAuthorizeTimeout = 10 * time.Minute
+
+// rcPendingAuth is an authorization started with config/authorize
+type rcPendingAuth struct {
+	name        string         // name of the remote to save the token to, if any
+	oauthConfig *oauth2.Config // config to exchange the code with
+	expires     time.Time      // when this authorization expires
+}
+
+var (
+	rcPendingMu sync.Mutex
+	rcPending   = map[string]*rcPendingAuth{}
+)
+
+// Authorize a remote over the rc
+func rcAuthorize(in rc.Params) (out rc.Params, err error) {
+	rcPendingMu.Lock()
+	now := time.Now()
+	for state, pending := range rcPending {
+		if now.After(pending.expires) {
+			delete(rcPending, state)
+		}
+	}
+	rcPendingMu.Unlock()
+	if state := in.GetString("state"); state != "" {
+		return rcAuthorizeFinish(state, in)
+	}
+	return rcAuthorizeStart(in)
+}
+
+// rcAuthorizeStart returns the URL for the user to visit
+func rcAuthorizeStart(in rc.Params) (out rc.Params, err error) {
+	name := in.GetString("name")
+	fsType := in.GetString("type")
+	var m configmap.Mapper
+	if name != "" {
+		fsType = config.RemoteType(name)
+		if fsType == "" {
+			return nil, errors.Errorf("couldn't find type of remote %q", name)
+		}
+	} else if fsType == "" {
+		return nil, errors.New("need type or name parameter")
+	}
+	ri, err := fs.Find(fsType)
+	if err != nil {
+		return nil, err
+	}
+	if ri.Config == nil {
+		return nil, errors.Errorf("%s backend doesn't use OAuth", fsType)
+	}
+	if name != "" {
+		m = fs.ConfigMap(ri, name)
+	} else {
+		m = configmap.Simple{
+			config.ConfigClientID:     in.GetString(config.ConfigClientID),
+			config.ConfigClientSecret: in.GetString(config.ConfigClientSecret),
+		}
+	}
+	opt, err := getOAuth(name, m, ri)
+	if err != nil {
+		return nil, err
+	}
+	oauthConfig, _ := overrideCredentials(name, m, opt.OAuth2Config)
+	if redirectURL := in.GetString("redirect_url"); redirectURL != "" {
+		oauthConfig.RedirectURL = redirectURL
+	}
+	state, authURL, err := newAuthURL(oauthConfig, opt)
+	if err != nil {
+		return nil, err
+	}
+	rcPendingMu.Lock()
+	rcPending[state] = &rcPendingAuth{
+		name:        name,
+		oauthConfig: oauthConfig,
+		expires:     time.Now().Add(rcAuthorizeTimeout),
+	}
+	rcPendingMu.Unlock()
+	return rc.Params{
+		"url":          authURL,
+		"state":        state,
+		"redirect_url": oauthConfig.RedirectURL,
+	}, nil
+}
+
+// rcAuthorizeFinish exchanges the code for a token
+func rcAuthorizeFinish(state string, in rc.Params) (out rc.Params, err error) {
+	code := strings.TrimSpace(in.GetString("code"))
+	if redirectURL := in.GetString("url"); redirectURL != "" {
+		u, err := url.Parse(strings.TrimSpace(redirectURL))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse url")
+		}
+		query := u.Query()
+		if gotState := query.Get("state"); gotState != "" && gotState != state {
+			return nil, errors.New("state in url doesn't match")
+		}
+		if authError := query.Get("error"); authError != "" {
+			return nil, errors.Errorf("authorization failed: %s: %s", authError, query.Get("error_description"))
+		}
+		code = query.Get("code")
+	}
+	if code == "" {
+		return nil, errors.New("need code or url parameter")
+	}
+	// The authorization can only be used once so remove it before
+	// exchanging the code, which is done without holding the lock
+	rcPendingMu.Lock()
+	pending, ok := rcPending[state]
+	delete(rcPending, state)
+	rcPendingMu.Unlock()
+	if !ok {
+		return nil, errors.New("authorization not found - it may have expired")
+	}
+	token, err := pending.oauthConfig.Exchange(oauth2.NoContext, code)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get token")
+	}
+	tokenBytes, err := json.Marshal(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal token")
+	}
+	if pending.name != "" {
+		err = config.SetValueAndSave(pending.name, config.ConfigToken, string(tokenBytes))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to save token")
+		}
+	}
+	return rc.Params{
+		"token": string(tokenBytes),
+	}, nil
+}
@@ -69,7 +69,7 @@ func (fh *ReadFileHandle) openPending() (err error) {
 	if err != nil {
 		return err
 	}
-	fh.r = accounting.NewAccount(r, o).WithBuffer() // account the transfer
+	fh.r = accounting.NewAccount(r, o).WithRemotes(o.Fs()).WithBuffer() // account the transfer
 	fh.opened = true
 	accounting.Stats.Transferring(o.Remote())
 	return nil